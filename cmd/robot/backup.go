@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Copy the sqlite3 database to a file while the server is running",
+	Args:  cobra.NoArgs,
+	RunE:  backup,
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Replace the sqlite3 database with a backup. Stop the server first",
+	Args:  cobra.NoArgs,
+	RunE:  restore,
+}
+
+func init() {
+	backupCmd.Flags().String("out", "", "File to write the backup to")
+	backupCmd.MarkFlagRequired("out")
+	restoreCmd.Flags().String("in", "", "Backup file to restore")
+	restoreCmd.MarkFlagRequired("in")
+
+	rootCmd.AddCommand(backupCmd, restoreCmd)
+}
+
+func backup(cmd *cobra.Command, args []string) error {
+	out, _ := cmd.Flags().GetString("out")
+
+	db, err := connectDB()
+	if err != nil {
+		return err
+	}
+	defer db.DB.Close()
+
+	if err = db.Backup(out); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "backed up %s to %s\n", db.DBName, out)
+	return nil
+}
+
+func restore(cmd *cobra.Command, args []string) error {
+	in, _ := cmd.Flags().GetString("in")
+
+	db, err := connectDB()
+	if err != nil {
+		return err
+	}
+	defer db.DB.Close()
+
+	if err = db.Restore(in); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "restored %s from %s\n", db.DBName, in)
+	return nil
+}
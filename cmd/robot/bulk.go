@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"robo-apocalypse/pkg/survivordb"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Register the survivors of a CSV or NDJSON file in one transaction",
+	Long: "Register the survivors of a CSV or NDJSON file in one transaction. Every row is checked and " +
+		"nothing is stored when any row is invalid or its id number is registered. Reads stdin when the file is -",
+	Args: cobra.ExactArgs(1),
+	RunE: importSurvivors,
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write every survivor as CSV or NDJSON",
+	Args:  cobra.NoArgs,
+	RunE:  exportSurvivors,
+}
+
+func init() {
+	importCmd.Flags().String("format", "", "csv or ndjson. Taken from the file extension when empty")
+	importCmd.Flags().Bool("dry-run", false, "Only check the survivors and report the errors, storing nothing")
+	exportCmd.Flags().String("format", survivordb.FormatCSV, "csv or ndjson")
+	exportCmd.Flags().String("out", "", "File to write the survivors to, stdout when empty")
+
+	rootCmd.AddCommand(importCmd, exportCmd)
+}
+
+func importSurvivors(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(args[0]), ".")
+	}
+
+	var in io.Reader = cmd.InOrStdin()
+	if args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.DB.Close()
+
+	result, err := survivordb.Import(db, in, format, dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, rowErr := range result.Errors {
+		fmt.Fprintf(cmd.OutOrStdout(), "row %d %s: %s\n", rowErr.Row, rowErr.IdNumber, rowErr.Message)
+		for _, fieldErr := range rowErr.Fields {
+			fmt.Fprintf(cmd.OutOrStdout(), "    %s %s\n", fieldErr.Field, fieldErr.Message)
+		}
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%d of %d rows have errors, nothing was imported", len(result.Errors), result.Rows)
+	}
+	if dryRun {
+		fmt.Fprintf(cmd.OutOrStdout(), "%d survivors can be imported\n", result.Imported)
+		return nil
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "imported %d survivors\n", result.Imported)
+	return nil
+}
+
+func exportSurvivors(cmd *cobra.Command, args []string) error {
+	format, _ := cmd.Flags().GetString("format")
+	out, _ := cmd.Flags().GetString("out")
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.DB.Close()
+
+	if out == "" {
+		return survivordb.Export(db, cmd.OutOrStdout(), format)
+	}
+	file, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err = survivordb.Export(db, file, format); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"robo-apocalypse/pkg/survivordb"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// connectDB opens the database of the dbDriver and dbDSN settings without
+// migrating it. SQLite opens the dbName file when dbDSN is empty
+func connectDB() (*survivordb.SurvivorDB, error) {
+	driver, dsn := viper.GetString("dbDriver"), viper.GetString("dbDSN")
+	if driver == survivordb.SQLite && dsn == "" {
+		dsn = viper.GetString("dbName")
+	}
+	db := survivordb.OpenDriver(driver, dsn)
+	if db == nil {
+		return nil, fmt.Errorf("could not open the %s database", driver)
+	}
+
+	return db, nil
+}
+
+// openDB opens the database, migrates it to the latest version and sets it up
+func openDB() (*survivordb.SurvivorDB, error) {
+	db, err := connectDB()
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Setup(); err != nil {
+		db.DB.Close()
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Error setting up database")
+		return nil, err
+	}
+
+	return db, nil
+}
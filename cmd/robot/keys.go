@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"robo-apocalypse/pkg/auth"
+	"robo-apocalypse/pkg/survivordb"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// keysCmd groups the API key commands
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Mint, list and revoke API keys",
+}
+
+var keysCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Mint an API key. The key is printed once and cannot be shown again",
+	Args:  cobra.NoArgs,
+	RunE:  createKey,
+}
+
+var keysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the API keys without their secrets",
+	Args:  cobra.NoArgs,
+	RunE:  listKeys,
+}
+
+var keysRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>",
+	Short: "Revoke an API key so it no longer authenticates",
+	Args:  cobra.ExactArgs(1),
+	RunE:  revokeKey,
+}
+
+func init() {
+	keysCreateCmd.Flags().String("name", "", "Who the key is for, recorded as the actor of their changes")
+	keysCreateCmd.Flags().String("role", survivordb.RoleViewer,
+		"Role of the key: "+strings.Join(survivordb.Roles, ", "))
+	keysCreateCmd.MarkFlagRequired("name")
+
+	keysCmd.AddCommand(keysCreateCmd, keysListCmd, keysRevokeCmd)
+	rootCmd.AddCommand(keysCmd)
+}
+
+func createKey(cmd *cobra.Command, args []string) error {
+	name, _ := cmd.Flags().GetString("name")
+	role, _ := cmd.Flags().GetString("role")
+	known := false
+	for _, r := range survivordb.Roles {
+		known = known || role == r
+	}
+	if !known {
+		return fmt.Errorf("unknown role %q, want one of %s", role, strings.Join(survivordb.Roles, ", "))
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.DB.Close()
+
+	key, apiKey, err := auth.NewKey(db, name, role)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "id:   %d\nname: %s\nrole: %s\nkey:  %s\n", apiKey.ID, apiKey.Name, apiKey.Role, key)
+	return nil
+}
+
+func listKeys(cmd *cobra.Command, args []string) error {
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.DB.Close()
+
+	keys := db.GetAPIKeys()
+	if keys == nil {
+		return fmt.Errorf("could not read the api keys")
+	}
+
+	writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tNAME\tROLE\tPREFIX\tCREATED\tREVOKED")
+	for _, key := range keys {
+		revoked := "-"
+		if key.RevokedAt != nil {
+			revoked = key.RevokedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Name, key.Role, key.Prefix, key.CreatedAt.Format("2006-01-02 15:04:05"), revoked)
+	}
+
+	return writer.Flush()
+}
+
+func revokeKey(cmd *cobra.Command, args []string) error {
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid key id %q", args[0])
+	}
+
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.DB.Close()
+
+	if err = db.RevokeAPIKey(id); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "revoked key %d\n", id)
+	return nil
+}
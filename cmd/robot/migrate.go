@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"robo-apocalypse/pkg/survivordb"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// migrateCmd groups the schema migration commands
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Show and change the version of the database schema",
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "List the migrations and when they were applied",
+	Args:  cobra.NoArgs,
+	RunE:  migrationStatus,
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply the migrations not yet applied, up to the latest by default",
+	Args:  cobra.NoArgs,
+	RunE:  migrateUp,
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Undo the last migration, or every migration above --to",
+	Long: `Undo the last migration, or every migration above --to.
+
+Undoing the first migration drops every table, including those of databases
+that predate the migrations, so it needs --force.`,
+	Args: cobra.NoArgs,
+	RunE: migrateDown,
+}
+
+func init() {
+	migrateUpCmd.Flags().Int("to", 0, "Version to migrate up to, 0 for the latest")
+	migrateDownCmd.Flags().Int("to", -1, "Version to migrate down to, 0 undoes every migration. Defaults to the version before the current one")
+	migrateDownCmd.Flags().Bool("force", false, "Undo the first migration too, dropping every table")
+
+	migrateCmd.AddCommand(migrateStatusCmd, migrateUpCmd, migrateDownCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func migrationStatus(cmd *cobra.Command, args []string) error {
+	db, err := connectDB()
+	if err != nil {
+		return err
+	}
+	defer db.DB.Close()
+
+	statuses, err := db.MigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "VERSION\tNAME\tAPPLIED")
+	for _, status := range statuses {
+		applied := "-"
+		if status.AppliedAt != nil {
+			applied = status.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\n", status.Version, status.Name, applied)
+	}
+
+	return writer.Flush()
+}
+
+func migrateUp(cmd *cobra.Command, args []string) error {
+	to, _ := cmd.Flags().GetInt("to")
+
+	db, err := connectDB()
+	if err != nil {
+		return err
+	}
+	defer db.DB.Close()
+
+	done, err := db.MigrateUp(to)
+	for _, migration := range done {
+		fmt.Fprintf(cmd.OutOrStdout(), "applied %04d_%s\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(done) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "the schema is up to date")
+	}
+
+	return nil
+}
+
+func migrateDown(cmd *cobra.Command, args []string) error {
+	to, _ := cmd.Flags().GetInt("to")
+	force, _ := cmd.Flags().GetBool("force")
+
+	db, err := connectDB()
+	if err != nil {
+		return err
+	}
+	defer db.DB.Close()
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return err
+	}
+	if !cmd.Flags().Changed("to") {
+		if to = version - 1; to < 0 {
+			to = 0
+		}
+	}
+
+	if to < 1 && version >= 1 && !force {
+		migrations, err := survivordb.Migrations(db.Driver)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "undoing %04d_%s drops the tables %s\n",
+			migrations[0].Version, migrations[0].Name, strings.Join(migrations[0].DroppedTables(), ", "))
+		return errors.New("refusing to undo the first migration without --force")
+	}
+
+	done, err := db.MigrateDown(to)
+	for _, migration := range done {
+		fmt.Fprintf(cmd.OutOrStdout(), "undid %04d_%s\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(done) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "no migration to undo")
+	}
+
+	return nil
+}
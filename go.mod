@@ -1,19 +1,20 @@
 module robo-apocalypse
 
-go 1.15
+go 1.16
 
 require (
 	github.com/bketelsen/crypt v0.0.3-0.20200106085610-5cbc8cc4026c // indirect
 	github.com/coreos/bbolt v1.3.2 // indirect
 	github.com/coreos/go-systemd v0.0.0-20190321100706-95778dfbb74e // indirect
 	github.com/coreos/pkg v0.0.0-20180928190104-399ea9e2e55f // indirect
-	github.com/go-openapi/runtime v0.23.2 // indirect
+	github.com/go-openapi/runtime v0.23.2
 	github.com/go-swagger/go-swagger v0.29.0 // indirect
 	github.com/google/uuid v1.2.0
-	github.com/gorilla/websocket v1.4.2 // indirect
+	github.com/gorilla/websocket v1.4.2
 	github.com/grpc-ecosystem/go-grpc-middleware v1.0.0 // indirect
 	github.com/grpc-ecosystem/go-grpc-prometheus v1.2.0 // indirect
 	github.com/jonboulle/clockwork v0.1.0 // indirect
+	github.com/lib/pq v1.10.9
 	github.com/magiconair/properties v1.8.6 // indirect
 	github.com/mattn/go-sqlite3 v1.14.6
 	github.com/prometheus/tsdb v0.7.1 // indirect
@@ -0,0 +1,320 @@
+// Package auth authenticates requests with API keys and authorizes them by
+// the role of the key
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"robo-apocalypse/pkg/ratelimit"
+	"robo-apocalypse/pkg/survivordb"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// keyPrefix starts every API key so that leaked keys are easy to recognise
+const keyPrefix = "apo_"
+
+// Lengths in bytes of the random public and secret parts of a key
+const (
+	publicLength = 6
+	secretLength = 24
+)
+
+// APIKeyHeader may carry the key instead of an Authorization: Bearer header
+const APIKeyHeader = "X-API-Key"
+
+// TokenParam carries the key for clients that cannot set headers, such as
+// browser EventSource and WebSocket connections
+const TokenParam = "token"
+
+var (
+	// ErrInvalidKey is returned when a key is malformed, unknown, revoked or wrong
+	ErrInvalidKey = errors.New("invalid api key")
+	// ErrTooManyChecks is returned when the IP address of a request has had
+	// too many keys checked and the key is not checked
+	ErrTooManyChecks = errors.New("too many api key checks")
+)
+
+// DefaultCheckLimit is the CheckLimit of an Authenticator that has none
+var DefaultCheckLimit = ratelimit.Limit{Rate: 1, Burst: 10}
+
+// Role groups used by the route policies
+var (
+	// Anyone is every role
+	Anyone = []string{survivordb.RoleViewer, survivordb.RoleScout, survivordb.RoleMedic, survivordb.RoleAdmin}
+	// FieldRoles are the roles that report on survivors in the field
+	FieldRoles = []string{survivordb.RoleScout, survivordb.RoleMedic, survivordb.RoleAdmin}
+	// MedicRoles are the roles that may report infections
+	MedicRoles = []string{survivordb.RoleMedic, survivordb.RoleAdmin}
+	// AdminRoles is the admin role only
+	AdminRoles = []string{survivordb.RoleAdmin}
+)
+
+// Policy lists the roles permitted per request method. The "*" entry applies
+// to the methods that are not listed
+type Policy map[string][]string
+
+// Any is the method key of a policy entry that applies to every method
+const Any = "*"
+
+// roles returns the roles permitted for a method, and false when the method
+// is not allowed at all
+func (p Policy) roles(method string) ([]string, bool) {
+	if roles, ok := p[method]; ok {
+		return roles, true
+	}
+	roles, ok := p[Any]
+
+	return roles, ok
+}
+
+type contextKey struct{}
+
+// KeyFromContext returns the API key a request was authenticated with
+func KeyFromContext(ctx context.Context) (*survivordb.APIKey, bool) {
+	key, ok := ctx.Value(contextKey{}).(*survivordb.APIKey)
+
+	return key, ok
+}
+
+// Permitted reports whether the key a request was authenticated with has one
+// of the roles. Requests made with auth turned off carry no key and are
+// always permitted
+func Permitted(ctx context.Context, roles []string) bool {
+	key, ok := KeyFromContext(ctx)
+	if !ok {
+		return true
+	}
+
+	return hasRole(key, roles)
+}
+
+// hasRole reports whether the role of key is one of roles
+func hasRole(key *survivordb.APIKey, roles []string) bool {
+	for _, role := range roles {
+		if key.Role == role {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewContext returns a context carrying the API key a request was
+// authenticated with
+func NewContext(ctx context.Context, key *survivordb.APIKey) context.Context {
+	return context.WithValue(ctx, contextKey{}, key)
+}
+
+// NewKey mints an API key for name with role and stores its hash. The
+// returned key is the only time the secret is available
+func NewKey(db survivordb.SurvivorStore, name, role string) (string, *survivordb.APIKey, error) {
+	public := make([]byte, publicLength)
+	secret := make([]byte, secretLength)
+	if _, err := rand.Read(public); err != nil {
+		return "", nil, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+
+	apiKey := &survivordb.APIKey{
+		Name:   name,
+		Role:   role,
+		Prefix: hex.EncodeToString(public),
+	}
+	secretHex := hex.EncodeToString(secret)
+	hash, err := bcrypt.GenerateFromPassword([]byte(secretHex), bcrypt.DefaultCost)
+	if err != nil {
+		return "", nil, err
+	}
+	apiKey.Hash = hash
+
+	if err = db.SaveAPIKey(apiKey); err != nil {
+		return "", nil, err
+	}
+
+	return keyPrefix + apiKey.Prefix + "_" + secretHex, apiKey, nil
+}
+
+// Authenticator checks the API keys of requests against the stored keys
+type Authenticator struct {
+	DB survivordb.SurvivorStore
+	// CheckLimit limits the keys each IP address may have checked with
+	// bcrypt, which is slow on purpose, so that wrong keys cannot keep the
+	// server hashing. Keys that passed before are not checked again and do
+	// not count. DefaultCheckLimit is used when it is the zero Limit
+	CheckLimit ratelimit.Limit
+
+	// checks holds the CheckLimit bucket of each IP address, made on first use
+	checksOnce sync.Once
+	checks     *ratelimit.Limiter
+
+	mu sync.Mutex
+	// verified holds the sha256 of the last key that passed bcrypt per key
+	// id, so that bcrypt only runs once per key rather than per request
+	verified map[int64][sha256.Size]byte
+}
+
+// Authenticate returns the valid, unrevoked API key matching key
+func (a *Authenticator) Authenticate(key string) (*survivordb.APIKey, error) {
+	apiKey, _, err := a.authenticate(nil, key)
+
+	return apiKey, err
+}
+
+// authenticate returns the valid, unrevoked API key matching the key that r
+// carries. Checking the key with bcrypt counts against the CheckLimit of the
+// IP address of r, unless r is nil; when it is used up ErrTooManyChecks is
+// returned with how long until another key may be checked
+func (a *Authenticator) authenticate(r *http.Request, key string) (*survivordb.APIKey, time.Duration, error) {
+	parts := strings.Split(strings.TrimPrefix(key, keyPrefix), "_")
+	if !strings.HasPrefix(key, keyPrefix) || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, 0, ErrInvalidKey
+	}
+
+	apiKey := a.DB.GetAPIKeyByPrefix(parts[0])
+	if apiKey == nil || apiKey.RevokedAt != nil {
+		return nil, 0, ErrInvalidKey
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	a.mu.Lock()
+	verified, ok := a.verified[apiKey.ID]
+	a.mu.Unlock()
+	if ok && verified == sum {
+		return apiKey, 0, nil
+	}
+
+	if r != nil {
+		if ok, retryAfter := a.allowCheck(r); !ok {
+			logrus.WithFields(logrus.Fields{
+				"key":        apiKey.Prefix,
+				"remoteAddr": r.RemoteAddr,
+			}).Info("Too many api key checks")
+			return nil, retryAfter, ErrTooManyChecks
+		}
+	}
+	if err := bcrypt.CompareHashAndPassword(apiKey.Hash, []byte(parts[1])); err != nil {
+		return nil, 0, ErrInvalidKey
+	}
+	a.mu.Lock()
+	if a.verified == nil {
+		a.verified = map[int64][sha256.Size]byte{}
+	}
+	a.verified[apiKey.ID] = sum
+	a.mu.Unlock()
+
+	return apiKey, 0, nil
+}
+
+// allowCheck takes a token from the CheckLimit bucket of the IP address of r
+func (a *Authenticator) allowCheck(r *http.Request) (bool, time.Duration) {
+	a.checksOnce.Do(func() {
+		a.checks = ratelimit.New(ratelimit.Config{})
+	})
+	limit := a.CheckLimit
+	if limit == (ratelimit.Limit{}) {
+		limit = DefaultCheckLimit
+	}
+
+	return a.checks.Allow(ratelimit.RemoteIP(r), "key check", limit)
+}
+
+// requestKey returns the key a request carries
+func requestKey(r *http.Request) string {
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+
+	return r.URL.Query().Get(TokenParam)
+}
+
+// Identify authenticates the key a request carries, for middleware that runs
+// before Require. It returns the request carrying the key, so Require does
+// not check it again, and "key:" followed by the key prefix. Requests without
+// a valid key are returned as they are with an empty name
+func (a *Authenticator) Identify(r *http.Request) (*http.Request, string) {
+	if apiKey, ok := KeyFromContext(r.Context()); ok {
+		return r, "key:" + apiKey.Prefix
+	}
+	key := requestKey(r)
+	if key == "" {
+		return r, ""
+	}
+	apiKey, _, err := a.authenticate(r, key)
+	if err != nil {
+		return r, ""
+	}
+
+	return r.WithContext(NewContext(r.Context(), apiKey)), "key:" + apiKey.Prefix
+}
+
+// Require wraps next so that it only serves requests made with a key whose
+// role the policy permits for the request method. Requests without a valid
+// key get 401, keys with another role get 403 and methods outside the policy
+// get 405. Requests whose IP address has had too many keys checked get 429.
+// CORS preflight requests are answered without a key
+func (a *Authenticator) Require(policy Policy, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			w.Header().Add("Access-Control-Allow-Origin", "*")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, "+APIKeyHeader)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		roles, ok := policy.roles(r.Method)
+		if !ok {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		apiKey, ok := KeyFromContext(r.Context())
+		if !ok {
+			var retryAfter time.Duration
+			var err error
+			if apiKey, retryAfter, err = a.authenticate(r, requestKey(r)); err == ErrTooManyChecks {
+				w.Header().Add("Access-Control-Allow-Origin", "*")
+				w.Header().Set("Access-Control-Expose-Headers", "Retry-After")
+				w.Header().Set("Retry-After", ratelimit.RetryAfter(retryAfter))
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			} else if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"path":       r.URL.Path,
+					"remoteAddr": r.RemoteAddr,
+				}).Info("Unauthenticated request")
+				w.Header().Add("Access-Control-Allow-Origin", "*")
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+
+		if !hasRole(apiKey, roles) {
+			logrus.WithFields(logrus.Fields{
+				"path": r.URL.Path,
+				"key":  apiKey.Prefix,
+				"role": apiKey.Role,
+			}).Info("Forbidden request")
+			w.Header().Add("Access-Control-Allow-Origin", "*")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		next(w, r.WithContext(NewContext(r.Context(), apiKey)))
+	})
+}
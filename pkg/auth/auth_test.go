@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"robo-apocalypse/pkg/ratelimit"
+	"robo-apocalypse/pkg/survivordb"
+	"testing"
+)
+
+// TestAuthenticator_Require checks if requests are only served with a valid
+// key whose role is permitted for the method
+func TestAuthenticator_Require(t *testing.T) {
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	defer os.Remove("./test.db")
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	viewer, _, err := NewKey(db, "viewer-1", survivordb.RoleViewer)
+	if err != nil {
+		t.Errorf("NewKey(): want: %v, got: %v", nil, err)
+		return
+	}
+	medic, medicKey, err := NewKey(db, "medic-1", survivordb.RoleMedic)
+	if err != nil {
+		t.Errorf("NewKey(): want: %v, got: %v", nil, err)
+		return
+	}
+	revoked, revokedKey, err := NewKey(db, "medic-2", survivordb.RoleMedic)
+	if err != nil {
+		t.Errorf("NewKey(): want: %v, got: %v", nil, err)
+		return
+	}
+	if err = db.RevokeAPIKey(revokedKey.ID); err != nil {
+		t.Errorf("SurvivorDB.RevokeAPIKey(): want: %v, got: %v", nil, err)
+	}
+
+	var actor string
+	authn := &Authenticator{DB: db}
+	handler := authn.Require(Policy{http.MethodGet: Anyone, http.MethodPut: MedicRoles}, func(w http.ResponseWriter, r *http.Request) {
+		key, _ := KeyFromContext(r.Context())
+		actor = key.Name
+	})
+
+	testCases := []struct {
+		method string
+		header string
+		value  string
+		query  string
+		want   int
+	}{
+		{http.MethodGet, "", "", "", http.StatusUnauthorized},
+		{http.MethodGet, "Authorization", "Bearer apo_nope_nope", "", http.StatusUnauthorized},
+		{http.MethodGet, "Authorization", "Bearer " + medic + "x", "", http.StatusUnauthorized},
+		{http.MethodGet, "Authorization", "Bearer " + revoked, "", http.StatusUnauthorized},
+		{http.MethodGet, "Authorization", "Bearer " + viewer, "", http.StatusOK},
+		{http.MethodGet, APIKeyHeader, viewer, "", http.StatusOK},
+		{http.MethodGet, "", "", "?token=" + viewer, http.StatusOK},
+		{http.MethodPut, "Authorization", "Bearer " + viewer, "", http.StatusForbidden},
+		{http.MethodPut, "Authorization", "Bearer " + medic, "", http.StatusOK},
+		{http.MethodPut, "Authorization", "Bearer " + medic, "", http.StatusOK},
+		{http.MethodDelete, "Authorization", "Bearer " + medic, "", http.StatusMethodNotAllowed},
+		{http.MethodOptions, "", "", "", http.StatusNoContent},
+	}
+	for _, tc := range testCases {
+		actor = ""
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(tc.method, "/survivors/infected"+tc.query, nil)
+		if tc.header != "" {
+			r.Header.Set(tc.header, tc.value)
+		}
+		handler.ServeHTTP(w, r)
+		if w.Code != tc.want {
+			t.Errorf("Authenticator.Require() - %v %v %q: want: %v, got: %v", tc.method, tc.header, tc.value, tc.want, w.Code)
+		}
+		if tc.method == http.MethodPut && w.Code == http.StatusOK && actor != medicKey.Name {
+			t.Errorf("Authenticator.Require(): actor: want: %v, got: %v", medicKey.Name, actor)
+		}
+	}
+}
+
+// TestAuthenticator_Identify checks if a valid key names the client and is
+// carried by the request
+func TestAuthenticator_Identify(t *testing.T) {
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	defer os.Remove("./test.db")
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+	key, apiKey, err := NewKey(db, "scout-1", survivordb.RoleScout)
+	if err != nil {
+		t.Errorf("NewKey(): want: %v, got: %v", nil, err)
+		return
+	}
+
+	authn := &Authenticator{DB: db}
+	for value, want := range map[string]string{"": "", "apo_nope_nope": "", key: "key:" + apiKey.Prefix} {
+		r := httptest.NewRequest(http.MethodGet, "/survivors", nil)
+		r.Header.Set(APIKeyHeader, value)
+		r, name := authn.Identify(r)
+		if _, ok := KeyFromContext(r.Context()); name != want || ok != (want != "") {
+			t.Errorf("Authenticator.Identify() - %q: want: %q, got: %q", value, want, name)
+		}
+	}
+}
+
+// TestAuthenticator_CheckLimit checks if an IP address that has had too many
+// keys checked gets 429 while keys that passed before keep working
+func TestAuthenticator_CheckLimit(t *testing.T) {
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	defer os.Remove("./test.db")
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+	key, _, err := NewKey(db, "scout-1", survivordb.RoleScout)
+	if err != nil {
+		t.Errorf("NewKey(): want: %v, got: %v", nil, err)
+		return
+	}
+
+	authn := &Authenticator{DB: db, CheckLimit: ratelimit.Limit{Rate: 0.001, Burst: 2}}
+	handler := authn.Require(Policy{http.MethodGet: Anyone}, func(w http.ResponseWriter, r *http.Request) {})
+	testCases := []struct {
+		remoteAddr string
+		key        string
+		want       int
+	}{
+		{"192.0.2.1:1234", key, http.StatusOK},
+		{"192.0.2.1:1234", key + "x", http.StatusUnauthorized},
+		{"192.0.2.1:1234", key + "x", http.StatusTooManyRequests},
+		{"192.0.2.1:1234", key, http.StatusOK},
+		{"192.0.2.2:1234", key + "x", http.StatusUnauthorized},
+	}
+	for i, tc := range testCases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/survivors", nil)
+		r.RemoteAddr = tc.remoteAddr
+		r.Header.Set(APIKeyHeader, tc.key)
+		handler.ServeHTTP(w, r)
+		if w.Code != tc.want {
+			t.Errorf("Authenticator.Require() - %d: want: %v, got: %v", i, tc.want, w.Code)
+		}
+		if tc.want == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+			t.Errorf("Authenticator.Require() - %d: want: a Retry-After header", i)
+		}
+	}
+}
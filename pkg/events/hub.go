@@ -0,0 +1,163 @@
+// Package events publishes survivor events to in-process subscribers
+package events
+
+import (
+	"robo-apocalypse/pkg/survivordb"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultBufferSize is the number of events a subscriber may fall behind by
+// before it is dropped
+const DefaultBufferSize = 64
+
+// syncBatchSize is the number of audit events read at a time by Sync
+const syncBatchSize = 100
+
+// Subscription receives the events published after it was made. Events is
+// closed when the subscriber falls too far behind or unsubscribes
+type Subscription struct {
+	Events <-chan survivordb.SurvivorEvent
+	events chan survivordb.SurvivorEvent
+}
+
+// Hub fans survivor events out to its subscribers. A subscriber that does not
+// keep up is dropped rather than slowing down the publisher; it can catch up
+// by replaying from the survivor database
+type Hub struct {
+	// BufferSize is the number of events buffered per subscriber,
+	// DefaultBufferSize when zero
+	BufferSize int
+
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+	closed      bool
+
+	syncMu sync.Mutex
+	lastID int64
+}
+
+// NewHub returns a hub that publishes the audit events after lastID on Sync
+func NewHub(lastID int64) *Hub {
+	return &Hub{
+		subscribers: map[*Subscription]struct{}{},
+		lastID:      lastID,
+	}
+}
+
+// Subscribe adds a subscriber
+func (h *Hub) Subscribe() *Subscription {
+	size := h.BufferSize
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	events := make(chan survivordb.SurvivorEvent, size)
+	sub := &Subscription{Events: events, events: events}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(events)
+		return sub
+	}
+	if h.subscribers == nil {
+		h.subscribers = map[*Subscription]struct{}{}
+	}
+	h.subscribers[sub] = struct{}{}
+
+	return sub
+}
+
+// Unsubscribe removes a subscriber and closes its channel
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Close closes the channels of all subscribers, so that long running streams
+// end when the server shuts down. Later subscriptions are closed at once
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subscribers {
+		delete(h.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Subscribers returns the number of subscribers
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.subscribers)
+}
+
+// Publish sends an event to every subscriber without blocking. Subscribers
+// whose buffer is full are dropped
+func (h *Hub) Publish(event survivordb.SurvivorEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			logrus.WithFields(logrus.Fields{
+				"event": event.ID,
+			}).Info("Dropping slow event subscriber")
+			delete(h.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// Sync publishes the survivor events of the audit events recorded since the
+// last Sync
+func (h *Hub) Sync(db survivordb.SurvivorStore) error {
+	h.syncMu.Lock()
+	defer h.syncMu.Unlock()
+
+	for {
+		auditEvents, err := db.GetAuditEventsAfter(h.lastID, syncBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, auditEvent := range auditEvents {
+			for _, event := range auditEvent.SurvivorEvents() {
+				h.Publish(event)
+			}
+			h.lastID = auditEvent.ID
+		}
+		if len(auditEvents) < syncBatchSize {
+			return nil
+		}
+	}
+}
+
+// Replay calls send with the survivor events of the audit events after
+// lastID, in order, and returns the id of the last audit event read
+func Replay(db survivordb.SurvivorStore, lastID int64, send func(survivordb.SurvivorEvent) error) (int64, error) {
+	for {
+		auditEvents, err := db.GetAuditEventsAfter(lastID, syncBatchSize)
+		if err != nil {
+			return lastID, err
+		}
+		for _, auditEvent := range auditEvents {
+			for _, event := range auditEvent.SurvivorEvents() {
+				if err = send(event); err != nil {
+					return lastID, err
+				}
+			}
+			lastID = auditEvent.ID
+		}
+		if len(auditEvents) < syncBatchSize {
+			return lastID, nil
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package events
+
+import (
+	"os"
+	"robo-apocalypse/pkg/survivordb"
+	"testing"
+)
+
+// TestHub_Publish checks if events reach every subscriber and slow
+// subscribers are dropped
+func TestHub_Publish(t *testing.T) {
+	hub := NewHub(0)
+	hub.BufferSize = 1
+	fast := hub.Subscribe()
+	slow := hub.Subscribe()
+
+	hub.Publish(survivordb.SurvivorEvent{ID: 1})
+	if event := <-fast.Events; event.ID != 1 {
+		t.Errorf("Hub.Publish(): want: %v, got: %v", 1, event.ID)
+	}
+	hub.Publish(survivordb.SurvivorEvent{ID: 2})
+	if hub.Subscribers() != 1 {
+		t.Errorf("Hub.Subscribers(): want: %v, got: %v", 1, hub.Subscribers())
+	}
+	<-slow.Events
+	if _, ok := <-slow.Events; ok {
+		t.Errorf("Hub.Publish(): want the slow subscriber closed")
+	}
+	if event := <-fast.Events; event.ID != 2 {
+		t.Errorf("Hub.Publish(): want: %v, got: %v", 2, event.ID)
+	}
+
+	hub.Unsubscribe(fast)
+	hub.Unsubscribe(fast)
+	if _, ok := <-fast.Events; ok || hub.Subscribers() != 0 {
+		t.Errorf("Hub.Unsubscribe(): want the subscriber closed")
+	}
+
+	hub.Close()
+	if _, ok := <-hub.Subscribe().Events; ok {
+		t.Errorf("Hub.Subscribe(): want a closed subscription after Close")
+	}
+}
+
+// TestHub_Sync checks if only the changes made after the hub was created
+// are published and replayed in order
+func TestHub_Sync(t *testing.T) {
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	defer os.Remove("./test.db")
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	if err = db.Save(&survivordb.Survivor{Name: "Jane Doe", Age: 1, Gender: "Female", IdNumber: "HD138VOP34219"}); err != nil {
+		t.Errorf("SurvivorDB.Save(): want: %v, got: %v", nil, err)
+	}
+	lastID, err := db.LastAuditEventID()
+	if err != nil || lastID != 1 {
+		t.Errorf("SurvivorDB.LastAuditEventID(): want: %v, got: %v, %v", 1, lastID, err)
+	}
+
+	hub := NewHub(lastID)
+	sub := hub.Subscribe()
+	if _, err = db.UpdateLocation("HD138VOP34219", 1, 2); err != nil {
+		t.Errorf("SurvivorDB.UpdateLocation(): want: %v, got: %v", nil, err)
+	}
+	if err = hub.Sync(db); err != nil {
+		t.Errorf("Hub.Sync(): want: %v, got: %v", nil, err)
+	}
+	if err = hub.Sync(db); err != nil {
+		t.Errorf("Hub.Sync(): want: %v, got: %v", nil, err)
+	}
+	if len(sub.Events) != 1 {
+		t.Errorf("Hub.Sync(): want: %v events, got: %v", 1, len(sub.Events))
+	}
+	if event := <-sub.Events; event.ID != 2 || event.Type != survivordb.EventLocationUpdated {
+		t.Errorf("Hub.Sync(): want: %v, got: %v", survivordb.EventLocationUpdated, event)
+	}
+
+	if err = db.DeleteSurvivor("HD138VOP34219"); err != nil {
+		t.Errorf("SurvivorDB.DeleteSurvivor(): want: %v, got: %v", nil, err)
+	}
+	if err = hub.Sync(db); err != nil {
+		t.Errorf("Hub.Sync(): want: %v, got: %v", nil, err)
+	}
+	if event := <-sub.Events; event.ID != 3 || event.Type != survivordb.EventSurvivorDeleted {
+		t.Errorf("Hub.Sync(): want: %v, got: %v", survivordb.EventSurvivorDeleted, event)
+	}
+
+	replayed := []string{}
+	last, err := Replay(db, 0, func(event survivordb.SurvivorEvent) error {
+		replayed = append(replayed, event.Type)
+		return nil
+	})
+	if err != nil || last != 3 || len(replayed) != 3 || replayed[0] != survivordb.EventSurvivorCreated ||
+		replayed[1] != survivordb.EventLocationUpdated || replayed[2] != survivordb.EventSurvivorDeleted {
+		t.Errorf("Replay(): want: %v, got: %v, %v, %v", 3, last, replayed, err)
+	}
+}
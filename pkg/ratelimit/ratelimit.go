@@ -0,0 +1,189 @@
+// Package ratelimit limits the requests of each client with token buckets
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sweepInterval is how often buckets that have refilled are forgotten
+const sweepInterval = time.Minute
+
+// Limit defines a token bucket: Burst requests may be made at once, refilled
+// at Rate requests per second. A Rate of zero or less is no limit, a Burst
+// of zero or less is a Burst of 1
+type Limit struct {
+	Rate  float64 `mapstructure:"rate"`
+	Burst int     `mapstructure:"burst"`
+}
+
+// Config defines the limits of a Limiter
+type Config struct {
+	// Default applies to the routes without a limit of their own, shared
+	// by all of them
+	Default Limit `mapstructure:"default"`
+	// Routes holds the limits of single routes, keyed by "METHOD /path" or
+	// by "/path" for every method. Paths match exactly
+	Routes map[string]Limit `mapstructure:"routes"`
+	// KeyChecks limits how many API keys each IP address may have checked,
+	// see auth.Authenticator
+	KeyChecks Limit `mapstructure:"keyChecks"`
+}
+
+// bucket holds the tokens left to a client on a route
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// take refills the bucket up to now and takes a token. When the bucket is
+// empty it returns false and how long until a token is available
+func (b *bucket) take(now time.Time) (bool, time.Duration) {
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second))
+}
+
+// full reports whether the bucket has refilled by now, so it can be forgotten
+func (b *bucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= float64(b.limit.Burst)
+}
+
+// Limiter limits the requests of each client per route
+type Limiter struct {
+	// Client returns the request to serve and the name its client is
+	// limited by, e.g. after authenticating its API key. The remote IP is
+	// used when it is nil or returns an empty name
+	Client func(r *http.Request) (*http.Request, string)
+
+	defaultLimit Limit
+	routes       map[string]Limit
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New returns a limiter with the limits of config. Methods in the route keys
+// may be any case, as configuration files often lower case keys
+func New(config Config) *Limiter {
+	routes := map[string]Limit{}
+	for route, limit := range config.Routes {
+		if parts := strings.Fields(route); len(parts) == 2 {
+			route = strings.ToUpper(parts[0]) + " " + parts[1]
+		}
+		routes[route] = limit
+	}
+
+	return &Limiter{
+		defaultLimit: config.Default,
+		routes:       routes,
+		buckets:      map[string]*bucket{},
+		now:          time.Now,
+	}
+}
+
+// limit returns the route key and limit of a request
+func (l *Limiter) limit(r *http.Request) (string, Limit) {
+	if limit, ok := l.routes[r.Method+" "+r.URL.Path]; ok {
+		return r.Method + " " + r.URL.Path, limit
+	}
+	if limit, ok := l.routes[r.URL.Path]; ok {
+		return r.URL.Path, limit
+	}
+
+	return "", l.defaultLimit
+}
+
+// Allow takes a token from the bucket of client on route. When there is
+// none it returns false and how long until there is
+func (l *Limiter) Allow(client, route string, limit Limit) (bool, time.Duration) {
+	if limit.Rate <= 0 {
+		return true, 0
+	}
+	// a bucket that holds no tokens would never let a request through
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		for key, b := range l.buckets {
+			if b.full(now) {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	key := client + " " + route
+	b, ok := l.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Burst), last: now, limit: limit}
+		l.buckets[key] = b
+	}
+
+	return b.take(now)
+}
+
+// Wrap limits the requests to next. Limited requests get 429 with a
+// Retry-After header in whole seconds
+func (l *Limiter) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client := ""
+		if l.Client != nil {
+			r, client = l.Client(r)
+		}
+		if client == "" {
+			client = RemoteIP(r)
+		}
+
+		route, limit := l.limit(r)
+		ok, retryAfter := l.Allow(client, route, limit)
+		if !ok {
+			logrus.WithFields(logrus.Fields{
+				"client": client,
+				"route":  route,
+				"path":   r.URL.Path,
+			}).Info("Rate limited")
+			w.Header().Add("Access-Control-Allow-Origin", "*")
+			w.Header().Set("Access-Control-Expose-Headers", "Retry-After")
+			w.Header().Set("Retry-After", RetryAfter(retryAfter))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RemoteIP returns the IP address a request came from as a client name
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// RetryAfter formats a wait as the whole seconds of a Retry-After header
+func RetryAfter(wait time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(wait.Seconds())))
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestLimiter_Wrap checks if each client gets its own bucket per route and
+// limited requests get 429 with Retry-After
+func TestLimiter_Wrap(t *testing.T) {
+	now := time.Date(2022, 3, 11, 0, 0, 0, 0, time.UTC)
+	limiter := New(Config{
+		Default: Limit{Rate: 10, Burst: 2},
+		Routes: map[string]Limit{
+			"put /survivors/infected": {Rate: 0.5, Burst: 1},
+			"/survivors/nearby":       {Rate: 0},
+		},
+	})
+	limiter.now = func() time.Time { return now }
+	limiter.Client = func(r *http.Request) (*http.Request, string) {
+		return r, r.Header.Get("X-Client")
+	}
+	handler := limiter.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	testCases := []struct {
+		method     string
+		path       string
+		client     string
+		remoteAddr string
+		advance    time.Duration
+		want       int
+		retryAfter string
+	}{
+		{http.MethodPut, "/survivors/infected", "a", "", 0, http.StatusOK, ""},
+		{http.MethodPut, "/survivors/infected", "a", "", 0, http.StatusTooManyRequests, "2"},
+		{http.MethodPut, "/survivors/infected", "b", "", 0, http.StatusOK, ""},
+		{http.MethodPut, "/survivors/infected", "a", "", time.Second, http.StatusTooManyRequests, "1"},
+		{http.MethodPut, "/survivors/infected", "a", "", time.Second, http.StatusOK, ""},
+		{http.MethodGet, "/survivors/infected", "a", "", 0, http.StatusOK, ""},
+		{http.MethodGet, "/survivors", "a", "", 0, http.StatusOK, ""},
+		{http.MethodGet, "/survivors", "a", "", 0, http.StatusTooManyRequests, "1"},
+		{http.MethodGet, "/survivors", "", "10.0.0.1:1234", 0, http.StatusOK, ""},
+		{http.MethodGet, "/survivors", "", "10.0.0.1:5678", 0, http.StatusOK, ""},
+		{http.MethodGet, "/survivors", "", "10.0.0.1:9012", 0, http.StatusTooManyRequests, "1"},
+		{http.MethodGet, "/survivors", "", "10.0.0.2:1234", 0, http.StatusOK, ""},
+		{http.MethodGet, "/survivors/nearby", "a", "", 0, http.StatusOK, ""},
+		{http.MethodGet, "/survivors/nearby", "a", "", 0, http.StatusOK, ""},
+		{http.MethodGet, "/survivors", "a", "", 100 * time.Millisecond, http.StatusOK, ""},
+	}
+	for i, tc := range testCases {
+		now = now.Add(tc.advance)
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(tc.method, tc.path, nil)
+		r.Header.Set("X-Client", tc.client)
+		if tc.remoteAddr != "" {
+			r.RemoteAddr = tc.remoteAddr
+		}
+		handler.ServeHTTP(w, r)
+		if w.Code != tc.want || w.Header().Get("Retry-After") != tc.retryAfter {
+			t.Errorf("Limiter.Wrap() - %d %v %v %q: want: %v %q, got: %v %q", i, tc.method, tc.path, tc.client,
+				tc.want, tc.retryAfter, w.Code, w.Header().Get("Retry-After"))
+		}
+	}
+}
+
+// TestLimiter_Allow checks if refilled buckets are forgotten
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2022, 3, 11, 0, 0, 0, 0, time.UTC)
+	limiter := New(Config{})
+	limiter.now = func() time.Time { return now }
+
+	limit := Limit{Rate: 1, Burst: 5}
+	limiter.Allow("a", "", limit)
+	limiter.Allow("b", "", limit)
+	now = now.Add(sweepInterval)
+	limiter.Allow("b", "", limit)
+	if len(limiter.buckets) != 1 {
+		t.Errorf("Limiter.Allow(): want: %v buckets, got: %v", 1, len(limiter.buckets))
+	}
+
+	// a burst of zero lets one request through at a time
+	zero := Limit{Rate: 1}
+	if ok, _ := limiter.Allow("c", "", zero); !ok {
+		t.Errorf("Limiter.Allow(): want: %v, got: %v", true, ok)
+	}
+	if ok, _ := limiter.Allow("c", "", zero); ok {
+		t.Errorf("Limiter.Allow(): want: %v, got: %v", false, ok)
+	}
+	now = now.Add(time.Second)
+	if ok, _ := limiter.Allow("c", "", zero); !ok {
+		t.Errorf("Limiter.Allow(): want: %v, got: %v", true, ok)
+	}
+}
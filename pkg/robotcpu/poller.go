@@ -0,0 +1,97 @@
+package robotcpu
+
+import (
+	"context"
+	"encoding/json"
+	"robo-apocalypse/pkg/survivordb"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultPollInterval is how often a Poller with no PollInterval polls
+const DefaultPollInterval = 5 * time.Minute
+
+// Poller fetches the robot inventory on an interval and saves it to DB, so
+// that the robots are still known while the robot CPU system is down
+type Poller struct {
+	Client *Client
+	DB     survivordb.SurvivorStore
+	// PollInterval is how often the inventory is fetched
+	PollInterval time.Duration
+
+	mu sync.Mutex
+	// stale is true when the last poll did not reach the robot CPU system
+	stale bool
+}
+
+// Run polls straight away and then every PollInterval until ctx is done
+func (p *Poller) Run(ctx context.Context) {
+	interval := p.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if saved, err := p.Poll(ctx); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+			}).Info("Error polling robot cpus")
+		} else {
+			logrus.WithFields(logrus.Fields{
+				"robots": saved,
+			}).Info("Polled robot cpus")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches the inventory and saves its robots, marking them as seen. It
+// returns the number of robots saved
+func (p *Poller) Poll(ctx context.Context) (int, error) {
+	body, err := p.Client.Fetch(ctx)
+	p.mu.Lock()
+	p.stale = err != nil
+	p.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	robots := []survivordb.Robot{}
+	if err = json.Unmarshal(body, &robots); err != nil {
+		return 0, err
+	}
+	valid := robots[:0]
+	for _, robot := range robots {
+		if robot.SerialNumber == "" {
+			logrus.WithFields(logrus.Fields{
+				"robot": robot,
+			}).Info("Skipping robot cpu without a serial number")
+			continue
+		}
+		valid = append(valid, robot)
+	}
+
+	if err = p.DB.SaveRobots(valid); err != nil {
+		return 0, err
+	}
+
+	return len(valid), nil
+}
+
+// Stale reports whether the last poll did not reach the robot CPU system, so
+// that the saved robots may be out of date
+func (p *Poller) Stale() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.stale
+}
@@ -0,0 +1,40 @@
+package robotcpu
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"robo-apocalypse/pkg/survivordb"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestPoller_Poll checks if polled robots are saved, those without a serial
+// number are skipped, every poll reaches the upstream and the robots are kept
+// while the upstream is down
+func TestPoller_Poll(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`[{"model":"AX-1","serialNumber":"S1","category":"Land"},{"model":"AX-2","category":"Land"}]`))
+	}))
+	poller := &Poller{DB: survivordb.NewMemoryStore(), Client: &Client{Endpoint: server.URL, Retries: -1,
+		BreakerThreshold: 1, BreakerCooldown: time.Hour}}
+
+	for i := 0; i < 2; i++ {
+		if saved, err := poller.Poll(context.Background()); err != nil || saved != 1 || poller.Stale() {
+			t.Errorf("Poller.Poll() - %d: want: %v, got: %v %v %v", i, 1, saved, err, poller.Stale())
+		}
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("Poller.Poll(): want: %v requests, got: %v", 2, got)
+	}
+	server.Close()
+	if saved, err := poller.Poll(context.Background()); err == nil || saved != 0 || !poller.Stale() {
+		t.Errorf("Poller.Poll(): want: stale %v, got: %v %v %v", 0, saved, err, poller.Stale())
+	}
+	if robots := poller.DB.GetRobots(); len(robots) != 1 || robots[0].SerialNumber != "S1" {
+		t.Errorf("SurvivorStore.GetRobots(): want: %v, got: %v", "S1", robots)
+	}
+}
@@ -0,0 +1,212 @@
+// Package robotcpu fetches the robot inventory from the robot CPU system,
+// with retries and a circuit breaker, and polls it into the database so that
+// the robots outlive its outages
+package robotcpu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Defaults used for the zero fields of a Client
+const (
+	DefaultTimeout          = 5 * time.Second
+	DefaultRetries          = 2
+	DefaultBackoff          = 200 * time.Millisecond
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// maxBodyBytes is the largest robot inventory read from the upstream
+const maxBodyBytes = 10 << 20
+
+// ErrCircuitOpen is returned while the breaker is open
+var ErrCircuitOpen = errors.New("robot cpu upstream circuit is open")
+
+// StatusError is returned when the upstream answers with a status other than 2xx
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("robot cpu upstream returned %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Client fetches the robot inventory from Endpoint. After BreakerThreshold
+// failed fetches in a row the upstream is left alone for BreakerCooldown,
+// after which one fetch is let through to check it has recovered
+type Client struct {
+	Endpoint string
+	// HTTPClient makes the requests, a client with Timeout when nil
+	HTTPClient *http.Client
+	// Timeout bounds every attempt
+	Timeout time.Duration
+	// Retries is the number of attempts after the first, none when it is
+	// negative. Network errors, 429 and 5xx responses are retried
+	Retries int
+	// Backoff is the most the first retry waits, doubled for every retry
+	// after it. The wait is picked at random up to it, so that clients do
+	// not retry in step
+	Backoff          time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	mu sync.Mutex
+	// failures counts the failed fetches since the last good one, and the
+	// breaker is open until openUntil
+	failures  int
+	openUntil time.Time
+}
+
+// settings are the fields of a Client with the defaults for the zero ones
+type settings struct {
+	timeout          time.Duration
+	retries          int
+	backoff          time.Duration
+	breakerThreshold int
+	breakerCooldown  time.Duration
+}
+
+func (c *Client) settings() settings {
+	s := settings{c.Timeout, c.Retries, c.Backoff, c.BreakerThreshold, c.BreakerCooldown}
+	if s.timeout <= 0 {
+		s.timeout = DefaultTimeout
+	}
+	if s.retries == 0 {
+		s.retries = DefaultRetries
+	} else if s.retries < 0 {
+		s.retries = 0
+	}
+	if s.backoff <= 0 {
+		s.backoff = DefaultBackoff
+	}
+	if s.breakerThreshold <= 0 {
+		s.breakerThreshold = DefaultBreakerThreshold
+	}
+	if s.breakerCooldown <= 0 {
+		s.breakerCooldown = DefaultBreakerCooldown
+	}
+
+	return s
+}
+
+// Fetch returns the robot inventory, a JSON array, from the upstream. It
+// returns ErrCircuitOpen without a request while the breaker is open
+func (c *Client) Fetch(ctx context.Context) ([]byte, error) {
+	s := c.settings()
+	now := time.Now()
+
+	c.mu.Lock()
+	if now.Before(c.openUntil) {
+		c.mu.Unlock()
+		return nil, ErrCircuitOpen
+	}
+	if c.failures >= s.breakerThreshold {
+		// half open: this fetch checks the upstream while the others keep
+		// getting ErrCircuitOpen
+		c.openUntil = now.Add(s.breakerCooldown)
+	}
+	c.mu.Unlock()
+
+	body, err := c.fetch(ctx, s)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		c.failures, c.openUntil = 0, time.Time{}
+		return body, nil
+	}
+
+	c.failures++
+	if c.failures >= s.breakerThreshold {
+		c.openUntil = time.Now().Add(s.breakerCooldown)
+		logrus.WithFields(logrus.Fields{
+			"Endpoint": c.Endpoint,
+			"failures": c.failures,
+			"until":    c.openUntil,
+		}).Info("Robot cpu circuit open")
+	}
+
+	return nil, err
+}
+
+// fetch gets the inventory, retrying failed attempts after a random wait
+func (c *Client) fetch(ctx context.Context, s settings) ([]byte, error) {
+	var err error
+	for attempt := 0; attempt <= s.retries; attempt++ {
+		if attempt > 0 {
+			wait := time.Duration(rand.Int63n(int64(s.backoff<<uint(attempt-1)) + 1))
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+
+		var body []byte
+		var retry bool
+		body, retry, err = c.get(ctx, s)
+		if err == nil {
+			return body, nil
+		}
+		logrus.WithFields(logrus.Fields{
+			"Error":    err,
+			"Endpoint": c.Endpoint,
+			"attempt":  attempt + 1,
+		}).Info("Error GET")
+		if !retry {
+			break
+		}
+	}
+
+	return nil, err
+}
+
+// get makes one attempt. retry is false when another attempt would not help
+func (c *Client) get(ctx context.Context, s settings) (body []byte, retry bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Endpoint, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	client := c.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: s.timeout}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxBodyBytes))
+		retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return nil, retry, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	body, err = ioutil.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	if err != nil {
+		return nil, true, err
+	}
+	var robots []json.RawMessage
+	if err = json.Unmarshal(body, &robots); err != nil {
+		return nil, true, fmt.Errorf("robot cpu upstream returned an invalid inventory: %v", err)
+	}
+
+	return body, false, nil
+}
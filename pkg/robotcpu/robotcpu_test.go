@@ -0,0 +1,105 @@
+package robotcpu
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const robotsJSON = `[{"model":"AX-1","serialNumber":"S1","manufacturedDate":"2021-09-03T11:04:17Z","category":"Land"}]`
+
+// upstream returns a server answering with the statuses in turn, the last
+// one for every request after them, and counts the requests
+func upstream(t *testing.T, statuses ...int) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&requests, 1))
+		status := statuses[len(statuses)-1]
+		if n <= len(statuses) {
+			status = statuses[n-1]
+		}
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			w.Write([]byte(robotsJSON))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+// TestClient_Fetch checks if failed attempts are retried and every fetch
+// reaches the upstream
+func TestClient_Fetch(t *testing.T) {
+	server, requests := upstream(t, http.StatusBadGateway, http.StatusOK)
+	client := &Client{Endpoint: server.URL, Backoff: time.Millisecond}
+
+	for i := 0; i < 2; i++ {
+		body, err := client.Fetch(context.Background())
+		if err != nil || string(body) != robotsJSON {
+			t.Errorf("Client.Fetch(): want: %v, got: %s %v", robotsJSON, body, err)
+		}
+	}
+	if got := atomic.LoadInt32(requests); got != 3 {
+		t.Errorf("Client.Fetch(): want: %v requests, got: %v", 3, got)
+	}
+}
+
+// TestClient_Fetch_Breaker checks if the breaker opens after the failures and
+// stops the requests
+func TestClient_Fetch_Breaker(t *testing.T) {
+	server, requests := upstream(t, http.StatusOK, http.StatusServiceUnavailable)
+	client := &Client{Endpoint: server.URL, Retries: 1, Backoff: time.Millisecond,
+		BreakerThreshold: 2, BreakerCooldown: time.Hour}
+
+	if _, err := client.Fetch(context.Background()); err != nil {
+		t.Fatalf("Client.Fetch(): want: %v, got: %v", nil, err)
+	}
+	for i := 0; i < 2; i++ {
+		_, err := client.Fetch(context.Background())
+		if statusErr, ok := err.(*StatusError); !ok || statusErr.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Client.Fetch() - %d: want: %v, got: %v", i, http.StatusServiceUnavailable, err)
+		}
+	}
+	if _, err := client.Fetch(context.Background()); err != ErrCircuitOpen {
+		t.Errorf("Client.Fetch(): want: %v, got: %v", ErrCircuitOpen, err)
+	}
+	// 1 good request, then 2 fetches of 2 attempts before the breaker opens
+	if got := atomic.LoadInt32(requests); got != 5 {
+		t.Errorf("Client.Fetch(): want: %v requests, got: %v", 5, got)
+	}
+}
+
+// TestClient_Fetch_Errors checks if failed fetches return their errors
+func TestClient_Fetch_Errors(t *testing.T) {
+	server, requests := upstream(t, http.StatusNotFound)
+	client := &Client{Endpoint: server.URL, Retries: 3, Backoff: time.Millisecond, BreakerThreshold: 1, BreakerCooldown: time.Hour}
+
+	_, err := client.Fetch(context.Background())
+	if statusErr, ok := err.(*StatusError); !ok || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Client.Fetch(): want: %v, got: %v", http.StatusNotFound, err)
+	}
+	if _, err = client.Fetch(context.Background()); err != ErrCircuitOpen {
+		t.Errorf("Client.Fetch(): want: %v, got: %v", ErrCircuitOpen, err)
+	}
+	// a 404 is not retried
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Errorf("Client.Fetch(): want: %v requests, got: %v", 1, got)
+	}
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+	client = &Client{Endpoint: slow.URL, Timeout: 10 * time.Millisecond, Retries: 1, Backoff: time.Millisecond}
+	start := time.Now()
+	if _, err = client.Fetch(context.Background()); err == nil || time.Since(start) > 500*time.Millisecond {
+		t.Errorf("Client.Fetch(): want: a timeout, got: %v after %v", err, time.Since(start))
+	}
+}
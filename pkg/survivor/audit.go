@@ -0,0 +1,137 @@
+package survivor
+
+import (
+	"net/http"
+	"robo-apocalypse/pkg/auth"
+	"robo-apocalypse/pkg/survivordb"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ActorHeader is the request header naming who makes a change
+const ActorHeader = "X-Actor"
+
+// anonymousActor is recorded when a request does not name its actor
+const anonymousActor = "anonymous"
+
+// requestActor returns who is making the changes of a request: the name of
+// its API key, or the X-Actor header when it was not authenticated
+func requestActor(r *http.Request) survivordb.Actor {
+	name := strings.TrimSpace(r.Header.Get(ActorHeader))
+	if key, ok := auth.KeyFromContext(r.Context()); ok {
+		name = key.Name
+	}
+	if name == "" {
+		name = anonymousActor
+	}
+
+	return survivordb.Actor{Name: name, RemoteAddr: r.RemoteAddr}
+}
+
+// swagger:parameters getAudit
+type AuditParam struct {
+	// Only return the events of the survivor with this id
+	//
+	// in: query
+	// example: survivor=HD138VOP34219
+	Survivor string `json:"survivor"`
+
+	// Only return events recorded at or after this RFC 3339 time
+	//
+	// in: query
+	// example: since=2022-03-11T00:00:00Z
+	Since string `json:"since"`
+
+	// Maximum number of events to return, at most 1000
+	//
+	// in: query
+	// example: limit=100
+	Limit int `json:"limit"`
+
+	// Only return events after the one with this id, as returned in the
+	// X-Next-Cursor header of the previous page
+	//
+	// in: query
+	// example: after=42
+	After int64 `json:"after"`
+}
+
+// maxAuditLimit largest page of audit events returned at once
+const maxAuditLimit = 1000
+
+// swagger:route GET /audit audit getAudit
+// Return a page of the changes made to survivors in the order they happened,
+// with who made them and the fields that changed. When there are more events
+// the X-Next-Cursor response header holds the after of the next page
+// responses:
+//	200: auditResponse
+//	400:
+//	500:
+
+// Audit handles GET requests and returns the audit events
+func (a *Apocalypse) Audit(w http.ResponseWriter, r *http.Request) {
+	logrus.Info("Apocalypse.Audit")
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	var since time.Time
+	if v := query.Get("since"); v != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, v); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"since": v,
+			}).Info("Error parsing time")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := maxAuditLimit
+	if v := query.Get("limit"); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"limit": v,
+			}).Info("Error parsing limit")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if limit > maxAuditLimit {
+			limit = maxAuditLimit
+		}
+	}
+	var after int64
+	if v := query.Get("after"); v != "" {
+		var err error
+		if after, err = strconv.ParseInt(v, 10, 64); err != nil || after < 0 {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"after": v,
+			}).Info("Error parsing after")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	// one event more than the page tells if there is a next page
+	events := a.DB.GetAuditEvents(query.Get("survivor"), since, after, limit+1)
+	if events == nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(events) > limit {
+		events = events[:limit]
+		next := strconv.FormatInt(events[limit-1].ID, 10)
+		w.Header().Set("X-Next-Cursor", next)
+		w.Header().Set("Access-Control-Expose-Headers", "X-Next-Cursor")
+	}
+	writeJSON(w, http.StatusOK, events)
+}
@@ -0,0 +1,89 @@
+package survivor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"robo-apocalypse/pkg/survivordb"
+	"strings"
+	"testing"
+)
+
+// TestApocalypseApi_Audit checks if the api endpoint
+// returns the changes made through the api with their actor
+func TestApocalypseApi_Audit(t *testing.T) {
+	robo := &Apocalypse{}
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	if db == nil {
+		return
+	}
+	robo.DB = db
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("Error setting up database: %v", err)
+		return
+	}
+
+	addw := httptest.NewRecorder()
+	addr := httptest.NewRequest(http.MethodPost, "/survivors", strings.NewReader(survivorRequest))
+	addr.Header.Set(ActorHeader, "medic")
+	robo.Survivor(addw, addr)
+
+	locw := httptest.NewRecorder()
+	locr := httptest.NewRequest(http.MethodPut, "/survivors/location", strings.NewReader(updaterLocationRequest))
+	robo.UpdateLocation(locw, locr)
+
+	testCases := []struct {
+		url     string
+		want    int
+		actions []string
+	}{
+		{"/audit", http.StatusOK, []string{survivordb.AuditCreate, survivordb.AuditUpdateLocation}},
+		{"/audit?survivor=HD138VOP34219&since=2022-03-11T00:00:00Z", http.StatusOK, []string{survivordb.AuditCreate, survivordb.AuditUpdateLocation}},
+		{"/audit?survivor=UNKNOWN", http.StatusOK, []string{}},
+		{"/audit?limit=1", http.StatusOK, []string{survivordb.AuditCreate}},
+		{"/audit?after=1", http.StatusOK, []string{survivordb.AuditUpdateLocation}},
+		{"/audit?since=yesterday", http.StatusBadRequest, nil},
+		{"/audit?limit=0", http.StatusBadRequest, nil},
+		{"/audit?after=first", http.StatusBadRequest, nil},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		robo.Audit(w, r)
+		if w.Code != tc.want {
+			t.Errorf("Apocalypse.Audit(w http.ResponseWriter, r *http.Request) - %q: want: %v, got: %v", tc.url, tc.want, w.Code)
+			continue
+		}
+		if tc.actions == nil {
+			continue
+		}
+		events := []survivordb.AuditEvent{}
+		if err := json.NewDecoder(w.Body).Decode(&events); err != nil || len(events) != len(tc.actions) {
+			t.Errorf("Apocalypse.Audit(w http.ResponseWriter, r *http.Request) - %q: want: %v, got: %v %v", tc.url, tc.actions, events, err)
+			continue
+		}
+		for i, action := range tc.actions {
+			if events[i].Action != action {
+				t.Errorf("Apocalypse.Audit(w http.ResponseWriter, r *http.Request) - %q: want: %v, got: %v", tc.url, action, events[i].Action)
+			}
+		}
+		if len(events) == 2 && (events[0].Actor != "medic" || events[1].Actor != anonymousActor || events[0].RemoteAddr == "") {
+			t.Errorf("Apocalypse.Audit(w http.ResponseWriter, r *http.Request) - %q: actors: got: %v", tc.url, events)
+		}
+	}
+
+	// the next page starts after the last event of a full page
+	w := httptest.NewRecorder()
+	robo.Audit(w, httptest.NewRequest(http.MethodGet, "/audit?limit=1", nil))
+	if next := w.Header().Get("X-Next-Cursor"); next != "1" {
+		t.Errorf("Apocalypse.Audit(w http.ResponseWriter, r *http.Request) - X-Next-Cursor: want: %v, got: %q", 1, next)
+	}
+	w = httptest.NewRecorder()
+	robo.Audit(w, httptest.NewRequest(http.MethodGet, "/audit?after=1", nil))
+	if next := w.Header().Get("X-Next-Cursor"); next != "" {
+		t.Errorf("Apocalypse.Audit(w http.ResponseWriter, r *http.Request) - X-Next-Cursor: want: %q, got: %q", "", next)
+	}
+}
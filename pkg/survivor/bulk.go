@@ -0,0 +1,192 @@
+package survivor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"robo-apocalypse/pkg/auth"
+	"robo-apocalypse/pkg/survivordb"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxImportBytes is the largest import file accepted
+const maxImportBytes = 32 << 20
+
+// formatContentTypes are the content types of the import and export formats
+var formatContentTypes = map[string]string{
+	survivordb.FormatCSV:    "text/csv",
+	survivordb.FormatNDJSON: "application/x-ndjson",
+}
+
+// swagger:parameters importSurvivors
+type ImportParam struct {
+	// csv or ndjson. When it is empty the format is taken from the
+	// Content-Type, text/csv or application/x-ndjson, and is csv otherwise
+	//
+	// in: query
+	// example: format=ndjson
+	Format string `json:"format"`
+
+	// Only check the survivors and report the errors, storing nothing
+	//
+	// in: query
+	// example: dryRun=true
+	DryRun bool `json:"dryRun"`
+
+	// Survivors in CSV with a header row naming the columns, or one survivor
+	// JSON object per line. The CSV columns are those of the export, of
+	// which only id is required
+	//
+	// in: body
+	Body string
+}
+
+// swagger:parameters exportSurvivors
+type ExportParam struct {
+	// csv or ndjson, csv by default
+	//
+	// in: query
+	// example: format=ndjson
+	Format string `json:"format"`
+}
+
+// importFormat returns the format of an import request
+func importFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	for format, formatType := range formatContentTypes {
+		if contentType == formatType {
+			return format
+		}
+	}
+
+	return survivordb.FormatCSV
+}
+
+// swagger:route POST /survivors/import survivors importSurvivors
+// Register the survivors of a CSV or NDJSON file in one transaction. Every
+// row is checked and nothing is stored when any row is invalid or its id
+// number is registered. Only medics and admins may import infected survivors
+// responses:
+//	200: importResponse
+//	400:
+//	403:
+//	413:
+//	422: importResponse
+//	500:
+
+// ImportSurvivors handles POST requests with a file of survivors to register
+func (a *Apocalypse) ImportSurvivors(w http.ResponseWriter, r *http.Request) {
+	logrus.Info("Apocalypse.ImportSurvivors")
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	defer a.publish()
+
+	dryRun := false
+	var err error
+	if value := r.URL.Query().Get("dryRun"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxImportBytes+1))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Error reading request")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxImportBytes {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	rows, rowErrors, err := survivordb.DecodeSurvivors(bytes.NewReader(body), importFormat(r))
+	if err != nil {
+		writeImportError(w, r, err)
+		return
+	}
+	// only those who may report infections may register infected survivors
+	for _, row := range rows {
+		if row.Survivor.Infected && !auth.Permitted(r.Context(), auth.MedicRoles) {
+			logrus.WithFields(logrus.Fields{
+				"row":        row.Row,
+				"id":         row.Survivor.IdNumber,
+				"remoteAddr": r.RemoteAddr,
+			}).Info("Forbidden infected survivor")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+	}
+
+	result, err := survivordb.ImportRows(a.DB.WithActor(requestActor(r)), rows, rowErrors, dryRun)
+	if err != nil {
+		writeImportError(w, r, err)
+		return
+	}
+
+	if len(result.Errors) > 0 {
+		writeJSON(w, http.StatusUnprocessableEntity, result)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// writeImportError answers an import that failed with err
+func writeImportError(w http.ResponseWriter, r *http.Request, err error) {
+	logrus.WithFields(logrus.Fields{
+		"Error": err,
+		"query": r.URL.RawQuery,
+	}).Info("Error importing survivors")
+	if err == survivordb.ErrUnknownFormat || errors.Is(err, survivordb.ErrInvalidImport) {
+		w.WriteHeader(http.StatusBadRequest)
+	} else {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// swagger:route GET /survivors/export survivors exportSurvivors
+// Return every survivor as a CSV or NDJSON file that /survivors/import reads
+// responses:
+//	200: exportResponse
+//	400:
+
+// ExportSurvivors handles GET requests and returns every survivor as a file
+func (a *Apocalypse) ExportSurvivors(w http.ResponseWriter, r *http.Request) {
+	logrus.Info("Apocalypse.ExportSurvivors")
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = survivordb.FormatCSV
+	}
+	contentType, ok := formatContentTypes[format]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="survivors.`+format+`"`)
+	if err := survivordb.Export(a.DB, w, format); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Error exporting survivors")
+	}
+}
@@ -0,0 +1,134 @@
+package survivor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"robo-apocalypse/pkg/auth"
+	"robo-apocalypse/pkg/survivordb"
+	"strings"
+	"testing"
+)
+
+// TestApocalypseApi_ImportSurvivors checks if the api endpoint imports
+// a file only when every row is valid
+func TestApocalypseApi_ImportSurvivors(t *testing.T) {
+	robo := &Apocalypse{}
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	if db == nil {
+		return
+	}
+	robo.DB = db
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("Error setting up database: %v", err)
+		return
+	}
+
+	valid := "id,name,gender,age\nA1,Jane Doe,Female,30\nA2,John Doe,Male,40\n"
+	testCases := []struct {
+		url         string
+		contentType string
+		body        string
+		want        int
+		imported    int
+		errors      int
+	}{
+		{"/survivors/import?dryRun=true", "text/csv", valid, http.StatusOK, 2, 0},
+		{"/survivors/import", "text/csv", "id,name,gender,age\nA1,Jane Doe,Female,30\nA2,John Doe,Male,old\n", http.StatusUnprocessableEntity, 0, 1},
+		{"/survivors/import", "text/csv", "id,robot\n", http.StatusBadRequest, 0, 0},
+		{"/survivors/import?format=xml", "", valid, http.StatusBadRequest, 0, 0},
+		{"/survivors/import?dryRun=maybe", "", valid, http.StatusBadRequest, 0, 0},
+		{"/survivors/import", "application/x-ndjson", `{"id":"A3","name":"Joe Doe","gender":"Male"}`, http.StatusOK, 1, 0},
+		{"/survivors/import", "", valid, http.StatusOK, 2, 0},
+		{"/survivors/import", "", valid, http.StatusUnprocessableEntity, 0, 2},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, tc.url, strings.NewReader(tc.body))
+		r.Header.Set("Content-Type", tc.contentType)
+		robo.ImportSurvivors(w, r)
+		if w.Code != tc.want {
+			t.Errorf("Apocalypse.ImportSurvivors(w http.ResponseWriter, r *http.Request) - %q: want: %v, got: %v", tc.url, tc.want, w.Code)
+			continue
+		}
+		if tc.want == http.StatusBadRequest {
+			continue
+		}
+		result := survivordb.ImportResult{}
+		if err := json.NewDecoder(w.Body).Decode(&result); err != nil || result.Imported != tc.imported || len(result.Errors) != tc.errors {
+			t.Errorf("Apocalypse.ImportSurvivors(w http.ResponseWriter, r *http.Request) - %q: got: %+v %v", tc.url, result, err)
+		}
+	}
+	if survivors := robo.DB.GetAllSurvivors(); len(survivors) != 3 {
+		t.Errorf("Apocalypse.ImportSurvivors(w http.ResponseWriter, r *http.Request): want: %v, got: %v", 3, len(survivors))
+	}
+
+	// only medics and admins may import infected survivors
+	for _, tc := range []struct {
+		role string
+		want int
+	}{
+		{survivordb.RoleScout, http.StatusForbidden},
+		{survivordb.RoleMedic, http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/survivors/import", strings.NewReader(`{"id":"A4","name":"Joe Doe","gender":"Male","infected":true}`))
+		r.Header.Set("Content-Type", "application/x-ndjson")
+		r = r.WithContext(auth.NewContext(r.Context(), &survivordb.APIKey{Name: "jane", Role: tc.role}))
+		robo.ImportSurvivors(w, r)
+		if w.Code != tc.want {
+			t.Errorf("Apocalypse.ImportSurvivors(w http.ResponseWriter, r *http.Request) - %s: want: %v, got: %v", tc.role, tc.want, w.Code)
+		}
+	}
+}
+
+// TestApocalypseApi_ExportSurvivors checks if the api endpoint returns every
+// survivor in the format asked for
+func TestApocalypseApi_ExportSurvivors(t *testing.T) {
+	robo := &Apocalypse{}
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	if db == nil {
+		return
+	}
+	robo.DB = db
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("Error setting up database: %v", err)
+		return
+	}
+
+	addw := httptest.NewRecorder()
+	addr := httptest.NewRequest(http.MethodPost, "/survivors", strings.NewReader(survivorRequest))
+	robo.Survivor(addw, addr)
+
+	testCases := []struct {
+		url         string
+		want        int
+		contentType string
+		lines       int
+	}{
+		{"/survivors/export", http.StatusOK, "text/csv", 2},
+		{"/survivors/export?format=ndjson", http.StatusOK, "application/x-ndjson", 1},
+		{"/survivors/export?format=xml", http.StatusBadRequest, "", 0},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		robo.ExportSurvivors(w, r)
+		if w.Code != tc.want {
+			t.Errorf("Apocalypse.ExportSurvivors(w http.ResponseWriter, r *http.Request) - %q: want: %v, got: %v", tc.url, tc.want, w.Code)
+			continue
+		}
+		if tc.want != http.StatusOK {
+			continue
+		}
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		if w.Header().Get("Content-Type") != tc.contentType || len(lines) != tc.lines {
+			t.Errorf("Apocalypse.ExportSurvivors(w http.ResponseWriter, r *http.Request) - %q: got: %q %q", tc.url, w.Header().Get("Content-Type"), w.Body.String())
+		}
+	}
+}
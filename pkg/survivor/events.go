@@ -0,0 +1,146 @@
+package survivor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"robo-apocalypse/pkg/events"
+	"robo-apocalypse/pkg/survivordb"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// eventsHeartbeat is how often a comment is sent to keep idle streams open
+const eventsHeartbeat = 15 * time.Second
+
+// eventsRetry is how long clients wait before reconnecting, in milliseconds
+const eventsRetry = 3000
+
+// swagger:parameters getEvents
+type EventsParam struct {
+	// Replay the events after this event id before streaming new ones. The
+	// Last-Event-ID header sent by reconnecting clients takes precedence
+	//
+	// in: query
+	// required: false
+	LastEventID int64 `json:"lastEventId"`
+}
+
+// swagger:route GET /events events getEvents
+// Stream survivor.created, survivor.infected, location.updated,
+// resources.updated and survivor.deleted events as Server-Sent Events. Each event has the id of
+// the change it comes from, so reconnecting with Last-Event-ID replays the
+// events that were missed
+// responses:
+//	200: eventsResponse
+//	400:
+//	503:
+
+// Events streams survivor events to the client until it disconnects
+func (a *Apocalypse) Events(w http.ResponseWriter, r *http.Request) {
+	logrus.Info("Apocalypse.Events")
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok || a.Hub == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	var lastID int64 = -1
+	if lastEventID != "" {
+		var err error
+		if lastID, err = strconv.ParseInt(lastEventID, 10, 64); err != nil || lastID < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	// subscribe before replaying so that no event falls between the two
+	sub := a.Hub.Subscribe()
+	defer a.Hub.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Add("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", eventsRetry)
+	flusher.Flush()
+
+	if lastID >= 0 {
+		var err error
+		lastID, err = events.Replay(a.DB, lastID, func(event survivordb.SurvivorEvent) error {
+			return writeEvent(w, event)
+		})
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+			}).Info("Error replaying events")
+			return
+		}
+		flusher.Flush()
+	}
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				// the client fell behind or the server is shutting down, the
+				// client reconnects with its Last-Event-ID
+				return
+			}
+			if event.ID <= lastID {
+				continue
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes a survivor event in the Server-Sent Events format
+func writeEvent(w http.ResponseWriter, event survivordb.SurvivorEvent) error {
+	buffer, err := json.Marshal(event)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"body":  event,
+			"Error": err,
+		}).Error("Marshal")
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, buffer)
+
+	return err
+}
+
+// publish sends the survivor changes made so far to the event subscribers
+func (a *Apocalypse) publish() {
+	if a.Hub == nil {
+		return
+	}
+	if err := a.Hub.Sync(a.DB); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Error publishing events")
+	}
+}
@@ -0,0 +1,99 @@
+package survivor
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"robo-apocalypse/pkg/events"
+	"robo-apocalypse/pkg/survivordb"
+	"strings"
+	"testing"
+)
+
+// readEvent reads the next event of a Server-Sent Events stream, skipping
+// comments and retry fields
+func readEvent(reader *bufio.Reader) map[string]string {
+	event := map[string]string{}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return event
+		}
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			if _, ok := event["id"]; ok {
+				return event
+			}
+			continue
+		}
+		if parts := strings.SplitN(line, ": ", 2); len(parts) == 2 && parts[0] != "" {
+			event[parts[0]] = parts[1]
+		}
+	}
+}
+
+// TestApocalypseApi_Events checks if survivor changes are streamed and
+// missed events are replayed from Last-Event-ID
+func TestApocalypseApi_Events(t *testing.T) {
+	robo := &Apocalypse{}
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	if db == nil {
+		return
+	}
+	robo.DB = db
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("Error setting up database: %v", err)
+		return
+	}
+	robo.Hub = events.NewHub(0)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/survivors", strings.NewReader(survivorRequest))
+	robo.Survivor(w, r)
+
+	server := httptest.NewServer(http.HandlerFunc(robo.Events))
+	defer server.Close()
+	defer robo.Hub.Close()
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	request.Header.Set("Last-Event-ID", "0")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Errorf("Apocalypse.Events(w http.ResponseWriter, r *http.Request): %v", err)
+		return
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Apocalypse.Events(w http.ResponseWriter, r *http.Request): want: %v, got: %v %v",
+			http.StatusOK, response.StatusCode, response.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(response.Body)
+
+	event := readEvent(reader)
+	if event["id"] != "1" || event["event"] != survivordb.EventSurvivorCreated || !strings.Contains(event["data"], "HD138VOP34219") {
+		t.Errorf("Apocalypse.Events(w http.ResponseWriter, r *http.Request): replay: got: %v", event)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, "/survivors/location", strings.NewReader(updaterLocationRequest))
+	robo.UpdateLocation(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("Apocalypse.UpdateLocation(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusOK, w.Code)
+	}
+
+	event = readEvent(reader)
+	if event["id"] != "2" || event["event"] != survivordb.EventLocationUpdated {
+		t.Errorf("Apocalypse.Events(w http.ResponseWriter, r *http.Request): live: got: %v", event)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/events", nil)
+	r.Header.Set("Last-Event-ID", "x")
+	robo.Events(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Apocalypse.Events(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,68 @@
+package survivor
+
+import (
+	"net/http"
+	"robo-apocalypse/pkg/survivordb"
+
+	"github.com/sirupsen/logrus"
+)
+
+// swagger:parameters getStatsHistory
+type StatsHistoryParam struct {
+	// Only return snapshots taken at or after this RFC 3339 time
+	//
+	// in: query
+	// example: from=2022-03-11T00:00:00Z
+	From string `json:"from"`
+
+	// Only return snapshots taken at or before this RFC 3339 time
+	//
+	// in: query
+	// example: to=2022-03-12T00:00:00Z
+	To string `json:"to"`
+
+	// Return only the last snapshot of each hour, day, week or month.
+	// Every snapshot is returned when it is empty
+	//
+	// in: query
+	// example: bucket=day
+	Bucket string `json:"bucket"`
+}
+
+// swagger:route GET /survivors/stats/history survivors getStatsHistory
+// Return the time ordered survivor counts and resource totals recorded by
+// the stats snapshotter
+// responses:
+//	200: statsHistoryResponse
+//	400:
+//	500:
+
+// StatsHistory handles GET requests and returns the stats snapshots
+func (a *Apocalypse) StatsHistory(w http.ResponseWriter, r *http.Request) {
+	logrus.Info("Apocalypse.StatsHistory")
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	from, to, ok := timeRange(w, r)
+	if !ok {
+		return
+	}
+
+	snapshots, err := a.DB.GetStatsHistory(from, to, r.URL.Query().Get("bucket"))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"query": r.URL.RawQuery,
+		}).Info("Error reading stats history")
+		if err == survivordb.ErrInvalidQuery {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, snapshots)
+}
@@ -0,0 +1,62 @@
+package survivor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"robo-apocalypse/pkg/survivordb"
+	"strings"
+	"testing"
+)
+
+// TestApocalypseApi_StatsHistory checks if the api endpoint
+// returns the recorded stats snapshots
+func TestApocalypseApi_StatsHistory(t *testing.T) {
+	robo := &Apocalypse{}
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	if db == nil {
+		return
+	}
+	robo.DB = db
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("Error setting up database: %v", err)
+		return
+	}
+
+	addreader := strings.NewReader(survivorRequest)
+	addw := httptest.NewRecorder()
+	addr := httptest.NewRequest(http.MethodPost, "/survivors", addreader)
+	robo.Survivor(addw, addr)
+	if err = robo.DB.SaveStatsSnapshot(); err != nil {
+		t.Errorf("SurvivorDB.SaveStatsSnapshot(): want: %v, got: %v", nil, err)
+	}
+
+	testCases := []struct {
+		url  string
+		want int
+	}{
+		{"/survivors/stats/history", http.StatusOK},
+		{"/survivors/stats/history?bucket=day&from=2022-03-11T00:00:00Z", http.StatusOK},
+		{"/survivors/stats/history?bucket=year", http.StatusBadRequest},
+		{"/survivors/stats/history?from=yesterday", http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		robo.StatsHistory(w, r)
+		if w.Code != tc.want {
+			t.Errorf("Apocalypse.StatsHistory(w http.ResponseWriter, r *http.Request) - %q: want: %v, got: %v", tc.url, tc.want, w.Code)
+			continue
+		}
+		if tc.want != http.StatusOK {
+			continue
+		}
+		snapshots := []survivordb.StatsSnapshot{}
+		if err := json.NewDecoder(w.Body).Decode(&snapshots); err != nil || len(snapshots) != 1 || snapshots[0].Healthy != 1 {
+			t.Errorf("Apocalypse.StatsHistory(w http.ResponseWriter, r *http.Request) - %q: got: %v %v", tc.url, snapshots, err)
+		}
+	}
+}
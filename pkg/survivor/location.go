@@ -0,0 +1,104 @@
+package survivor
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// swagger:parameters getLocations
+type LocationHistoryParam struct {
+	// The id of the survivor
+	//
+	// in: path
+	// required: true
+	IdNumber string `json:"id"`
+
+	// Only return locations recorded at or after this RFC 3339 time
+	//
+	// in: query
+	// example: from=2022-03-11T00:00:00Z
+	From string `json:"from"`
+
+	// Only return locations recorded at or before this RFC 3339 time
+	//
+	// in: query
+	// example: to=2022-03-12T00:00:00Z
+	To string `json:"to"`
+}
+
+// swagger:route GET /survivors/{id}/locations survivors getLocations
+// Return the time ordered location track of a survivor
+// responses:
+//	200: locationsResponse
+//	400:
+//	404:
+
+// LocationHistory handles GET requests and returns the locations of a survivor
+func (a *Apocalypse) LocationHistory(w http.ResponseWriter, r *http.Request, idNumber string) {
+	logrus.Info("Apocalypse.LocationHistory")
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	from, to, ok := timeRange(w, r)
+	if !ok {
+		return
+	}
+
+	if a.DB.GetSurvivor(idNumber) == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	locations := a.DB.GetLocationHistory(idNumber, from, to)
+	if locations == nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	locationsBuffer, err := json.Marshal(locations)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"body":  locations,
+			"Error": err,
+		}).Error("Marshal")
+		return
+	}
+
+	w.Header().Add("Access-Control-Allow-Origin", "*")
+	w.Write(locationsBuffer)
+}
+
+// timeRange parses the optional RFC 3339 from and to query parameters,
+// writing a 400 response and returning false when either is malformed
+func timeRange(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	query := r.URL.Query()
+	var from, to time.Time
+	var err error
+	if v := query.Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"from":  v,
+			}).Info("Error parsing time")
+			w.WriteHeader(http.StatusBadRequest)
+			return from, to, false
+		}
+	}
+	if v := query.Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"to":    v,
+			}).Info("Error parsing time")
+			w.WriteHeader(http.StatusBadRequest)
+			return from, to, false
+		}
+	}
+
+	return from, to, true
+}
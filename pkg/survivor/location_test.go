@@ -0,0 +1,67 @@
+package survivor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"robo-apocalypse/pkg/survivordb"
+	"strings"
+	"testing"
+)
+
+// TestApocalypseApi_LocationHistory checks if the api endpoint
+// returns the location track of a survivor
+func TestApocalypseApi_LocationHistory(t *testing.T) {
+	robo := &Apocalypse{}
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	if db == nil {
+		return
+	}
+	robo.DB = db
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("Error setting up database: %v", err)
+		return
+	}
+
+	addreader := strings.NewReader(survivorRequest)
+	addw := httptest.NewRecorder()
+	addr := httptest.NewRequest(http.MethodPost, "/survivors", addreader)
+	robo.Survivor(addw, addr)
+
+	reader := strings.NewReader(updaterLocationRequest)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/survivors/location", reader)
+	robo.UpdateLocation(w, r)
+
+	testCases := []struct {
+		url  string
+		want int
+	}{
+		{url: "/survivors/HD138VOP34219/locations", want: http.StatusOK},
+		{url: "/survivors/HD138VOP34219/locations?from=yesterday", want: http.StatusBadRequest},
+		{url: "/survivors/UNKNOWN/locations", want: http.StatusNotFound},
+		{url: "/survivors/HD138VOP34219/elsewhere", want: http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		robo.SurvivorByID(w, r)
+		if w.Code != tc.want {
+			t.Errorf("Apocalypse.SurvivorByID(w http.ResponseWriter, r *http.Request) - %q: want: %v, got: %v", tc.url, tc.want, w.Code)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/survivors/HD138VOP34219/locations", nil)
+	robo.SurvivorByID(w, r)
+	locations := []survivordb.LocationRecord{}
+	if err := json.NewDecoder(w.Body).Decode(&locations); err != nil {
+		t.Errorf("Apocalypse.LocationHistory(w http.ResponseWriter, r *http.Request): could not decode response: %v", err)
+	}
+	if len(locations) != 2 || locations[1].Longitude != 1 || locations[1].Latitude != 2 {
+		t.Errorf("Apocalypse.LocationHistory(w http.ResponseWriter, r *http.Request): got: %v", locations)
+	}
+}
@@ -0,0 +1,108 @@
+package survivor
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// swagger:parameters getNearby
+type NearbyParam struct {
+	// Latitude of the point to search around
+	//
+	// in: query
+	// required: true
+	// example: lat=-17.82
+	Lat float64 `json:"lat"`
+
+	// Longitude of the point to search around
+	//
+	// in: query
+	// required: true
+	// example: lon=31.05
+	Lon float64 `json:"lon"`
+
+	// Search radius in km
+	//
+	// in: query
+	// required: true
+	// example: radius=5
+	Radius float64 `json:"radius"`
+
+	// Only return infected (true) or healthy (false) survivors
+	//
+	// in: query
+	// example: infected=false
+	Infected string `json:"infected"`
+}
+
+// swagger:route GET /survivors/nearby survivors getNearby
+// Return the survivors within a radius of a point, nearest first
+// responses:
+//	200: nearbyResponse
+//	400:
+
+// NearbySurvivors handles GET requests and returns survivors near a point
+func (a *Apocalypse) NearbySurvivors(w http.ResponseWriter, r *http.Request) {
+	logrus.Info("Apocalypse.NearbySurvivors")
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	latitude, err := strconv.ParseFloat(query.Get("lat"), 64)
+	if err != nil || latitude < -90 || latitude > 90 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	longitude, err := strconv.ParseFloat(query.Get("lon"), 64)
+	if err != nil || longitude < -180 || longitude > 180 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	radius, err := strconv.ParseFloat(query.Get("radius"), 64)
+	if err != nil || radius <= 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var infected *bool
+	switch query.Get("infected") {
+	case "true":
+		infected = new(bool)
+		*infected = true
+	case "false":
+		infected = new(bool)
+	case "":
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	nearby := a.DB.GetNearbySurvivors(latitude, longitude, radius, infected)
+	if nearby == nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	nearbyBuffer, err := json.Marshal(nearby)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"body":  nearby,
+			"Error": err,
+		}).Error("Marshal")
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"lat":    latitude,
+		"lon":    longitude,
+		"radius": radius,
+		"count":  len(nearby),
+	}).Info("Data")
+
+	w.Header().Add("Access-Control-Allow-Origin", "*")
+	w.Write(nearbyBuffer)
+}
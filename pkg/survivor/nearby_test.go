@@ -0,0 +1,62 @@
+package survivor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"robo-apocalypse/pkg/survivordb"
+	"strings"
+	"testing"
+)
+
+// TestApocalypseApi_NearbySurvivors checks if the api endpoint
+// validates its parameters and returns survivors with their distance
+func TestApocalypseApi_NearbySurvivors(t *testing.T) {
+	robo := &Apocalypse{}
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	if db == nil {
+		return
+	}
+	robo.DB = db
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("Error setting up database: %v", err)
+		return
+	}
+
+	addreader := strings.NewReader(survivorRequest)
+	addw := httptest.NewRecorder()
+	addr := httptest.NewRequest(http.MethodPost, "/survivors", addreader)
+	robo.Survivor(addw, addr)
+
+	testCases := []struct {
+		url  string
+		want int
+	}{
+		{url: "/survivors/nearby?lat=0&lon=0&radius=5", want: http.StatusOK},
+		{url: "/survivors/nearby?lat=900&lon=0&radius=5", want: http.StatusBadRequest},
+		{url: "/survivors/nearby?lat=0&lon=0", want: http.StatusBadRequest},
+		{url: "/survivors/nearby?lat=0&lon=0&radius=5&infected=maybe", want: http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		robo.NearbySurvivors(w, r)
+		if w.Code != tc.want {
+			t.Errorf("Apocalypse.NearbySurvivors(w http.ResponseWriter, r *http.Request) - %q: want: %v, got: %v", tc.url, tc.want, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/survivors/nearby?lat=0.01&lon=0&radius=5&infected=false", nil)
+	robo.NearbySurvivors(w, r)
+	nearby := []survivordb.NearbySurvivor{}
+	if err := json.NewDecoder(w.Body).Decode(&nearby); err != nil {
+		t.Errorf("Apocalypse.NearbySurvivors(w http.ResponseWriter, r *http.Request): could not decode response: %v", err)
+	}
+	if len(nearby) != 1 || nearby[0].IdNumber != "HD138VOP34219" || nearby[0].Distance < 1 {
+		t.Errorf("Apocalypse.NearbySurvivors(w http.ResponseWriter, r *http.Request): got: %v", nearby)
+	}
+}
@@ -6,22 +6,41 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"reflect"
+	"robo-apocalypse/pkg/auth"
+	"robo-apocalypse/pkg/events"
+	"robo-apocalypse/pkg/robotcpu"
 	"robo-apocalypse/pkg/survivordb"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
 )
 
 var TemplateFuncs = template.FuncMap{"rangeStruct": rangeStructer}
 
+// DefaultInfectionThreshold number of independent reports needed to flag a survivor as infected
+const DefaultInfectionThreshold = 3
+
 // Tracker structure of a Tracker object
 type Apocalypse struct {
-	DB               *survivordb.SurvivorDB
-	HTMLTemplate     *template.Template
-	HTMLTemplateName string
+	DB                 survivordb.SurvivorStore
+	HTMLTemplate       *template.Template
+	HTMLTemplateName   string
+	InfectionThreshold int
+	// Hub publishes survivor changes to /events, none are published when nil
+	Hub *events.Hub
+	// Routes serves the REST requests that /ws messages are mirrored to,
+	// so that they pass the same middleware. The handlers are called
+	// directly when nil
+	Routes http.Handler
+	// RobotPoller saves the robots /robotcpu returns from the robot CPU
+	// system. /robotcpu warns that they are stale while the poller does not
+	// reach it; it never does when nil
+	RobotPoller *robotcpu.Poller
 }
 
 // DefaultPath endpoint to the default path
@@ -80,9 +99,14 @@ func (a *Apocalypse) SurvivorStats(w http.ResponseWriter, r *http.Request) {
 		infectedPercentage = float64(infectedCount) / total * 100
 	}
 	stats := &struct {
-		HealthyPercentage  float64 `json:"healthyPercentage"`
-		InfectedPercentage float64 `json:"infectedPercentage"`
-	}{HealthyPercentage: healthyPercentage, InfectedPercentage: infectedPercentage}
+		HealthyPercentage  float64                    `json:"healthyPercentage"`
+		InfectedPercentage float64                    `json:"infectedPercentage"`
+		Inventory          []survivordb.InventoryItem `json:"inventory"`
+	}{
+		HealthyPercentage:  healthyPercentage,
+		InfectedPercentage: infectedPercentage,
+		Inventory:          a.DB.InventoryTotals(),
+	}
 
 	statsBuffer, err := json.Marshal(stats)
 	if err != nil {
@@ -101,10 +125,150 @@ func (a *Apocalypse) SurvivorStats(w http.ResponseWriter, r *http.Request) {
 	w.Write(statsBuffer)
 }
 
+// swagger:route GET /survivors/stats/detailed survivors getDetailedStats
+// Return the average resources per non infected survivor, the resources
+// lost to infected survivors and the survivor counts by gender and age band
+// responses:
+//	200: detailedStatsResponse
+//	500:
+
+// DetailedStats handles GET requests and returns the detailed survivor statistics
+func (a *Apocalypse) DetailedStats(w http.ResponseWriter, r *http.Request) {
+	logrus.Info("Apocalypse.DetailedStats")
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats := a.DB.GetDetailedStats()
+	if stats == nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// swagger:parameters getSurvivors
+type SurvivorsPageParam struct {
+	// Maximum number of survivors to return, at most 1000
+	//
+	// in: query
+	// example: limit=50
+	Limit int `json:"limit"`
+
+	// Cursor returned in the X-Next-Cursor header of the previous page
+	//
+	// in: query
+	Cursor string `json:"cursor"`
+
+	// Sort by name, age or last_ts
+	//
+	// in: query
+	// example: sort=name
+	Sort string `json:"sort"`
+
+	// Sort order, asc or desc
+	//
+	// in: query
+	// example: order=desc
+	Order string `json:"order"`
+
+	// Only return survivors of this gender
+	//
+	// in: query
+	// example: gender=Female
+	Gender string `json:"gender"`
+
+	// Only return survivors at least this old
+	//
+	// in: query
+	MinAge int `json:"minAge"`
+
+	// Only return survivors at most this old
+	//
+	// in: query
+	MaxAge int `json:"maxAge"`
+
+	// Only return infected (true) or healthy (false) survivors
+	//
+	// in: query
+	Infected string `json:"infected"`
+}
+
+// maxPageLimit largest page of survivors returned at once
+const maxPageLimit = 1000
+
+// survivorQuery reads the paging, sorting and filtering parameters of a request
+func survivorQuery(r *http.Request) (*survivordb.SurvivorQuery, error) {
+	query := r.URL.Query()
+	q := &survivordb.SurvivorQuery{
+		Sort:   query.Get("sort"),
+		Gender: query.Get("gender"),
+	}
+
+	var err error
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{
+		{"limit", &q.Limit},
+		{"minAge", &q.MinAge},
+		{"maxAge", &q.MaxAge},
+	} {
+		if v := query.Get(param.name); v != "" {
+			if *param.value, err = strconv.Atoi(v); err != nil || *param.value < 0 {
+				return nil, survivordb.ErrInvalidQuery
+			}
+		}
+	}
+	if q.Limit > maxPageLimit {
+		q.Limit = maxPageLimit
+	}
+	q.Cursor = query.Get("cursor")
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.Desc = true
+	default:
+		return nil, survivordb.ErrInvalidQuery
+	}
+	switch query.Get("infected") {
+	case "":
+	case "true":
+		q.Infected = new(bool)
+		*q.Infected = true
+	case "false":
+		q.Infected = new(bool)
+	default:
+		return nil, survivordb.ErrInvalidQuery
+	}
+
+	return q, nil
+}
+
+// listSurvivors endpoint to Apocalypse
 func (a *Apocalypse) listSurvivors(w http.ResponseWriter, r *http.Request) {
-	logrus.Info("Apocalypse.SurvivorStats")
+	logrus.Info("Apocalypse.listSurvivors")
 
-	survivors := a.DB.GetAllSurvivors()
+	q, err := survivorQuery(r)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"query": r.URL.RawQuery,
+		}).Info("Error parsing query")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	survivors, next, err := a.DB.GetSurvivorsPage(q)
+	if err != nil {
+		if err == survivordb.ErrInvalidQuery {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
 
 	survivorsBuffer, err := json.Marshal(survivors)
 	if err != nil {
@@ -116,16 +280,22 @@ func (a *Apocalypse) listSurvivors(w http.ResponseWriter, r *http.Request) {
 	}
 
 	logrus.WithFields(logrus.Fields{
-		"body": string(survivorsBuffer),
+		"count": len(survivors),
+		"next":  next,
 	}).Info("Data")
 
 	w.Header().Add("Access-Control-Allow-Origin", "*")
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+		w.Header().Set("Access-Control-Expose-Headers", "X-Next-Cursor")
+	}
 	w.Write(survivorsBuffer)
 }
 
 // newSurvivor endpoint to Apocalypse
 func (a *Apocalypse) newSurvivor(w http.ResponseWriter, r *http.Request) {
 	logrus.Info("Apocalypse.newSurvivor")
+	defer a.publish()
 	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
@@ -135,19 +305,37 @@ func (a *Apocalypse) newSurvivor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	survivor := &survivordb.Survivor{}
-	if err := json.Unmarshal(body, survivor); err != nil {
+	if !decodePayload(w, body, survivor) {
+		return
+	}
+	if err := survivor.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	// only those who may report infections may register infected survivors
+	if survivor.Infected && !auth.Permitted(r.Context(), auth.MedicRoles) {
 		logrus.WithFields(logrus.Fields{
-			"Error": err,
-			"body":  string(body),
-		}).Info("Error unmarshalling")
-		w.WriteHeader(http.StatusBadRequest)
+			"id":         survivor.IdNumber,
+			"remoteAddr": r.RemoteAddr,
+		}).Info("Forbidden infected survivor")
+		w.WriteHeader(http.StatusForbidden)
 		return
 	}
 
 	logrus.WithFields(logrus.Fields{
 		"body": survivor,
 	}).Info("Incoming")
-	err = a.DB.Save(survivor)
+	err = a.DB.WithActor(requestActor(r)).Save(survivor)
+	if err == survivordb.ErrDuplicateIdNumber {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"id":    survivor.IdNumber,
+		}).Info("Error saving")
+		w.Header().Set("Location", "/survivors/"+url.PathEscape(survivor.IdNumber))
+		w.Header().Set("Access-Control-Expose-Headers", "Location")
+		writeJSON(w, http.StatusConflict, a.DB.GetSurvivor(survivor.IdNumber))
+		return
+	}
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"Error": err,
@@ -159,17 +347,24 @@ func (a *Apocalypse) newSurvivor(w http.ResponseWriter, r *http.Request) {
 }
 
 // swagger:route GET /survivors survivors getSurvivors
-// Return a list of survivors from the database
+// Return a page of survivors from the database. When there are more
+// survivors the X-Next-Cursor response header holds the cursor of the next page
 // responses:
 //	200: surivivorsResponse
 
 // Survivor handles GET requests and returns all survivors
 
 // swagger:route POST /survivors survivors createSurvivor
-// Create a new Survivor
+// Create a new Survivor. When the id number is already registered the
+// response is 409 with the existing survivor and its Location. Only medics
+// and admins may create infected survivors
 //
 // responses:
 //	200:
+//	400:
+//	403:
+//	409: survivorResponse
+//	422: validationErrorResponse
 //  	500:
 
 // Survivor handles POST requests to add new survivor
@@ -185,16 +380,154 @@ func (a *Apocalypse) Survivor(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// swagger:parameters getSurvivor patchSurvivor deleteSurvivor
+type SurvivorIDParam struct {
+	// The id number of the survivor
+	//
+	// in: path
+	// required: true
+	IdNumber string `json:"id"`
+}
+
+// swagger:route GET /survivors/{id} survivors getSurvivor
+// Return a survivor
+// responses:
+//	200: survivorResponse
+//	404:
+
+// swagger:route PATCH /survivors/{id} survivors patchSurvivor
+// Change some of the fields of a survivor. Setting infected needs the medic
+// or admin role
+// responses:
+//	200: survivorResponse
+//	400:
+//	403:
+//	404:
+//	409:
+//	422: validationErrorResponse
+
+// swagger:route DELETE /survivors/{id} survivors deleteSurvivor
+// Delete a survivor with their inventory, location history and reports
+// responses:
+//	204:
+//	404:
+
+// SurvivorByID handles requests for a single survivor under /survivors/{id}
+func (a *Apocalypse) SurvivorByID(w http.ResponseWriter, r *http.Request) {
+	logrus.Info("Apocalypse.SurvivorByID")
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/survivors/"), "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		a.survivorByID(w, r, parts[0])
+	case len(parts) == 2 && parts[0] != "" && parts[1] == "locations":
+		a.LocationHistory(w, r, parts[0])
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// survivorByID reads, patches or deletes a single survivor
+func (a *Apocalypse) survivorByID(w http.ResponseWriter, r *http.Request, idNumber string) {
+	switch r.Method {
+	case http.MethodGet:
+		survivor := a.DB.GetSurvivor(idNumber)
+		if survivor == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, survivor)
+	case http.MethodPatch:
+		a.patchSurvivor(w, r, idNumber)
+	case http.MethodDelete:
+		if err := a.DB.WithActor(requestActor(r)).DeleteSurvivor(idNumber); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"id":    idNumber,
+			}).Info("Error deleting")
+			if err == survivordb.ErrNotFound {
+				w.WriteHeader(http.StatusNotFound)
+			} else {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+		a.publish()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// patchSurvivor endpoint to change some of the fields of a survivor
+func (a *Apocalypse) patchSurvivor(w http.ResponseWriter, r *http.Request, idNumber string) {
+	logrus.Info("Apocalypse.patchSurvivor")
+	defer a.publish()
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Error reading response")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	patch := &survivordb.SurvivorPatch{}
+	if !decodePayload(w, body, patch) {
+		return
+	}
+	if err := patch.Validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	// only those who may report infections may set them, as on
+	// PUT /survivors/infected
+	if patch.Infected != nil && !auth.Permitted(r.Context(), auth.MedicRoles) {
+		logrus.WithFields(logrus.Fields{
+			"id":         idNumber,
+			"remoteAddr": r.RemoteAddr,
+		}).Info("Forbidden infected patch")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"body": string(body),
+		"id":   idNumber,
+	}).Info("Incoming")
+	survivor, err := a.DB.WithActor(requestActor(r)).PatchSurvivor(idNumber, patch)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"id":    idNumber,
+		}).Info("Error saving")
+		switch err {
+		case survivordb.ErrNotFound:
+			w.WriteHeader(http.StatusNotFound)
+		case survivordb.ErrDuplicateIdNumber:
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, survivor)
+}
+
 // swagger:route PUT /survivors/location survivors updateLocation
-// Return the HTTP response code: 200, 404, 500
+// Return the HTTP response code: 200, 404, 422, 500
 // responses:
 //	200:
+//	400:
 //	404:
+//	422: validationErrorResponse
 //	500:
 
-// UpdateLocation handles PUT requests and returns an HTTP response code
+// UpdateLocation handles PUT requests, records any safe zone the survivor
+// entered or left and returns an HTTP response code
 func (a *Apocalypse) UpdateLocation(w http.ResponseWriter, r *http.Request) {
 	logrus.Info("Apocalypse.UpdateLocation")
+	defer a.publish()
 	if r.Method != http.MethodPut {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
@@ -213,32 +546,42 @@ func (a *Apocalypse) UpdateLocation(w http.ResponseWriter, r *http.Request) {
 		IdNumber string `json:"id"`
 		survivordb.LastLocation
 	}{}
-	if err := json.Unmarshal(body, locationPayload); err != nil {
-		logrus.WithFields(logrus.Fields{
-			"Error": err,
-			"body":  string(body),
-		}).Info("Error unmarshalling")
-		w.WriteHeader(http.StatusBadRequest)
+	if !decodePayload(w, body, locationPayload) {
+		return
+	}
+	if err := survivordb.ValidateLocation(locationPayload.IdNumber, locationPayload.LastLocation); err != nil {
+		writeValidationError(w, err)
 		return
 	}
 
 	logrus.WithFields(logrus.Fields{
 		"body": locationPayload,
 	}).Info("Incoming")
-	err = a.DB.UpdateLocation(locationPayload.IdNumber, locationPayload.Longitude, locationPayload.Latitude)
+	events, err := a.DB.WithActor(requestActor(r)).UpdateLocation(locationPayload.IdNumber, locationPayload.Longitude, locationPayload.Latitude)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"Error":  err,
 			"action": locationPayload,
 		}).Info("Error saving")
-		w.WriteHeader(http.StatusInternalServerError)
+		if err == survivordb.ErrNotFound {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
 		return
 	}
+
+	for _, event := range events {
+		logrus.WithFields(logrus.Fields{
+			"event": event,
+		}).Info("Zone event")
+	}
 }
 
-// updateInfected endpoint to update a survivor location
+// updateInfected endpoint to report a survivor as infected
 func (a *Apocalypse) updateInfected(w http.ResponseWriter, r *http.Request) {
 	logrus.Info("Apocalypse.updateInfected")
+	defer a.publish()
 
 	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
 	if err != nil {
@@ -251,28 +594,52 @@ func (a *Apocalypse) updateInfected(w http.ResponseWriter, r *http.Request) {
 
 	infectedPayload := &struct {
 		IdNumber string `json:"id"`
+		Reporter string `json:"reporter"`
 	}{}
-	if err := json.Unmarshal(body, infectedPayload); err != nil {
-		logrus.WithFields(logrus.Fields{
-			"Error": err,
-			"body":  string(body),
-		}).Info("Error unmarshalling")
-		w.WriteHeader(http.StatusBadRequest)
+	if !decodePayload(w, body, infectedPayload) {
 		return
 	}
 
 	logrus.WithFields(logrus.Fields{
 		"body": infectedPayload,
 	}).Info("Incoming")
-	err = a.DB.UpdateInfected(infectedPayload.IdNumber)
+	if infectedPayload.Reporter == "" || infectedPayload.Reporter == infectedPayload.IdNumber {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	threshold := a.InfectionThreshold
+	if threshold <= 0 {
+		threshold = DefaultInfectionThreshold
+	}
+	report, err := a.DB.WithActor(requestActor(r)).ReportInfection(infectedPayload.Reporter, infectedPayload.IdNumber, threshold)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"Error":  err,
 			"action": infectedPayload,
 		}).Info("Error saving")
-		w.WriteHeader(http.StatusNotFound)
+		switch err {
+		case survivordb.ErrNotFound:
+			w.WriteHeader(http.StatusNotFound)
+		case survivordb.ErrDuplicateReport:
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
 		return
 	}
+
+	reportBuffer, err := json.Marshal(report)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"body":  report,
+			"Error": err,
+		}).Error("Marshal")
+		return
+	}
+
+	w.Header().Add("Access-Control-Allow-Origin", "*")
+	w.Write(reportBuffer)
 }
 
 // listSurvivors endpoint to Apocalypse
@@ -327,13 +694,16 @@ type InfectedStatusParam struct {
 // Infected handles GET requests and returns infected survivors
 
 // swagger:route PUT /survivors/infected survivors setInfected
-// Return the HTTP response code: 200, 404, 500
+// Report a survivor as infected. The survivor is flagged once enough
+// independent reporters have reported them
 // responses:
-//	200:
+//	200: infectionReportResponse
+//	400:
 //	404:
+//	409:
 //	500:
 
-// Infected handles PUT requests and returns an HTTP response code
+// Infected handles PUT requests and returns the current report count
 func (a *Apocalypse) Infected(w http.ResponseWriter, r *http.Request) {
 	logrus.Info("Apocalypse.Infected")
 	switch r.Method {
@@ -347,13 +717,20 @@ func (a *Apocalypse) Infected(w http.ResponseWriter, r *http.Request) {
 }
 
 // swagger:route PUT /survivors/resource survivors updateResource
-// Return the HTTP response code: 200, 404, 500
+// Replace the resources of a survivor, either from the itemised inventory
+// or from the legacy water, food, medication and ammunition fields.
+// Return the HTTP response code: 200, 404, 422, 500
 // responses:
 //	200:
+//	400:
+//	404:
+//	422: validationErrorResponse
+//	500:
 
 // UpdateResources handles PUT requests and returns an HTTP response code
 func (a *Apocalypse) UpdateResources(w http.ResponseWriter, r *http.Request) {
 	logrus.Info("Apocalypse.UpdateResources")
+	defer a.publish()
 	if r.Method != http.MethodPut {
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
@@ -372,30 +749,28 @@ func (a *Apocalypse) UpdateResources(w http.ResponseWriter, r *http.Request) {
 		IdNumber string `json:"id"`
 		survivordb.Resources
 	}{}
-	if err := json.Unmarshal(body, resourcePayload); err != nil {
-		logrus.WithFields(logrus.Fields{
-			"Error": err,
-			"body":  string(body),
-		}).Info("Error unmarshalling")
-		w.WriteHeader(http.StatusBadRequest)
+	if !decodePayload(w, body, resourcePayload) {
+		return
+	}
+	if err := survivordb.ValidateResources(resourcePayload.IdNumber, &resourcePayload.Resources); err != nil {
+		writeValidationError(w, err)
 		return
 	}
 
 	logrus.WithFields(logrus.Fields{
 		"body": resourcePayload,
 	}).Info("Incoming")
-	err = a.DB.UpdateResource(resourcePayload.IdNumber,
-		resourcePayload.Water,
-		resourcePayload.Food,
-		resourcePayload.Medication,
-		resourcePayload.Ammunition,
-	)
+	err = a.DB.WithActor(requestActor(r)).UpdateInventory(resourcePayload.IdNumber, resourcePayload.Items())
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"Error": err,
 			"body":  resourcePayload,
 		}).Info("Error saving")
-		w.WriteHeader(http.StatusNotFound)
+		if err == survivordb.ErrNotFound {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
 		return
 	}
 }
@@ -420,16 +795,9 @@ func (a *Apocalypse) Report(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-type RobotCpu struct {
-	Model            string    `json:"model"`
-	SerialNumber     string    `json:"serialNumber"`
-	ManufacturedDate time.Time `json:"manufacturedDate"`
-	Category         string    `json:"category"`
-}
-
 type RobotCpuSorter struct {
-	robots []RobotCpu
-	by     func(p1, p2 *RobotCpu) bool // Closure used in the Less method.
+	robots []survivordb.Robot
+	by     func(p1, p2 *survivordb.Robot) bool // Closure used in the Less method.
 }
 
 func (c *RobotCpuSorter) Len() int {
@@ -451,7 +819,7 @@ func (c *RobotCpuSorter) Less(i, j int) bool {
 type robotcpuResponseWrapper struct {
 	// All current robotcpus
 	// in: body
-	Body []RobotCpu
+	Body []survivordb.Robot
 }
 
 // swagger:parameters getRobotCPU
@@ -478,10 +846,16 @@ type RobortCategoryCPUParam struct {
 	Category string `json:"category"`
 }
 
-// swagger:route GET /survivors/infected survivors getRobotCPU
-// Returns a list of infected survivors from the database
+// staleWarning is the Warning header of the saved robot cpus while the robot
+// CPU system is down
+const staleWarning = `110 - "Response is Stale"`
+
+// swagger:route GET /robotcpu robots getRobotCPU
+// Returns the robots polled from the robot CPU system with when they were
+// first and last seen
 // responses:
 //	200: robotcpuResponse
+//	500:
 
 // RobotCPU handles GET requests and returns robotCPUs
 func (a *Apocalypse) RobotCPU(w http.ResponseWriter, r *http.Request) {
@@ -491,42 +865,20 @@ func (a *Apocalypse) RobotCPU(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	enpoint := viper.GetString("destEndpoint")
-	resp, err := http.Get(enpoint)
-	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"Error":    err,
-			"Endpoint": enpoint,
-		}).Info("Error GET")
+	robotcpus := &RobotCpuSorter{robots: a.DB.GetRobots()}
+	if robotcpus.robots == nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"Error": err,
-		}).Info("Error reading response")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	robotcpus := &RobotCpuSorter{}
-
-	if err := json.Unmarshal(body, &robotcpus.robots); err != nil {
-		logrus.WithFields(logrus.Fields{
-			"Error": err,
-			"body":  string(body),
-		}).Info("Error unmarshalling")
-		w.WriteHeader(http.StatusBadRequest)
-		return
+	if a.RobotPoller != nil && a.RobotPoller.Stale() {
+		w.Header().Set("Warning", staleWarning)
 	}
 
 	query := r.URL.Query()
 	category := query.Get("category")
 	switch category {
 	case "Flying":
-		var tmp []RobotCpu
+		var tmp []survivordb.Robot
 		for _, robot := range robotcpus.robots {
 			if robot.Category == "Flying" {
 				tmp = append(tmp, robot)
@@ -534,7 +886,7 @@ func (a *Apocalypse) RobotCPU(w http.ResponseWriter, r *http.Request) {
 		}
 		robotcpus.robots = tmp
 	case "Land":
-		var tmp []RobotCpu
+		var tmp []survivordb.Robot
 		for _, robot := range robotcpus.robots {
 			if robot.Category == "Land" {
 				tmp = append(tmp, robot)
@@ -545,29 +897,41 @@ func (a *Apocalypse) RobotCPU(w http.ResponseWriter, r *http.Request) {
 	sortColumn := query.Get("sortby")
 	switch sortColumn {
 	case "model":
-		cmp := func(r1, r2 *RobotCpu) bool {
+		cmp := func(r1, r2 *survivordb.Robot) bool {
 			return r1.Model < r2.Model
 		}
 		robotcpus.by = cmp
 		sort.Sort(robotcpus)
 	case "serialNumber":
-		cmp := func(r1, r2 *RobotCpu) bool {
+		cmp := func(r1, r2 *survivordb.Robot) bool {
 			return r1.SerialNumber < r2.SerialNumber
 		}
 		robotcpus.by = cmp
 		sort.Sort(robotcpus)
 	case "manufacturedDate":
-		cmp := func(r1, r2 *RobotCpu) bool {
+		cmp := func(r1, r2 *survivordb.Robot) bool {
 			return r1.ManufacturedDate.Before(r2.ManufacturedDate)
 		}
 		robotcpus.by = cmp
 		sort.Sort(robotcpus)
 	case "category":
-		cmp := func(r1, r2 *RobotCpu) bool {
+		cmp := func(r1, r2 *survivordb.Robot) bool {
 			return r1.Category < r2.Category
 		}
 		robotcpus.by = cmp
 		sort.Sort(robotcpus)
+	case "firstSeen":
+		cmp := func(r1, r2 *survivordb.Robot) bool {
+			return r1.FirstSeen.Before(r2.FirstSeen)
+		}
+		robotcpus.by = cmp
+		sort.Sort(robotcpus)
+	case "lastSeen":
+		cmp := func(r1, r2 *survivordb.Robot) bool {
+			return r1.LastSeen.Before(r2.LastSeen)
+		}
+		robotcpus.by = cmp
+		sort.Sort(robotcpus)
 	}
 
 	robotsBuffer, err := json.Marshal(robotcpus.robots)
@@ -589,6 +953,24 @@ func (a *Apocalypse) RobotCPU(w http.ResponseWriter, r *http.Request) {
 	w.Write(robotsBuffer)
 }
 
+// writeJSON marshals body and writes it with the status code
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	buffer, err := json.Marshal(body)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"body":  body,
+			"Error": err,
+		}).Error("Marshal")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(buffer)
+}
+
 // rangeStructer takes the first argument, which must be a struct, and
 // returns the value of each field in a slice. It will return nil
 // if there are no arguments or first argument is not a struct
@@ -1,15 +1,20 @@
 package survivor
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"html/template"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"robo-apocalypse/pkg/auth"
+	"robo-apocalypse/pkg/robotcpu"
 	"robo-apocalypse/pkg/survivordb"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -42,7 +47,8 @@ var updaterResourceRequest string = `{
 }`
 
 var updaterInfectedRequest string = `{
-	"id":		"HD138VOP34219"
+	"id":		"HD138VOP34219",
+	"reporter":	"%s"
 }`
 
 // TestApocalypseApi_NewSurvivor checks if the api endpoint
@@ -50,11 +56,12 @@ var updaterInfectedRequest string = `{
 func TestApocalypseApi_NewSurvivor(t *testing.T) {
 	robo := &Apocalypse{}
 	os.Remove("./test.db")
-	robo.DB = survivordb.Open("./test.db")
-	if robo.DB == nil {
+	db := survivordb.Open("./test.db")
+	if db == nil {
 		return
 	}
-	err := robo.DB.Setup()
+	robo.DB = db
+	err := db.Setup()
 	if err != nil {
 		t.Errorf("Error setting up database: %v", err)
 		return
@@ -79,6 +86,36 @@ func TestApocalypseApi_NewSurvivor(t *testing.T) {
 	if newSurvivor == nil {
 		t.Errorf("SurvivorDB.GetSurvivor() - %q: want: not nil, got: %v", survivor.Name, newSurvivor)
 	}
+
+	dupw := httptest.NewRecorder()
+	dupr := httptest.NewRequest(http.MethodPost, "/survivors", strings.NewReader(survivorRequest))
+	robo.Survivor(dupw, dupr)
+	if dupw.Code != http.StatusConflict {
+		t.Errorf("Apocalypse.NewSurvivor(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusConflict, dupw.Code)
+	}
+	if location := dupw.Header().Get("Location"); location != "/survivors/HD138VOP34219" {
+		t.Errorf("Apocalypse.NewSurvivor(w http.ResponseWriter, r *http.Request): want: %v, got: %v", "/survivors/HD138VOP34219", location)
+	}
+	if survivors := robo.DB.GetAllSurvivors(); len(survivors) != 1 {
+		t.Errorf("SurvivorDB.GetAllSurvivors(): want: %v, got: %v", 1, len(survivors))
+	}
+
+	// only medics and admins may register infected survivors
+	for _, tc := range []struct {
+		role string
+		want int
+	}{
+		{survivordb.RoleScout, http.StatusForbidden},
+		{survivordb.RoleMedic, http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/survivors", strings.NewReader(`{"name": "John Doe", "age": 40, "gender": "Male", "id": "HD138VOP34220", "infected": true}`))
+		r = r.WithContext(auth.NewContext(r.Context(), &survivordb.APIKey{Name: "jane", Role: tc.role}))
+		robo.Survivor(w, r)
+		if w.Code != tc.want {
+			t.Errorf("Apocalypse.NewSurvivor(w http.ResponseWriter, r *http.Request) - %s: want: %v, got: %v", tc.role, tc.want, w.Code)
+		}
+	}
 }
 
 // TestApocalypseApi_UpdateLocation checks if the api endpoint
@@ -86,11 +123,12 @@ func TestApocalypseApi_NewSurvivor(t *testing.T) {
 func TestApocalypseApi_UpdateLocation(t *testing.T) {
 	robo := &Apocalypse{}
 	os.Remove("./test.db")
-	robo.DB = survivordb.Open("./test.db")
-	if robo.DB == nil {
+	db := survivordb.Open("./test.db")
+	if db == nil {
 		return
 	}
-	err := robo.DB.Setup()
+	robo.DB = db
+	err := db.Setup()
 	if err != nil {
 		t.Errorf("Error setting up database: %v", err)
 		return
@@ -135,11 +173,12 @@ func TestApocalypseApi_UpdateLocation(t *testing.T) {
 func TestApocalypseApi_UpdateInfected(t *testing.T) {
 	robo := &Apocalypse{}
 	os.Remove("./test.db")
-	robo.DB = survivordb.Open("./test.db")
-	if robo.DB == nil {
+	db := survivordb.Open("./test.db")
+	if db == nil {
 		return
 	}
-	err := robo.DB.Setup()
+	robo.DB = db
+	err := db.Setup()
 	if err != nil {
 		t.Errorf("Error setting up database: %v", err)
 		return
@@ -154,13 +193,38 @@ func TestApocalypseApi_UpdateInfected(t *testing.T) {
 		t.Errorf("Apocalypse.UpdateLocation(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusOK, addresp.Status)
 	}
 
-	reader := strings.NewReader(updaterInfectedRequest)
+	reporters := []string{"HD138VOP34220", "HD138VOP34221", "HD138VOP34222"}
+	for _, reporter := range reporters {
+		err = robo.DB.Save(&survivordb.Survivor{Name: "John Doe", Age: 1, Gender: "Male", IdNumber: reporter})
+		if err != nil {
+			t.Errorf("SurvivorDB.Save() - %q: want: %v, got: %v", reporter, nil, err)
+		}
+	}
+
+	for i, reporter := range reporters {
+		reader := strings.NewReader(fmt.Sprintf(updaterInfectedRequest, reporter))
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPut, "/survivors/infected", reader)
+		robo.Infected(w, r)
+		resp := w.Result()
+		if resp.Status != fmt.Sprintf("%d OK", http.StatusOK) {
+			t.Errorf("Apocalypse.UpdateInfected(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusOK, resp.Status)
+		}
+		report := &survivordb.InfectionReport{}
+		if err := json.NewDecoder(resp.Body).Decode(report); err != nil {
+			t.Errorf("Apocalypse.UpdateInfected(w http.ResponseWriter, r *http.Request): could not decode response: %v", err)
+		}
+		if report.Reports != i+1 || report.Infected != (i+1 == DefaultInfectionThreshold) {
+			t.Errorf("Apocalypse.UpdateInfected(w http.ResponseWriter, r *http.Request): want: %v reports, got: %v", i+1, report)
+		}
+	}
+
+	reader := strings.NewReader(fmt.Sprintf(updaterInfectedRequest, reporters[0]))
 	w := httptest.NewRecorder()
 	r := httptest.NewRequest(http.MethodPut, "/survivors/infected", reader)
 	robo.Infected(w, r)
-	resp := w.Result()
-	if resp.Status != fmt.Sprintf("%d OK", http.StatusOK) {
-		t.Errorf("Apocalypse.UpdateInfected(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusOK, resp.Status)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Apocalypse.UpdateInfected(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusConflict, w.Code)
 	}
 	locationPayload := &struct {
 		IdNumber string `json:"id"`
@@ -184,11 +248,12 @@ func TestApocalypseApi_UpdateInfected(t *testing.T) {
 func TestApocalypseApi_UpdateResources(t *testing.T) {
 	robo := &Apocalypse{}
 	os.Remove("./test.db")
-	robo.DB = survivordb.Open("./test.db")
-	if robo.DB == nil {
+	db := survivordb.Open("./test.db")
+	if db == nil {
 		return
 	}
-	err := robo.DB.Setup()
+	robo.DB = db
+	err := db.Setup()
 	if err != nil {
 		t.Errorf("Error setting up database: %v", err)
 		return
@@ -228,6 +293,233 @@ func TestApocalypseApi_UpdateResources(t *testing.T) {
 	}
 }
 
+// TestApocalypseApi_ListSurvivors checks if the api endpoint
+// pages through survivors with the X-Next-Cursor header
+func TestApocalypseApi_ListSurvivors(t *testing.T) {
+	robo := &Apocalypse{}
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	if db == nil {
+		return
+	}
+	robo.DB = db
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("Error setting up database: %v", err)
+		return
+	}
+
+	for _, idNumber := range []string{"HD138VOP34219", "HD138VOP34220", "HD138VOP34221"} {
+		err = robo.DB.Save(&survivordb.Survivor{Name: "Jane Doe", Age: 1, Gender: "Female", IdNumber: idNumber})
+		if err != nil {
+			t.Errorf("SurvivorDB.Save() - %q: want: %v, got: %v", idNumber, nil, err)
+		}
+	}
+
+	ids := []string{}
+	url := "/survivors?limit=2&sort=name"
+	for url != "" {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, url, nil)
+		robo.Survivor(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("Apocalypse.Survivor(w http.ResponseWriter, r *http.Request) - %q: want: %v, got: %v", url, http.StatusOK, w.Code)
+			return
+		}
+		survivors := []survivordb.Survivor{}
+		if err := json.NewDecoder(w.Body).Decode(&survivors); err != nil {
+			t.Errorf("Apocalypse.Survivor(w http.ResponseWriter, r *http.Request): could not decode response: %v", err)
+			return
+		}
+		for _, survivor := range survivors {
+			ids = append(ids, survivor.IdNumber)
+		}
+		url = ""
+		if next := w.Header().Get("X-Next-Cursor"); next != "" {
+			url = "/survivors?limit=2&sort=name&cursor=" + next
+		}
+	}
+	if len(ids) != 3 {
+		t.Errorf("Apocalypse.Survivor(w http.ResponseWriter, r *http.Request): want: %v, got: %v", 3, ids)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/survivors?sort=height", nil)
+	robo.Survivor(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Apocalypse.Survivor(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestApocalypseApi_SurvivorByID checks if the api endpoint
+// reads, patches and deletes a single survivor
+func TestApocalypseApi_SurvivorByID(t *testing.T) {
+	robo := &Apocalypse{}
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	if db == nil {
+		return
+	}
+	robo.DB = db
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("Error setting up database: %v", err)
+		return
+	}
+
+	addreader := strings.NewReader(survivorRequest)
+	addw := httptest.NewRecorder()
+	addr := httptest.NewRequest(http.MethodPost, "/survivors", addreader)
+	robo.Survivor(addw, addr)
+
+	testCases := []struct {
+		method string
+		url    string
+		body   string
+		want   int
+	}{
+		{http.MethodGet, "/survivors/HD138VOP34219", "", http.StatusOK},
+		{http.MethodGet, "/survivors/UNKNOWN", "", http.StatusNotFound},
+		{http.MethodPatch, "/survivors/HD138VOP34219", `{"name": "Jane Smith", "age": 31}`, http.StatusOK},
+		{http.MethodPatch, "/survivors/HD138VOP34219", `{"age": "old"}`, http.StatusUnprocessableEntity},
+		{http.MethodPatch, "/survivors/HD138VOP34219", `{"latitude": 900}`, http.StatusUnprocessableEntity},
+		{http.MethodPatch, "/survivors/UNKNOWN", `{"age": 31}`, http.StatusNotFound},
+		{http.MethodDelete, "/survivors/UNKNOWN", "", http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+		robo.SurvivorByID(w, r)
+		if w.Code != tc.want {
+			t.Errorf("Apocalypse.SurvivorByID(w http.ResponseWriter, r *http.Request) - %s %q: want: %v, got: %v", tc.method, tc.url, tc.want, w.Code)
+		}
+	}
+
+	// only medics and admins may set infected
+	for _, tc := range []struct {
+		role string
+		want int
+	}{
+		{survivordb.RoleScout, http.StatusForbidden},
+		{survivordb.RoleMedic, http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPatch, "/survivors/HD138VOP34219", strings.NewReader(`{"infected": false}`))
+		r = r.WithContext(auth.NewContext(r.Context(), &survivordb.APIKey{Name: "jane", Role: tc.role}))
+		robo.SurvivorByID(w, r)
+		if w.Code != tc.want {
+			t.Errorf("Apocalypse.SurvivorByID(w http.ResponseWriter, r *http.Request) - %s: want: %v, got: %v", tc.role, tc.want, w.Code)
+		}
+	}
+
+	survivor := robo.DB.GetSurvivor("HD138VOP34219")
+	if survivor == nil || survivor.Name != "Jane Smith" || survivor.Age != 31 || survivor.Water != 2000 {
+		t.Errorf("SurvivorDB.GetSurvivor() - %q: got: %v", "HD138VOP34219", survivor)
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodDelete, "/survivors/HD138VOP34219", nil)
+	robo.SurvivorByID(w, r)
+	if w.Code != http.StatusNoContent || robo.DB.GetSurvivor("HD138VOP34219") != nil {
+		t.Errorf("Apocalypse.SurvivorByID(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusNoContent, w.Code)
+	}
+}
+
+var updaterInventoryRequest string = `{
+	"id":		"HD138VOP34219",
+	"inventory":	[
+		{"type": "water", "name": "water", "quantity": 12},
+		{"type": "food", "name": "Rice", "quantity": 2, "unit": "kg"}
+	]
+}`
+
+// TestApocalypseApi_SurvivorStats checks if the api endpoint
+// returns inventory totals after an itemised resource update
+func TestApocalypseApi_SurvivorStats(t *testing.T) {
+	robo := &Apocalypse{}
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	if db == nil {
+		return
+	}
+	robo.DB = db
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("Error setting up database: %v", err)
+		return
+	}
+
+	addreader := strings.NewReader(survivorRequest)
+	addw := httptest.NewRecorder()
+	addr := httptest.NewRequest(http.MethodPost, "/survivors", addreader)
+	robo.Survivor(addw, addr)
+
+	reader := strings.NewReader(updaterInventoryRequest)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPut, "/survivors/resources", reader)
+	robo.UpdateResources(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("Apocalypse.UpdateResources(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusOK, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/survivors/stats", nil)
+	robo.SurvivorStats(w, r)
+	stats := &struct {
+		HealthyPercentage float64                    `json:"healthyPercentage"`
+		Inventory         []survivordb.InventoryItem `json:"inventory"`
+	}{}
+	if err := json.NewDecoder(w.Body).Decode(stats); err != nil {
+		t.Errorf("Apocalypse.SurvivorStats(w http.ResponseWriter, r *http.Request): could not decode response: %v", err)
+	}
+	if stats.HealthyPercentage != 100 || len(stats.Inventory) != 2 {
+		t.Errorf("Apocalypse.SurvivorStats(w http.ResponseWriter, r *http.Request): got: %v", stats)
+	}
+}
+
+// TestApocalypseApi_DetailedStats checks if the api endpoint
+// returns the detailed survivor statistics
+func TestApocalypseApi_DetailedStats(t *testing.T) {
+	robo := &Apocalypse{}
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	if db == nil {
+		return
+	}
+	robo.DB = db
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("Error setting up database: %v", err)
+		return
+	}
+
+	addreader := strings.NewReader(survivorRequest)
+	addw := httptest.NewRecorder()
+	addr := httptest.NewRequest(http.MethodPost, "/survivors", addreader)
+	robo.Survivor(addw, addr)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/survivors/stats/detailed", nil)
+	robo.DetailedStats(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("Apocalypse.DetailedStats(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusOK, w.Code)
+	}
+	stats := &survivordb.DetailedStats{}
+	if err := json.NewDecoder(w.Body).Decode(stats); err != nil {
+		t.Errorf("Apocalypse.DetailedStats(w http.ResponseWriter, r *http.Request): could not decode response: %v", err)
+	}
+	if stats.Survivors != 1 || stats.AverageWater != 2000 || len(stats.ByGender) != 1 || len(stats.ByAgeBand) != 1 {
+		t.Errorf("Apocalypse.DetailedStats(w http.ResponseWriter, r *http.Request): got: %v", stats)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/survivors/stats/detailed", nil)
+	robo.DetailedStats(w, r)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Apocalypse.DetailedStats(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusMethodNotAllowed, w.Code)
+	}
+}
+
 var tmplStr string = `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN"                            
 "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd">                                
 <html xmlns="http://www.w3.org/1999/xhtml">                                         
@@ -265,11 +557,12 @@ var tmplStr string = `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN"
 func TestApocalypseApi_ReportWeb(t *testing.T) {
 	robo := &Apocalypse{}
 	os.Remove("./test.db")
-	robo.DB = survivordb.Open("./test.db")
-	if robo.DB == nil {
+	db := survivordb.Open("./test.db")
+	if db == nil {
 		return
 	}
-	err := robo.DB.Setup()
+	robo.DB = db
+	err := db.Setup()
 	if err != nil {
 		t.Errorf("Error setting up database: %v", err)
 		return
@@ -289,3 +582,56 @@ func TestApocalypseApi_ReportWeb(t *testing.T) {
 		t.Errorf("Apocalypse.ReportWeb(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusOK, resp.Status)
 	}
 }
+
+// TestApocalypseApi_RobotCPU checks if the api endpoint filters and sorts the
+// polled robots and keeps serving them with a warning when the upstream is down
+func TestApocalypseApi_RobotCPU(t *testing.T) {
+	var down int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&down) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[{"model":"B","serialNumber":"S2","category":"Land"},
+			{"model":"A","serialNumber":"S1","category":"Flying"},
+			{"model":"C","serialNumber":"S3","category":"Land"}]`))
+	}))
+	defer upstream.Close()
+	robo := &Apocalypse{DB: survivordb.NewMemoryStore()}
+	robo.RobotPoller = &robotcpu.Poller{DB: robo.DB, Client: &robotcpu.Client{Endpoint: upstream.URL, Retries: -1,
+		BreakerThreshold: 1, BreakerCooldown: time.Hour}}
+
+	testCases := []struct {
+		url     string
+		down    int32
+		want    string
+		warning string
+	}{
+		{"/robotcpu", 0, "S2 S1 S3", ""},
+		{"/robotcpu?category=Land&sortby=model", 0, "S2 S3", ""},
+		{"/robotcpu?sortby=model", 1, "S1 S2 S3", staleWarning},
+		{"/robotcpu", 1, "S2 S1 S3", staleWarning},
+	}
+	for _, tc := range testCases {
+		atomic.StoreInt32(&down, tc.down)
+		robo.RobotPoller.Poll(context.Background())
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+		robo.RobotCPU(w, r)
+		robots := []survivordb.Robot{}
+		if err := json.NewDecoder(w.Body).Decode(&robots); err != nil || w.Code != http.StatusOK {
+			t.Errorf("Apocalypse.RobotCPU(w http.ResponseWriter, r *http.Request) - %q: want: %v, got: %v %v", tc.url, http.StatusOK, w.Code, err)
+			continue
+		}
+		serials := []string{}
+		for _, robot := range robots {
+			serials = append(serials, robot.SerialNumber)
+			if robot.FirstSeen.IsZero() || robot.LastSeen.Before(robot.FirstSeen) {
+				t.Errorf("Apocalypse.RobotCPU(w http.ResponseWriter, r *http.Request) - %q: want: seen, got: %v %v", tc.url, robot.FirstSeen, robot.LastSeen)
+			}
+		}
+		if got := strings.Join(serials, " "); got != tc.want || w.Header().Get("Warning") != tc.warning {
+			t.Errorf("Apocalypse.RobotCPU(w http.ResponseWriter, r *http.Request) - %q: want: %v %q, got: %v %q", tc.url, tc.want, tc.warning, got, w.Header().Get("Warning"))
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package survivor
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"robo-apocalypse/pkg/survivordb"
+
+	"github.com/sirupsen/logrus"
+)
+
+// swagger:route POST /survivors/trades survivors createTrade
+// Exchange resources between two survivors. Both sides of the trade must be
+// worth the same points and neither survivor may be infected
+// responses:
+//	200: surivivorsResponse
+//	400:
+//	403:
+//	404:
+//	422:
+//	500:
+
+// Trades handles POST requests to trade resources between survivors
+func (a *Apocalypse) Trades(w http.ResponseWriter, r *http.Request) {
+	logrus.Info("Apocalypse.Trades")
+	defer a.publish()
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Error reading response")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	trade := &survivordb.Trade{}
+	if err := json.Unmarshal(body, trade); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"body":  string(body),
+		}).Info("Error unmarshalling")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"body": trade,
+	}).Info("Incoming")
+	survivors, err := a.DB.WithActor(requestActor(r)).Trade(trade)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error":  err,
+			"action": trade,
+		}).Info("Error trading")
+		switch err {
+		case survivordb.ErrInvalidTrade:
+			w.WriteHeader(http.StatusBadRequest)
+		case survivordb.ErrInfectedSurvivor:
+			w.WriteHeader(http.StatusForbidden)
+		case survivordb.ErrNotFound:
+			w.WriteHeader(http.StatusNotFound)
+		case survivordb.ErrUnbalancedTrade, survivordb.ErrInsufficientResources:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	survivorsBuffer, err := json.Marshal(survivors)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"body":  survivors,
+			"Error": err,
+		}).Error("Marshal")
+		return
+	}
+
+	w.Header().Add("Access-Control-Allow-Origin", "*")
+	w.Write(survivorsBuffer)
+}
@@ -0,0 +1,63 @@
+package survivor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"robo-apocalypse/pkg/survivordb"
+	"strings"
+	"testing"
+)
+
+var tradeRequest string = `{
+	"from":	{"id": "HD138VOP34219", "water": 1},
+	"to":	{"id": "HD138VOP34220", "ammunition": 4}
+}`
+
+// TestApocalypseApi_Trades checks if the api endpoint
+// returns a success http status for a balanced trade
+func TestApocalypseApi_Trades(t *testing.T) {
+	robo := &Apocalypse{}
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	if db == nil {
+		return
+	}
+	robo.DB = db
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("Error setting up database: %v", err)
+		return
+	}
+
+	addreader := strings.NewReader(survivorRequest)
+	addw := httptest.NewRecorder()
+	addr := httptest.NewRequest(http.MethodPost, "/survivors", addreader)
+	robo.Survivor(addw, addr)
+	err = robo.DB.Save(&survivordb.Survivor{
+		Name:      "John Doe",
+		Age:       1,
+		Gender:    "Male",
+		IdNumber:  "HD138VOP34220",
+		Resources: survivordb.Resources{Ammunition: 4},
+	})
+	if err != nil {
+		t.Errorf("SurvivorDB.Save(): want: %v, got: %v", nil, err)
+	}
+
+	reader := strings.NewReader(tradeRequest)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/survivors/trades", reader)
+	robo.Trades(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("Apocalypse.Trades(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusOK, w.Code)
+	}
+
+	reader = strings.NewReader(tradeRequest)
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/survivors/trades", reader)
+	robo.Trades(w, r)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Apocalypse.Trades(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusUnprocessableEntity, w.Code)
+	}
+}
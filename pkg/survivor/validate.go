@@ -0,0 +1,77 @@
+package survivor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"robo-apocalypse/pkg/survivordb"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// unknownFieldPrefix starts the message encoding/json returns for a field
+// that is not in the payload struct
+const unknownFieldPrefix = "json: unknown field "
+
+// errTrailingData is returned when a body holds more than one JSON value
+var errTrailingData = errors.New("trailing data after the payload")
+
+// decodePayload unmarshals body into payload, rejecting unknown fields.
+// Unknown fields and values of the wrong type get a 422 response listing
+// the fields, other malformed bodies, including those with data after the
+// payload, get a 400. It returns false when a response was written
+func decodePayload(w http.ResponseWriter, body []byte, payload interface{}) bool {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(payload)
+	if err == nil {
+		var trailing json.RawMessage
+		if err = decoder.Decode(&trailing); err == io.EOF {
+			return true
+		}
+		if err == nil {
+			err = errTrailingData
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"Error": err,
+		"body":  string(body),
+	}).Info("Error unmarshalling")
+
+	if typeErr, ok := err.(*json.UnmarshalTypeError); ok {
+		writeValidationError(w, &survivordb.ValidationError{Errors: []survivordb.FieldError{
+			{Field: typeErr.Field, Message: "must be a " + typeErr.Type.String()},
+		}})
+		return false
+	}
+	if strings.HasPrefix(err.Error(), unknownFieldPrefix) {
+		field, unquoteErr := strconv.Unquote(strings.TrimPrefix(err.Error(), unknownFieldPrefix))
+		if unquoteErr == nil {
+			writeValidationError(w, &survivordb.ValidationError{Errors: []survivordb.FieldError{
+				{Field: field, Message: "is not a known field"},
+			}})
+			return false
+		}
+	}
+
+	w.WriteHeader(http.StatusBadRequest)
+	return false
+}
+
+// writeValidationError writes a 422 response listing the invalid fields
+func writeValidationError(w http.ResponseWriter, err error) {
+	logrus.WithFields(logrus.Fields{
+		"Error": err,
+	}).Info("Invalid payload")
+	validationErr, ok := err.(*survivordb.ValidationError)
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusUnprocessableEntity, validationErr)
+}
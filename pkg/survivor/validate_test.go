@@ -0,0 +1,80 @@
+package survivor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"robo-apocalypse/pkg/survivordb"
+	"strings"
+	"testing"
+)
+
+// TestApocalypseApi_Validation checks if invalid payloads get a 422
+// response listing each invalid field
+func TestApocalypseApi_Validation(t *testing.T) {
+	robo := &Apocalypse{}
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	if db == nil {
+		return
+	}
+	robo.DB = db
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("Error setting up database: %v", err)
+		return
+	}
+
+	addw := httptest.NewRecorder()
+	addr := httptest.NewRequest(http.MethodPost, "/survivors", strings.NewReader(survivorRequest))
+	robo.Survivor(addw, addr)
+
+	testCases := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		body    string
+		want    int
+		fields  []string
+	}{
+		{"empty id", robo.Survivor, http.MethodPost, `{"name": "Jane Doe", "gender": "Female", "id": ""}`, http.StatusUnprocessableEntity, []string{"id"}},
+		{"several fields", robo.Survivor, http.MethodPost, `{"name": "", "gender": "Female", "id": "HD138VOP34221", "latitude": 900, "ammunition": -1}`, http.StatusUnprocessableEntity, []string{"name", "latitude", "ammunition"}},
+		{"unknown field", robo.Survivor, http.MethodPost, `{"name": "Jane Doe", "gender": "Female", "id": "HD138VOP34221", "weapon": "axe"}`, http.StatusUnprocessableEntity, []string{"weapon"}},
+		{"wrong type", robo.Survivor, http.MethodPost, `{"name": "Jane Doe", "gender": "Female", "id": "HD138VOP34221", "age": "old"}`, http.StatusUnprocessableEntity, []string{"age"}},
+		{"malformed", robo.Survivor, http.MethodPost, `{"name": `, http.StatusBadRequest, nil},
+		{"trailing garbage", robo.Survivor, http.MethodPost, `{"name": "Jane Doe", "gender": "Female", "id": "HD138VOP34221"}garbage`, http.StatusBadRequest, nil},
+		{"two payloads", robo.Survivor, http.MethodPost, `{"name": "Jane Doe", "gender": "Female", "id": "HD138VOP34221"} {"name": "John Doe", "gender": "Male", "id": "HD138VOP34222"}`, http.StatusBadRequest, nil},
+		{"location", robo.UpdateLocation, http.MethodPut, `{"id": "HD138VOP34219", "latitude": 900, "longitude": 0}`, http.StatusUnprocessableEntity, []string{"latitude"}},
+		{"location unknown field", robo.UpdateLocation, http.MethodPut, `{"id": "HD138VOP34219", "altitude": 9}`, http.StatusUnprocessableEntity, []string{"altitude"}},
+		{"resources", robo.UpdateResources, http.MethodPut, `{"id": "HD138VOP34219", "ammunition": -1}`, http.StatusUnprocessableEntity, []string{"ammunition"}},
+		{"valid location", robo.UpdateLocation, http.MethodPut, `{"id": "HD138VOP34219", "latitude": 1, "longitude": 2}`, http.StatusOK, nil},
+	}
+
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(tc.method, "/survivors", strings.NewReader(tc.body))
+		tc.handler(w, r)
+		if w.Code != tc.want {
+			t.Errorf("%s: want: %v, got: %v", tc.name, tc.want, w.Code)
+			continue
+		}
+		if tc.fields == nil {
+			continue
+		}
+		validationErr := &survivordb.ValidationError{}
+		if err := json.Unmarshal(w.Body.Bytes(), validationErr); err != nil || len(validationErr.Errors) != len(tc.fields) {
+			t.Errorf("%s: want: %v, got: %v", tc.name, tc.fields, w.Body.String())
+			continue
+		}
+		for i, field := range tc.fields {
+			if validationErr.Errors[i].Field != field {
+				t.Errorf("%s: want: %v, got: %v", tc.name, field, validationErr.Errors[i].Field)
+			}
+		}
+	}
+
+	if survivors := robo.DB.GetAllSurvivors(); len(survivors) != 1 {
+		t.Errorf("SurvivorDB.GetAllSurvivors(): want: %v, got: %v", 1, len(survivors))
+	}
+}
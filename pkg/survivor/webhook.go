@@ -0,0 +1,196 @@
+package survivor
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"robo-apocalypse/pkg/survivordb"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// webhookDeliveriesLimit is the number of recent deliveries returned for a webhook
+const webhookDeliveriesLimit = 100
+
+// swagger:parameters getWebhook updateWebhook deleteWebhook getWebhookDeliveries
+type WebhookIDParam struct {
+	// The id of the webhook
+	//
+	// in: path
+	// required: true
+	ID int64 `json:"id"`
+}
+
+// swagger:route GET /webhooks webhooks getWebhooks
+// Return all webhooks without their secrets
+// responses:
+//	200: webhooksResponse
+
+// swagger:route POST /webhooks webhooks createWebhook
+// Register a webhook. The response holds the secret the payloads are signed
+// with, which is not returned again
+// responses:
+//	201: webhookResponse
+//	400:
+
+// Webhooks handles GET requests to list webhooks and POST requests to register one
+func (a *Apocalypse) Webhooks(w http.ResponseWriter, r *http.Request) {
+	logrus.Info("Apocalypse.Webhooks")
+	switch r.Method {
+	case http.MethodGet:
+		webhooks := a.DB.GetWebhooks()
+		if webhooks == nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		for i := range webhooks {
+			webhooks[i].Secret = ""
+		}
+		writeJSON(w, http.StatusOK, webhooks)
+	case http.MethodPost:
+		webhook := a.readWebhook(w, r)
+		if webhook == nil {
+			return
+		}
+		if err := a.DB.SaveWebhook(webhook); err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, webhook)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// swagger:route GET /webhooks/{id} webhooks getWebhook
+// Return a webhook without its secret
+// responses:
+//	200: webhookResponse
+//	404:
+
+// swagger:route PUT /webhooks/{id} webhooks updateWebhook
+// Replace the url, events and disabled flag of a webhook. The secret is only
+// replaced when one is given
+// responses:
+//	200: webhookResponse
+//	400:
+//	404:
+
+// swagger:route DELETE /webhooks/{id} webhooks deleteWebhook
+// Delete a webhook with its deliveries
+// responses:
+//	204:
+//	404:
+
+// swagger:route GET /webhooks/{id}/deliveries webhooks getWebhookDeliveries
+// Return the last 100 deliveries of a webhook, newest first, with every attempt
+// responses:
+//	200: webhookDeliveriesResponse
+//	404:
+
+// WebhookByID handles requests for a single webhook under /webhooks/{id}
+func (a *Apocalypse) WebhookByID(w http.ResponseWriter, r *http.Request) {
+	logrus.Info("Apocalypse.WebhookByID")
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/webhooks/"), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || len(parts) > 2 || (len(parts) == 2 && parts[1] != "deliveries") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if len(parts) == 2 {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if a.DB.GetWebhook(id) == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		deliveries := a.DB.GetWebhookDeliveries(id, webhookDeliveriesLimit)
+		if deliveries == nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, deliveries)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		webhook := a.DB.GetWebhook(id)
+		if webhook == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		webhook.Secret = ""
+		writeJSON(w, http.StatusOK, webhook)
+	case http.MethodPut:
+		webhook := a.readWebhook(w, r)
+		if webhook == nil {
+			return
+		}
+		webhook.ID = id
+		if err := a.DB.UpdateWebhook(webhook); err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+		webhook.Secret = ""
+		writeJSON(w, http.StatusOK, webhook)
+	case http.MethodDelete:
+		if err := a.DB.DeleteWebhook(id); err != nil {
+			writeWebhookError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// readWebhook reads a webhook from the request body, writing a 400 response
+// and returning nil when it cannot be read
+func (a *Apocalypse) readWebhook(w http.ResponseWriter, r *http.Request) *survivordb.Webhook {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Error reading response")
+		w.WriteHeader(http.StatusBadRequest)
+		return nil
+	}
+
+	webhook := &survivordb.Webhook{}
+	if err := json.Unmarshal(body, webhook); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Error unmarshalling")
+		w.WriteHeader(http.StatusBadRequest)
+		return nil
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"url":    webhook.URL,
+		"events": webhook.Events,
+	}).Info("Incoming")
+
+	return webhook
+}
+
+// writeWebhookError writes the response code for a webhook error
+func writeWebhookError(w http.ResponseWriter, err error) {
+	logrus.WithFields(logrus.Fields{
+		"Error": err,
+	}).Info("Error saving webhook")
+	switch err {
+	case survivordb.ErrInvalidWebhook:
+		w.WriteHeader(http.StatusBadRequest)
+	case survivordb.ErrWebhookNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
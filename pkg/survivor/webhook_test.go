@@ -0,0 +1,83 @@
+package survivor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"robo-apocalypse/pkg/survivordb"
+	"strings"
+	"testing"
+)
+
+// TestApocalypseApi_Webhooks checks if webhooks can be registered, read,
+// updated and deleted without exposing their secrets
+func TestApocalypseApi_Webhooks(t *testing.T) {
+	robo := &Apocalypse{}
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	if db == nil {
+		return
+	}
+	robo.DB = db
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("Error setting up database: %v", err)
+		return
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url": "https://example.com/hook", "events": ["survivor.infected"]}`))
+	robo.Webhooks(w, r)
+	if w.Code != http.StatusCreated {
+		t.Errorf("Apocalypse.Webhooks(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusCreated, w.Code)
+	}
+	webhook := &survivordb.Webhook{}
+	if err := json.NewDecoder(w.Body).Decode(webhook); err != nil || webhook.ID == 0 || webhook.Secret == "" {
+		t.Errorf("Apocalypse.Webhooks(w http.ResponseWriter, r *http.Request): want an id and secret, got: %v, %v", webhook, err)
+		return
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/webhooks", nil)
+	robo.Webhooks(w, r)
+	webhooks := []survivordb.Webhook{}
+	if err := json.NewDecoder(w.Body).Decode(&webhooks); err != nil || len(webhooks) != 1 || webhooks[0].Secret != "" {
+		t.Errorf("Apocalypse.Webhooks(w http.ResponseWriter, r *http.Request): want one webhook without secret, got: %v, %v", webhooks, err)
+	}
+
+	testCases := []struct {
+		method string
+		url    string
+		body   string
+		want   int
+	}{
+		{http.MethodPost, "/webhooks", `{"url": "example.com"}`, http.StatusBadRequest},
+		{http.MethodPost, "/webhooks", `{"url": "https://example.com", "events": ["robot.spotted"]}`, http.StatusBadRequest},
+		{http.MethodGet, "/webhooks/1", "", http.StatusOK},
+		{http.MethodGet, "/webhooks/2", "", http.StatusNotFound},
+		{http.MethodGet, "/webhooks/x", "", http.StatusNotFound},
+		{http.MethodPut, "/webhooks/1", `{"url": "https://example.com/other", "disabled": true}`, http.StatusOK},
+		{http.MethodPut, "/webhooks/2", `{"url": "https://example.com/other"}`, http.StatusNotFound},
+		{http.MethodGet, "/webhooks/1/deliveries", "", http.StatusOK},
+		{http.MethodGet, "/webhooks/2/deliveries", "", http.StatusNotFound},
+		{http.MethodPost, "/webhooks/1/deliveries", "", http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/webhooks/1", "", http.StatusNoContent},
+		{http.MethodDelete, "/webhooks/1", "", http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(tc.method, tc.url, strings.NewReader(tc.body))
+		if tc.url == "/webhooks" {
+			robo.Webhooks(w, r)
+		} else {
+			robo.WebhookByID(w, r)
+		}
+		if w.Code != tc.want {
+			t.Errorf("Apocalypse.WebhookByID(w http.ResponseWriter, r *http.Request) - %v %v: want: %v, got: %v", tc.method, tc.url, tc.want, w.Code)
+		}
+		if tc.method == http.MethodGet && tc.url == "/webhooks/1" && strings.Contains(w.Body.String(), webhook.Secret) {
+			t.Errorf("Apocalypse.WebhookByID(w http.ResponseWriter, r *http.Request): the secret was returned: %v", w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package survivor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"robo-apocalypse/pkg/events"
+	"robo-apocalypse/pkg/survivordb"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// WebSocket message types
+const (
+	WSLocation  = "location"
+	WSResources = "resources"
+	WSInfection = "infection"
+	WSAck       = "ack"
+	WSError     = "error"
+	WSEvent     = "event"
+)
+
+const (
+	// wsWriteWait is the time allowed to write a message to the device
+	wsWriteWait = 10 * time.Second
+	// wsPongWait is the time allowed between pongs before the device is
+	// considered gone
+	wsPongWait = 60 * time.Second
+	// wsPingPeriod is how often the device is pinged, less than wsPongWait
+	wsPingPeriod = wsPongWait * 9 / 10
+	// wsMaxMessageSize is the largest message accepted from a device
+	wsMaxMessageSize = 65536
+	// wsReplyBuffer is the number of replies queued for a device before
+	// reading its next message waits
+	wsReplyBuffer = 16
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// devices authenticate with a token, so any origin may connect
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// WSMessage defines a message sent by a device
+// swagger:model
+type WSMessage struct {
+	// location, resources or infection
+	//
+	// required: true
+	Type string `json:"type"`
+
+	// echoed in the reply so the device can match it to the message
+	//
+	// required: false
+	Ref string `json:"ref,omitempty"`
+
+	// the body of the matching REST request: PUT /survivors/location,
+	// PUT /survivors/resources or PUT /survivors/infected
+	//
+	// required: true
+	Payload json.RawMessage `json:"payload"`
+}
+
+// WSReply defines a message sent to a device
+// swagger:model
+type WSReply struct {
+	// ack, error or event
+	//
+	// required: true
+	Type string `json:"type"`
+
+	// the ref of the message replied to
+	//
+	// required: false
+	Ref string `json:"ref,omitempty"`
+
+	// the http status code the matching REST request would get
+	//
+	// required: false
+	Status int `json:"status,omitempty"`
+
+	// the body the matching REST request would get, e.g. the field errors
+	//
+	// required: false
+	Body json.RawMessage `json:"body,omitempty"`
+
+	// the survivor event of an event message
+	//
+	// required: false
+	Event *survivordb.SurvivorEvent `json:"event,omitempty"`
+}
+
+// swagger:parameters connectWS
+type WSParam struct {
+	// Replay the events after this event id before sending new ones
+	//
+	// in: query
+	// required: false
+	LastEventID int64 `json:"lastEventId"`
+}
+
+// swagger:route GET /ws events connectWS
+// Open a WebSocket for a field device authenticated with its API key. The
+// messages are authorized by the role of the key like the REST requests they
+// mirror. The device sends location, resources
+// and infection messages and gets an ack or error reply for each, along with
+// every survivor event. Devices that fall behind on events are disconnected
+// with close code 1013 and reconnect with lastEventId
+// responses:
+//	101:
+//	400:
+//	401:
+//	403:
+//	503:
+
+// WS upgrades an authenticated device connection to a WebSocket
+func (a *Apocalypse) WS(w http.ResponseWriter, r *http.Request) {
+	logrus.Info("Apocalypse.WS")
+	if a.Hub == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	device := requestActor(r).Name
+
+	var lastID int64 = -1
+	if lastEventID := r.URL.Query().Get("lastEventId"); lastEventID != "" {
+		var err error
+		if lastID, err = strconv.ParseInt(lastEventID, 10, 64); err != nil || lastID < 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	// subscribe before upgrading so that no event falls between the replay
+	// and the live events
+	sub := a.Hub.Subscribe()
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		a.Hub.Unsubscribe(sub)
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Error upgrading to websocket")
+		return
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"device":     device,
+		"remoteAddr": r.RemoteAddr,
+	}).Info("Device connected")
+
+	replies := make(chan WSReply, wsReplyBuffer)
+	done := make(chan struct{})
+	go a.wsWrite(conn, sub, replies, done, lastID)
+	a.wsRead(r.Context(), conn, device, r.RemoteAddr, replies, done)
+	a.Hub.Unsubscribe(sub)
+}
+
+// wsRead handles the messages of a device one at a time until the connection
+// closes. A reply waits while the reply queue is full, so a device sending
+// faster than its replies are written is slowed down
+func (a *Apocalypse) wsRead(ctx context.Context, conn *websocket.Conn, device, remoteAddr string, replies chan<- WSReply, done <-chan struct{}) {
+	defer close(replies)
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				logrus.WithFields(logrus.Fields{
+					"Error":  err,
+					"device": device,
+				}).Info("Error reading from device")
+			}
+			return
+		}
+
+		select {
+		case replies <- a.wsHandle(ctx, data, device, remoteAddr):
+		case <-done:
+			return
+		}
+	}
+}
+
+// wsHandle runs a device message through the REST handler that takes the
+// same payload and returns its response as the reply. The request carries
+// ctx, so it is authorized with the key the device connected with
+func (a *Apocalypse) wsHandle(ctx context.Context, data []byte, device, remoteAddr string) WSReply {
+	message := &WSMessage{}
+	if err := json.Unmarshal(data, message); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Error unmarshalling")
+		return WSReply{Type: WSError, Status: http.StatusBadRequest}
+	}
+
+	var path string
+	var handler http.Handler
+	switch message.Type {
+	case WSLocation:
+		path, handler = "/survivors/location", http.HandlerFunc(a.UpdateLocation)
+	case WSResources:
+		path, handler = "/survivors/resources", http.HandlerFunc(a.UpdateResources)
+	case WSInfection:
+		path, handler = "/survivors/infected", http.HandlerFunc(a.Infected)
+	default:
+		return WSReply{Type: WSError, Ref: message.Ref, Status: http.StatusBadRequest}
+	}
+	if a.Routes != nil {
+		handler = a.Routes
+	}
+
+	r, err := http.NewRequest(http.MethodPut, path, bytes.NewReader(message.Payload))
+	if err != nil {
+		return WSReply{Type: WSError, Ref: message.Ref, Status: http.StatusInternalServerError}
+	}
+	r = r.WithContext(ctx)
+	r.Header.Set(ActorHeader, device)
+	r.RemoteAddr = remoteAddr
+
+	response := &wsResponse{header: http.Header{}}
+	handler.ServeHTTP(response, r)
+
+	reply := WSReply{Type: WSAck, Ref: message.Ref, Status: response.status()}
+	if reply.Status >= http.StatusBadRequest {
+		reply.Type = WSError
+	}
+	if body := bytes.TrimSpace(response.body.Bytes()); json.Valid(body) {
+		reply.Body = json.RawMessage(body)
+	} else if len(body) > 0 {
+		// plain text bodies such as http.Error messages are sent as a string
+		reply.Body, _ = json.Marshal(string(body))
+	}
+
+	return reply
+}
+
+// wsWrite sends replies, survivor events and pings to the device until the
+// replies are closed or the device falls behind on events
+func (a *Apocalypse) wsWrite(conn *websocket.Conn, sub *events.Subscription, replies <-chan WSReply, done chan<- struct{}, lastID int64) {
+	ping := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ping.Stop()
+		close(done)
+		conn.Close()
+	}()
+
+	write := func(reply WSReply) error {
+		conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		return conn.WriteJSON(reply)
+	}
+
+	if lastID >= 0 {
+		var err error
+		lastID, err = events.Replay(a.DB, lastID, func(event survivordb.SurvivorEvent) error {
+			return write(WSReply{Type: WSEvent, Event: &event})
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	for {
+		select {
+		case reply, ok := <-replies:
+			if !ok {
+				conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			if err := write(reply); err != nil {
+				return
+			}
+		case event, ok := <-sub.Events:
+			if !ok {
+				// the device fell behind or the server is shutting down,
+				// the device reconnects with its lastEventId
+				conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "reconnect with lastEventId"))
+				return
+			}
+			if event.ID <= lastID {
+				continue
+			}
+			if err := write(WSReply{Type: WSEvent, Event: &event}); err != nil {
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// wsResponse records the response of a REST handler run for a device message
+type wsResponse struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (w *wsResponse) Header() http.Header {
+	return w.header
+}
+
+func (w *wsResponse) Write(data []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.body.Write(data)
+}
+
+func (w *wsResponse) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+// status returns the status code written, 200 when none was
+func (w *wsResponse) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
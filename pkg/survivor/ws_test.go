@@ -0,0 +1,153 @@
+package survivor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"robo-apocalypse/pkg/auth"
+	"robo-apocalypse/pkg/events"
+	"robo-apocalypse/pkg/survivordb"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestApocalypseApi_WS checks if an authenticated device can update a
+// survivor over a WebSocket and receives the events back
+func TestApocalypseApi_WS(t *testing.T) {
+	robo := &Apocalypse{}
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	if db == nil {
+		return
+	}
+	robo.DB = db
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("Error setting up database: %v", err)
+		return
+	}
+	robo.Hub = events.NewHub(0)
+	key, _, err := auth.NewKey(robo.DB, "scout-1", survivordb.RoleScout)
+	if err != nil {
+		t.Errorf("auth.NewKey(): want: %v, got: %v", nil, err)
+		return
+	}
+	viewerKey, _, err := auth.NewKey(robo.DB, "viewer-1", survivordb.RoleViewer)
+	if err != nil {
+		t.Errorf("auth.NewKey(): want: %v, got: %v", nil, err)
+		return
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/survivors", strings.NewReader(survivorRequest))
+	robo.Survivor(w, r)
+
+	authn := &auth.Authenticator{DB: robo.DB}
+	mux := http.NewServeMux()
+	mux.Handle("/ws", authn.Require(auth.Policy{http.MethodGet: auth.FieldRoles}, robo.WS))
+	mux.Handle("/survivors/location", authn.Require(auth.Policy{http.MethodPut: auth.FieldRoles}, robo.UpdateLocation))
+	mux.Handle("/survivors/infected", authn.Require(auth.Policy{http.MethodPut: auth.MedicRoles}, robo.Infected))
+	robo.Routes = mux
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	for token, want := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusUnauthorized, viewerKey: http.StatusForbidden} {
+		_, response, err := websocket.DefaultDialer.Dial(url+"?token="+token, nil)
+		if err == nil || response == nil || response.StatusCode != want {
+			t.Errorf("Apocalypse.WS(w http.ResponseWriter, r *http.Request) - %q: want: %v, got: %v", token, want, err)
+		}
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+key)
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?lastEventId=0", header)
+	if err != nil {
+		t.Errorf("Apocalypse.WS(w http.ResponseWriter, r *http.Request): %v", err)
+		return
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	reply := WSReply{}
+	if err = conn.ReadJSON(&reply); err != nil || reply.Type != WSEvent || reply.Event.Type != survivordb.EventSurvivorCreated {
+		t.Errorf("Apocalypse.WS(w http.ResponseWriter, r *http.Request): replay: got: %v, %v", reply, err)
+	}
+
+	testCases := []struct {
+		message WSMessage
+		want    WSReply
+	}{
+		{WSMessage{Type: WSLocation, Ref: "1", Payload: []byte(updaterLocationRequest)}, WSReply{Type: WSAck, Ref: "1", Status: http.StatusOK}},
+		{WSMessage{Type: WSLocation, Ref: "2", Payload: []byte(`{"id": "HD138VOP34219", "latitude": 91}`)}, WSReply{Type: WSError, Ref: "2", Status: http.StatusUnprocessableEntity}},
+		{WSMessage{Type: WSResources, Ref: "3", Payload: []byte(`{"id": "HD138VOP00000", "water": 1}`)}, WSReply{Type: WSError, Ref: "3", Status: http.StatusNotFound}},
+		{WSMessage{Type: "trade", Ref: "4", Payload: []byte(`{}`)}, WSReply{Type: WSError, Ref: "4", Status: http.StatusBadRequest}},
+		{WSMessage{Type: WSInfection, Ref: "5", Payload: []byte(`{"id": "HD138VOP34219", "reporter": "HD138VOP34220"}`)}, WSReply{Type: WSError, Ref: "5", Status: http.StatusForbidden}},
+	}
+	for _, tc := range testCases {
+		if err = conn.WriteJSON(tc.message); err != nil {
+			t.Errorf("Apocalypse.WS(w http.ResponseWriter, r *http.Request): %v", err)
+			return
+		}
+		// events of earlier messages may arrive before the reply
+		for {
+			reply = WSReply{}
+			if err = conn.ReadJSON(&reply); err != nil || reply.Type != WSEvent {
+				break
+			}
+		}
+		if err != nil || reply.Type != tc.want.Type || reply.Ref != tc.want.Ref || reply.Status != tc.want.Status {
+			t.Errorf("Apocalypse.WS(w http.ResponseWriter, r *http.Request) - %v: want: %v, got: %v, %v", tc.message.Type, tc.want, reply, err)
+		}
+	}
+
+	events := robo.DB.GetAuditEvents("HD138VOP34219", time.Time{}, 0, 100)
+	if len(events) != 2 || events[1].Action != survivordb.AuditUpdateLocation || events[1].Actor != "scout-1" {
+		t.Errorf("Apocalypse.WS(w http.ResponseWriter, r *http.Request): want the location update by %v, got: %v", "scout-1", events)
+	}
+}
+
+// TestApocalypseApi_WSSlowDevice checks if a device that does not read its
+// events is disconnected with a try again later close code
+func TestApocalypseApi_WSSlowDevice(t *testing.T) {
+	robo := &Apocalypse{}
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	if db == nil {
+		return
+	}
+	robo.DB = db
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("Error setting up database: %v", err)
+		return
+	}
+	robo.Hub = events.NewHub(0)
+	robo.Hub.BufferSize = 1
+
+	server := httptest.NewServer(http.HandlerFunc(robo.WS))
+	defer server.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Errorf("Apocalypse.WS(w http.ResponseWriter, r *http.Request): %v", err)
+		return
+	}
+	defer conn.Close()
+
+	for robo.Hub.Subscribers() > 0 {
+		robo.Hub.Publish(survivordb.SurvivorEvent{ID: 1, Type: survivordb.EventLocationUpdated})
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err = conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+	if !websocket.IsCloseError(err, websocket.CloseTryAgainLater) {
+		t.Errorf("Apocalypse.WS(w http.ResponseWriter, r *http.Request): want: %v, got: %v", websocket.CloseTryAgainLater, err)
+	}
+}
@@ -0,0 +1,198 @@
+package survivor
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"robo-apocalypse/pkg/survivordb"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// swagger:parameters getZone updateZone deleteZone getZoneSurvivors getZoneEvents
+type ZoneIDParam struct {
+	// The id of the zone
+	//
+	// in: path
+	// required: true
+	ID int64 `json:"id"`
+}
+
+// swagger:route GET /zones zones getZones
+// Return all safe zones
+// responses:
+//	200: zonesResponse
+
+// swagger:route POST /zones zones createZone
+// Create a new safe zone
+// responses:
+//	201: zoneResponse
+//	400:
+//	409:
+
+// Zones handles GET requests to list zones and POST requests to create one
+func (a *Apocalypse) Zones(w http.ResponseWriter, r *http.Request) {
+	logrus.Info("Apocalypse.Zones")
+	switch r.Method {
+	case http.MethodGet:
+		zones := a.DB.GetZones()
+		if zones == nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, zones)
+	case http.MethodPost:
+		zone := a.readZone(w, r)
+		if zone == nil {
+			return
+		}
+		if err := a.DB.SaveZone(zone); err != nil {
+			writeZoneError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, zone)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// swagger:route GET /zones/{id} zones getZone
+// Return a safe zone
+// responses:
+//	200: zoneResponse
+//	404:
+
+// swagger:route PUT /zones/{id} zones updateZone
+// Replace the definition of a safe zone
+// responses:
+//	200: zoneResponse
+//	400:
+//	404:
+//	409:
+
+// swagger:route DELETE /zones/{id} zones deleteZone
+// Delete a safe zone with its occupants and events
+// responses:
+//	204:
+//	404:
+
+// swagger:route GET /zones/{id}/survivors zones getZoneSurvivors
+// Return the survivors currently inside a safe zone
+// responses:
+//	200: surivivorsResponse
+//	404:
+
+// swagger:route GET /zones/{id}/events zones getZoneEvents
+// Return the enter and exit events of a safe zone in time order
+// responses:
+//	200: zoneEventsResponse
+//	404:
+
+// ZoneByID handles requests for a single zone under /zones/{id}
+func (a *Apocalypse) ZoneByID(w http.ResponseWriter, r *http.Request) {
+	logrus.Info("Apocalypse.ZoneByID")
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/zones/"), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || len(parts) > 2 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if len(parts) == 2 {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if a.DB.GetZone(id) == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch parts[1] {
+		case "survivors":
+			writeJSON(w, http.StatusOK, a.DB.GetZoneOccupants(id))
+		case "events":
+			writeJSON(w, http.StatusOK, a.DB.GetZoneEvents(id))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		zone := a.DB.GetZone(id)
+		if zone == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, zone)
+	case http.MethodPut:
+		zone := a.readZone(w, r)
+		if zone == nil {
+			return
+		}
+		zone.ID = id
+		if err := a.DB.UpdateZone(zone); err != nil {
+			writeZoneError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, zone)
+	case http.MethodDelete:
+		if err := a.DB.DeleteZone(id); err != nil {
+			writeZoneError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// readZone reads a zone from the request body, writing a 400 response and
+// returning nil when it cannot be read
+func (a *Apocalypse) readZone(w http.ResponseWriter, r *http.Request) *survivordb.Zone {
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1048576))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Error reading response")
+		w.WriteHeader(http.StatusBadRequest)
+		return nil
+	}
+
+	zone := &survivordb.Zone{}
+	if err := json.Unmarshal(body, zone); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"body":  string(body),
+		}).Info("Error unmarshalling")
+		w.WriteHeader(http.StatusBadRequest)
+		return nil
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"body": zone,
+	}).Info("Incoming")
+
+	return zone
+}
+
+// writeZoneError writes the response code for a zone error
+func writeZoneError(w http.ResponseWriter, err error) {
+	logrus.WithFields(logrus.Fields{
+		"Error": err,
+	}).Info("Error saving zone")
+	switch err {
+	case survivordb.ErrInvalidZone:
+		w.WriteHeader(http.StatusBadRequest)
+	case survivordb.ErrZoneNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	case survivordb.ErrDuplicateZone:
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
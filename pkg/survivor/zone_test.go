@@ -0,0 +1,97 @@
+package survivor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"robo-apocalypse/pkg/survivordb"
+	"strings"
+	"testing"
+)
+
+var zoneRequest string = `{
+	"name":		"Camp",
+	"kind":		"circle",
+	"center":	{"longitude": 1, "latitude": 2},
+	"radius":	1
+}`
+
+// TestApocalypseApi_Zones checks if zones can be created and
+// report the survivors inside them after a location update
+func TestApocalypseApi_Zones(t *testing.T) {
+	robo := &Apocalypse{}
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	if db == nil {
+		return
+	}
+	robo.DB = db
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("Error setting up database: %v", err)
+		return
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/zones", strings.NewReader(zoneRequest))
+	robo.Zones(w, r)
+	if w.Code != http.StatusCreated {
+		t.Errorf("Apocalypse.Zones(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusCreated, w.Code)
+	}
+	zone := &survivordb.Zone{}
+	if err := json.NewDecoder(w.Body).Decode(zone); err != nil || zone.ID == 0 {
+		t.Errorf("Apocalypse.Zones(w http.ResponseWriter, r *http.Request): could not decode response: %v", err)
+		return
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, "/zones", strings.NewReader(zoneRequest))
+	robo.Zones(w, r)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Apocalypse.Zones(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusConflict, w.Code)
+	}
+
+	addw := httptest.NewRecorder()
+	addr := httptest.NewRequest(http.MethodPost, "/survivors", strings.NewReader(survivorRequest))
+	robo.Survivor(addw, addr)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPut, "/survivors/location", strings.NewReader(updaterLocationRequest))
+	robo.UpdateLocation(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("Apocalypse.UpdateLocation(w http.ResponseWriter, r *http.Request): want: %v, got: %v", http.StatusOK, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/zones/1/survivors", nil)
+	robo.ZoneByID(w, r)
+	survivors := []survivordb.Survivor{}
+	if err := json.NewDecoder(w.Body).Decode(&survivors); err != nil {
+		t.Errorf("Apocalypse.ZoneByID(w http.ResponseWriter, r *http.Request): could not decode response: %v", err)
+	}
+	if len(survivors) != 1 || survivors[0].IdNumber != "HD138VOP34219" {
+		t.Errorf("Apocalypse.ZoneByID(w http.ResponseWriter, r *http.Request): got: %v", survivors)
+	}
+
+	testCases := []struct {
+		method string
+		url    string
+		want   int
+	}{
+		{http.MethodGet, "/zones/1", http.StatusOK},
+		{http.MethodGet, "/zones/1/events", http.StatusOK},
+		{http.MethodGet, "/zones/2", http.StatusNotFound},
+		{http.MethodGet, "/zones/camp", http.StatusNotFound},
+		{http.MethodDelete, "/zones/1", http.StatusNoContent},
+		{http.MethodGet, "/zones/1/survivors", http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(tc.method, tc.url, nil)
+		robo.ZoneByID(w, r)
+		if w.Code != tc.want {
+			t.Errorf("Apocalypse.ZoneByID(w http.ResponseWriter, r *http.Request) - %s %q: want: %v, got: %v", tc.method, tc.url, tc.want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package survivordb
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrInvalidAPIKey is returned when an API key has no name or an unknown role
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	// ErrAPIKeyNotFound is returned when an API key id is not in the APIKeys
+	// table or the key is already revoked
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+// API key roles
+const (
+	RoleViewer = "viewer"
+	RoleScout  = "scout"
+	RoleMedic  = "medic"
+	RoleAdmin  = "admin"
+)
+
+// Roles lists every API key role
+var Roles = []string{RoleViewer, RoleScout, RoleMedic, RoleAdmin}
+
+// APIKey defines a key clients authenticate with. Only a bcrypt hash of the
+// secret part of the key is stored
+type APIKey struct {
+	// the id of the key
+	ID int64 `json:"id"`
+
+	// who the key was given to, recorded as the actor of their changes
+	Name string `json:"name"`
+
+	// viewer, scout, medic or admin
+	Role string `json:"role"`
+
+	// the public part of the key the key is looked up by
+	Prefix string `json:"prefix"`
+
+	// the bcrypt hash of the secret part of the key
+	Hash []byte `json:"-"`
+
+	// when the key was minted
+	CreatedAt time.Time `json:"createdAt"`
+
+	// when the key was revoked, nil while it is valid
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
+
+// Validate checks the name and role of an API key
+func (k *APIKey) Validate() error {
+	if k.Name == "" || k.Prefix == "" || len(k.Hash) == 0 {
+		return ErrInvalidAPIKey
+	}
+	for _, role := range Roles {
+		if k.Role == role {
+			return nil
+		}
+	}
+
+	return ErrInvalidAPIKey
+}
+
+type apiKeyStmts struct {
+	insertAPIKeyStmt         *sql.Stmt
+	selectAPIKeysStmt        *sql.Stmt
+	selectAPIKeyByPrefixStmt *sql.Stmt
+	revokeAPIKeyStmt         *sql.Stmt
+}
+
+const (
+	insertAPIKeySQL         = `INSERT INTO APIKeys (name, role, prefix, hash) VALUES(?,?,?,?);`
+	selectAPIKeysSQL        = `SELECT id, name, role, prefix, hash, created_ts, revoked_ts FROM APIKeys ORDER BY id;`
+	selectAPIKeyByPrefixSQL = `SELECT id, name, role, prefix, hash, created_ts, revoked_ts FROM APIKeys WHERE prefix = ?;`
+	revokeAPIKeySQL         = `UPDATE APIKeys SET revoked_ts = CURRENT_TIMESTAMP WHERE id = ? AND revoked_ts IS NULL;`
+)
+
+// setupAPIKeys prepares the statements of the APIKeys table
+func (s *SurvivorDB) setupAPIKeys() error {
+	var err error
+	if s.insertAPIKeyStmt, err = s.prepare(insertAPIKeySQL); err != nil {
+		return err
+	}
+	if s.selectAPIKeysStmt, err = s.prepare(selectAPIKeysSQL); err != nil {
+		return err
+	}
+	if s.selectAPIKeyByPrefixStmt, err = s.prepare(selectAPIKeyByPrefixSQL); err != nil {
+		return err
+	}
+	if s.revokeAPIKeyStmt, err = s.prepare(revokeAPIKeySQL); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveAPIKey inserts an API key into the APIKeys table
+func (s *SurvivorDB) SaveAPIKey(key *APIKey) error {
+	if err := key.Validate(); err != nil {
+		return err
+	}
+
+	id, err := s.insert(s.insertAPIKeyStmt, key.Name, key.Role, key.Prefix, key.Hash)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   insertAPIKeySQL,
+		}).Info("Sql error")
+		return err
+	}
+	key.ID = id
+
+	return nil
+}
+
+// RevokeAPIKey marks an API key as revoked so it no longer authenticates
+func (s *SurvivorDB) RevokeAPIKey(id int64) error {
+	result, err := s.revokeAPIKeyStmt.Exec(id)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   revokeAPIKeySQL,
+		}).Info("Sql error")
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// scanAPIKey reads a row of the APIKeys table
+func scanAPIKey(row interface{ Scan(...interface{}) error }) (*APIKey, error) {
+	key := &APIKey{}
+	var revoked sql.NullTime
+	if err := row.Scan(&key.ID, &key.Name, &key.Role, &key.Prefix, &key.Hash, &key.CreatedAt, &revoked); err != nil {
+		return nil, err
+	}
+	if revoked.Valid {
+		key.RevokedAt = &revoked.Time
+	}
+
+	return key, nil
+}
+
+// GetAPIKeys selects all API keys, including the revoked ones
+func (s *SurvivorDB) GetAPIKeys() []APIKey {
+	rows, err := s.selectAPIKeysStmt.Query()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectAPIKeysSQL,
+		}).Info("Sql error")
+		return nil
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectAPIKeysSQL,
+			}).Info("Sql error")
+			return nil
+		}
+		keys = append(keys, *key)
+	}
+	if err = rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectAPIKeysSQL,
+		}).Info("Sql error")
+		return nil
+	}
+
+	return keys
+}
+
+// GetAPIKeyByPrefix selects the API key with the given public prefix
+func (s *SurvivorDB) GetAPIKeyByPrefix(prefix string) *APIKey {
+	key, err := scanAPIKey(s.selectAPIKeyByPrefixStmt.QueryRow(prefix))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectAPIKeyByPrefixSQL,
+			}).Info("Sql error")
+		}
+		return nil
+	}
+
+	return key
+}
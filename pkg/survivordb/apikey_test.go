@@ -0,0 +1,56 @@
+package survivordb
+
+import (
+	"os"
+	"testing"
+)
+
+// TestSurvivorDB_APIKeys checks if API keys can be saved, looked up by
+// prefix and revoked
+func TestSurvivorDB_APIKeys(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	for _, key := range []*APIKey{
+		{Name: "", Role: RoleScout, Prefix: "a1", Hash: []byte("hash")},
+		{Name: "Jane", Role: "boss", Prefix: "a2", Hash: []byte("hash")},
+		{Name: "Jane", Role: RoleScout, Prefix: "a3"},
+	} {
+		if err = survivordb.SaveAPIKey(key); err != ErrInvalidAPIKey {
+			t.Errorf("SurvivorDB.SaveAPIKey() - %v: want: %v, got: %v", key, ErrInvalidAPIKey, err)
+		}
+	}
+
+	key := &APIKey{Name: "Jane", Role: RoleMedic, Prefix: "b1", Hash: []byte("hash")}
+	if err = survivordb.SaveAPIKey(key); err != nil || key.ID == 0 {
+		t.Errorf("SurvivorDB.SaveAPIKey(): want: %v, got: %v", nil, err)
+		return
+	}
+	if err = survivordb.SaveAPIKey(&APIKey{Name: "John", Role: RoleViewer, Prefix: "b1", Hash: []byte("hash")}); err == nil {
+		t.Errorf("SurvivorDB.SaveAPIKey(): want an error for a duplicate prefix")
+	}
+
+	got := survivordb.GetAPIKeyByPrefix("b1")
+	if got == nil || got.Name != "Jane" || got.Role != RoleMedic || string(got.Hash) != "hash" || got.RevokedAt != nil {
+		t.Errorf("SurvivorDB.GetAPIKeyByPrefix(): want: %v, got: %v", key, got)
+	}
+	if got = survivordb.GetAPIKeyByPrefix("zz"); got != nil {
+		t.Errorf("SurvivorDB.GetAPIKeyByPrefix(): want: %v, got: %v", nil, got)
+	}
+
+	if err = survivordb.RevokeAPIKey(key.ID); err != nil {
+		t.Errorf("SurvivorDB.RevokeAPIKey(): want: %v, got: %v", nil, err)
+	}
+	if err = survivordb.RevokeAPIKey(key.ID); err != ErrAPIKeyNotFound {
+		t.Errorf("SurvivorDB.RevokeAPIKey(): want: %v, got: %v", ErrAPIKeyNotFound, err)
+	}
+	keys := survivordb.GetAPIKeys()
+	if len(keys) != 1 || keys[0].RevokedAt == nil {
+		t.Errorf("SurvivorDB.GetAPIKeys(): want one revoked key, got: %v", keys)
+	}
+}
@@ -0,0 +1,324 @@
+package survivordb
+
+import (
+	"database/sql"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Audit actions
+const (
+	AuditCreate          = "create"
+	AuditUpdateLocation  = "update_location"
+	AuditUpdateResources = "update_resources"
+	AuditUpdateInfected  = "update_infected"
+	AuditReportInfection = "report_infection"
+	AuditTrade           = "trade"
+	AuditPatch           = "patch"
+	AuditDelete          = "delete"
+)
+
+// systemActor is recorded for changes made without an actor, such as
+// background jobs and command line tools
+const systemActor = "system"
+
+// Actor defines who is making a change
+type Actor struct {
+	// Name identifies the caller
+	Name string
+	// RemoteAddr is the network address the change came from
+	RemoteAddr string
+}
+
+// AuditChange defines the value of a survivor field before and after a change
+// swagger:model
+type AuditChange struct {
+	// the value before the change, null when the survivor was created
+	//
+	// required: true
+	Before interface{} `json:"before"`
+
+	// the value after the change, null when the survivor was deleted
+	//
+	// required: true
+	After interface{} `json:"after"`
+}
+
+// AuditEvent defines one change to a survivor
+// swagger:model
+type AuditEvent struct {
+	// the id of the event
+	//
+	// required: true
+	ID int64 `json:"eventId"`
+
+	// the id number of the survivor that changed
+	//
+	// required: true
+	IdNumber string `json:"id"`
+
+	// the change made: create, update_location, update_resources,
+	// update_infected, report_infection, trade, patch or delete
+	//
+	// required: true
+	Action string `json:"action"`
+
+	// who made the change
+	//
+	// required: true
+	Actor string `json:"actor"`
+
+	// the network address the change came from
+	//
+	// required: true
+	RemoteAddr string `json:"remoteAddr"`
+
+	// the survivor fields that changed, keyed by field name
+	//
+	// required: true
+	Diff map[string]AuditChange `json:"diff"`
+
+	// the time of the change
+	//
+	// required: true
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type auditStmts struct {
+	insertAuditStmt      *sql.Stmt
+	selectAuditStmt      *sql.Stmt
+	selectAuditAfterStmt *sql.Stmt
+	selectLastAuditStmt  *sql.Stmt
+}
+
+const (
+	insertAuditSQL = `INSERT INTO AuditEvents (survivor_id, action, actor, remote_addr, diff) VALUES(?,?,?,?,?);`
+	selectAuditSQL = `SELECT id, survivor_id, action, actor, remote_addr, diff, recorded_ts FROM AuditEvents
+	WHERE (? = '' OR survivor_id = ?) AND recorded_ts >= ? AND id > ? ORDER BY id LIMIT ?;`
+	selectAuditAfterSQL = `SELECT id, survivor_id, action, actor, remote_addr, diff, recorded_ts FROM AuditEvents
+	WHERE id > ? ORDER BY id LIMIT ?;`
+	selectLastAuditSQL = `SELECT coalesce(max(id), 0) FROM AuditEvents;`
+)
+
+// setupAuditEvents prepares the statements of the append-only AuditEvents table
+func (s *SurvivorDB) setupAuditEvents() error {
+	var err error
+	if s.insertAuditStmt, err = s.prepare(insertAuditSQL); err != nil {
+		return err
+	}
+	if s.selectAuditStmt, err = s.prepare(selectAuditSQL); err != nil {
+		return err
+	}
+	if s.selectAuditAfterStmt, err = s.prepare(selectAuditAfterSQL); err != nil {
+		return err
+	}
+	if s.selectLastAuditStmt, err = s.prepare(selectLastAuditSQL); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// WithActor returns a copy of the database handle that records actor in the
+// audit events of the changes it makes
+func (s *SurvivorDB) WithActor(actor Actor) SurvivorStore {
+	audited := *s
+	audited.actor = actor
+
+	return &audited
+}
+
+// clone copies a survivor so that changing its inventory leaves the original alone
+func (s *Survivor) clone() *Survivor {
+	cloned := *s
+	cloned.Inventory = append([]InventoryItem(nil), s.Inventory...)
+
+	return &cloned
+}
+
+// diffSurvivors lists the json fields that differ between before and after.
+// A nil survivor has no fields
+func diffSurvivors(before, after *Survivor) (map[string]AuditChange, error) {
+	fields := func(survivor *Survivor) (map[string]interface{}, error) {
+		values := map[string]interface{}{}
+		if survivor == nil {
+			return values, nil
+		}
+		buffer, err := json.Marshal(survivor)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(buffer, &values); err != nil {
+			return nil, err
+		}
+		delete(values, "timestamp")
+
+		return values, nil
+	}
+
+	beforeFields, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := map[string]AuditChange{}
+	for name, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[name]) {
+			diff[name] = AuditChange{Before: value, After: afterFields[name]}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			diff[name] = AuditChange{Before: nil, After: value}
+		}
+	}
+
+	return diff, nil
+}
+
+// auditTx records a change to a survivor inside a transaction. before is the
+// survivor as it was, or nil when it was created. The survivor as it is now
+// is read back from the transaction
+func (s *SurvivorDB) auditTx(tx *sql.Tx, action, idNumber string, before *Survivor) error {
+	after, err := s.getSurvivorTx(tx, idNumber)
+	if err == ErrNotFound {
+		after, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	diff, err := diffSurvivors(before, after)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Error computing audit diff")
+		return err
+	}
+	diffBuffer, err := json.Marshal(diff)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Error computing audit diff")
+		return err
+	}
+
+	actor := s.actor.Name
+	if actor == "" {
+		actor = systemActor
+	}
+	_, err = tx.Stmt(s.insertAuditStmt).Exec(idNumber, action, actor, s.actor.RemoteAddr, string(diffBuffer))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   insertAuditSQL,
+		}).Info("Sql error")
+		return err
+	}
+
+	return nil
+}
+
+// GetAuditEvents selects at most limit audit events of a survivor, or of all
+// survivors when idNumber is empty, recorded at or after since with an id
+// greater than afterID in the order they happened
+func (s *SurvivorDB) GetAuditEvents(idNumber string, since time.Time, afterID int64, limit int) []AuditEvent {
+	sinceParam := minTimestamp
+	if !since.IsZero() {
+		sinceParam = since.UTC().Format(sqliteTimeFormat)
+	}
+
+	rows, err := s.selectAuditStmt.Query(idNumber, idNumber, sinceParam, afterID, limit)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectAuditSQL,
+		}).Info("Sql error")
+		return nil
+	}
+	defer rows.Close()
+
+	events, err := scanAuditEvents(rows, selectAuditSQL)
+	if err != nil {
+		return nil
+	}
+
+	return events
+}
+
+// GetAuditEventsAfter selects at most limit audit events with an id greater
+// than afterID in the order they happened
+func (s *SurvivorDB) GetAuditEventsAfter(afterID int64, limit int) ([]AuditEvent, error) {
+	rows, err := s.selectAuditAfterStmt.Query(afterID, limit)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectAuditAfterSQL,
+		}).Info("Sql error")
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanAuditEvents(rows, selectAuditAfterSQL)
+}
+
+// LastAuditEventID returns the id of the last audit event, 0 when there are none
+func (s *SurvivorDB) LastAuditEventID() (int64, error) {
+	var id int64
+	if err := s.selectLastAuditStmt.QueryRow().Scan(&id); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectLastAuditSQL,
+		}).Info("Sql error")
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// scanAuditEvents reads the rows of an AuditEvents query
+func scanAuditEvents(rows *sql.Rows, query string) ([]AuditEvent, error) {
+	events := []AuditEvent{}
+	for rows.Next() {
+		event := AuditEvent{}
+		var diff string
+		err := rows.Scan(&event.ID,
+			&event.IdNumber,
+			&event.Action,
+			&event.Actor,
+			&event.RemoteAddr,
+			&diff,
+			&event.Timestamp)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   query,
+			}).Info("Sql error")
+			return nil, err
+		}
+		if err = json.Unmarshal([]byte(diff), &event.Diff); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"diff":  diff,
+			}).Info("Error unmarshalling")
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   query,
+		}).Info("Sql error")
+		return nil, err
+	}
+
+	return events, nil
+}
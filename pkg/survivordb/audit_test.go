@@ -0,0 +1,105 @@
+package survivordb
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// TestSurvivorDB_AuditEvents checks if every change to a survivor is recorded
+func TestSurvivorDB_AuditEvents(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	scout := survivordb.WithActor(Actor{Name: "scout", RemoteAddr: "10.0.0.1:1234"})
+	for _, survivor := range []*Survivor{
+		{Name: "Jane Doe", Age: 1, Gender: "Female", IdNumber: "HD138VOP34219", Resources: Resources{Water: 2}},
+		{Name: "John Doe", Age: 1, Gender: "Male", IdNumber: "HD138VOP34220", Resources: Resources{Ammunition: 8}},
+	} {
+		if err = scout.Save(survivor); err != nil {
+			t.Errorf("SurvivorDB.Save() - %q: want: %v, got: %v", survivor.Name, nil, err)
+		}
+	}
+	if _, err = scout.UpdateLocation("HD138VOP34219", 1, 2); err != nil {
+		t.Errorf("SurvivorDB.UpdateLocation(): want: %v, got: %v", nil, err)
+	}
+	if err = survivordb.UpdateResource("HD138VOP34219", 4, "Fish", "", 0); err != nil {
+		t.Errorf("SurvivorDB.UpdateResource(): want: %v, got: %v", nil, err)
+	}
+	if _, err = scout.ReportInfection("HD138VOP34220", "HD138VOP34219", 1); err != nil {
+		t.Errorf("SurvivorDB.ReportInfection(): want: %v, got: %v", nil, err)
+	}
+	if err = scout.DeleteSurvivor("HD138VOP34220"); err != nil {
+		t.Errorf("SurvivorDB.DeleteSurvivor(): want: %v, got: %v", nil, err)
+	}
+
+	events := survivordb.GetAuditEvents("HD138VOP34219", time.Time{}, 0, 100)
+	wantActions := []string{AuditCreate, AuditUpdateLocation, AuditUpdateResources, AuditReportInfection}
+	if len(events) != len(wantActions) {
+		t.Errorf("SurvivorDB.GetAuditEvents(): want: %v, got: %v", wantActions, events)
+		return
+	}
+	for i, action := range wantActions {
+		if events[i].Action != action || events[i].IdNumber != "HD138VOP34219" {
+			t.Errorf("SurvivorDB.GetAuditEvents(): want: %v, got: %v", action, events[i])
+		}
+	}
+
+	created := events[0]
+	if created.Actor != "scout" || created.RemoteAddr != "10.0.0.1:1234" || created.Diff["name"].After != "Jane Doe" ||
+		created.Diff["name"].Before != nil || created.Timestamp.IsZero() {
+		t.Errorf("SurvivorDB.GetAuditEvents(): create: got: %v", created)
+	}
+	moved := events[1]
+	if len(moved.Diff) != 2 || moved.Diff["longitude"].Before != 0.0 || moved.Diff["longitude"].After != 1.0 ||
+		moved.Diff["latitude"].After != 2.0 {
+		t.Errorf("SurvivorDB.GetAuditEvents(): location diff: got: %v", moved.Diff)
+	}
+	if events[2].Actor != systemActor || events[2].Diff["water"].After != 4.0 {
+		t.Errorf("SurvivorDB.GetAuditEvents(): resources: got: %v", events[2])
+	}
+	if infected := events[3].Diff["infected"]; infected.Before != false || infected.After != true {
+		t.Errorf("SurvivorDB.GetAuditEvents(): infected diff: got: %v", events[3].Diff)
+	}
+
+	deleted := survivordb.GetAuditEvents("HD138VOP34220", time.Time{}, 0, 100)
+	if len(deleted) != 2 || deleted[1].Action != AuditDelete || deleted[1].Diff["name"].After != nil {
+		t.Errorf("SurvivorDB.GetAuditEvents(): delete: got: %v", deleted)
+	}
+	if all := survivordb.GetAuditEvents("", time.Time{}, 0, 100); len(all) != 6 {
+		t.Errorf("SurvivorDB.GetAuditEvents(): want: %v, got: %v", 6, len(all))
+	}
+	if future := survivordb.GetAuditEvents("", time.Now().Add(time.Hour), 0, 100); len(future) != 0 {
+		t.Errorf("SurvivorDB.GetAuditEvents(): want: %v, got: %v", 0, len(future))
+	}
+
+	if _, err = survivordb.DB.Exec(`DELETE FROM AuditEvents;`); err == nil {
+		t.Errorf("SurvivorDB.DB.Exec(): want: append-only error, got: %v", err)
+	}
+	if _, err = survivordb.DB.Exec(`UPDATE AuditEvents SET actor = 'nobody';`); err == nil {
+		t.Errorf("SurvivorDB.DB.Exec(): want: append-only error, got: %v", err)
+	}
+}
+
+// TestSurvivorDB_AuditEvents_Failed checks if failed changes are not recorded
+func TestSurvivorDB_AuditEvents_Failed(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	if _, err = survivordb.UpdateLocation("UNKNOWN", 1, 2); err != ErrNotFound {
+		t.Errorf("SurvivorDB.UpdateLocation(): want: %v, got: %v", ErrNotFound, err)
+	}
+	if events := survivordb.GetAuditEvents("", time.Time{}, 0, 100); len(events) != 0 {
+		t.Errorf("SurvivorDB.GetAuditEvents(): want: %v, got: %v", 0, events)
+	}
+}
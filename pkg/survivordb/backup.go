@@ -0,0 +1,245 @@
+package survivordb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrBackupUnsupported is returned when backing up or restoring a database
+// that is not SQLite
+var ErrBackupUnsupported = errors.New("backups are only supported on sqlite3")
+
+// ErrInvalidBackup is returned when a backup fails its integrity check or
+// is not a survivors database
+var ErrInvalidBackup = errors.New("invalid backup")
+
+const (
+	// backupPages is the number of pages copied at a time, between which
+	// the database is unlocked so that the server keeps writing
+	backupPages = 256
+	// backupPause is how long the copy waits between steps
+	backupPause = 10 * time.Millisecond
+	// backupPrefix and backupTimeFormat name the scheduled backups so that
+	// they sort by age
+	backupPrefix     = "apocalypse-"
+	backupTimeFormat = "20060102T150405.000Z"
+	backupExt        = ".db"
+)
+
+// copySQLite copies the main database of src into that of dest with SQLite's
+// online backup API, a few pages at a time
+func copySQLite(ctx context.Context, dest, src *sql.Conn) error {
+	return dest.Raw(func(destConn interface{}) error {
+		return src.Raw(func(srcConn interface{}) error {
+			backup, err := destConn.(*sqlite3.SQLiteConn).Backup("main", srcConn.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			for {
+				done, err := backup.Step(backupPages)
+				if err != nil {
+					backup.Finish()
+					return err
+				}
+				if done {
+					return backup.Finish()
+				}
+
+				select {
+				case <-ctx.Done():
+					backup.Finish()
+					return ctx.Err()
+				case <-time.After(backupPause):
+				}
+			}
+		})
+	})
+}
+
+// copyFile copies the database file name into the database s when restore is
+// true and s into name otherwise
+func (s *SurvivorDB) copyFile(name string, restore bool) error {
+	if s.Driver != SQLite {
+		return ErrBackupUnsupported
+	}
+
+	ctx := context.Background()
+	file, err := sql.Open(SQLite, name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	fileConn, err := file.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer fileConn.Close()
+	conn, err := s.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if restore {
+		return copySQLite(ctx, conn, fileConn)
+	}
+	return copySQLite(ctx, fileConn, conn)
+}
+
+// Backup copies the database to the file dest while it is in use. The copy
+// is written next to dest and only replaces it once it passes VerifyBackup
+func (s *SurvivorDB) Backup(dest string) error {
+	tmp := dest + ".tmp"
+	os.Remove(tmp)
+	if err := s.copyFile(tmp, false); err != nil {
+		os.Remove(tmp)
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"file":  dest,
+		}).Info("Backup error")
+		return err
+	}
+	if err := VerifyBackup(tmp); err != nil {
+		os.Remove(tmp)
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"file":  dest,
+		}).Info("Backup error")
+		return err
+	}
+
+	return os.Rename(tmp, dest)
+}
+
+// Restore replaces the database with the backup src once it passes
+// VerifyBackup
+func (s *SurvivorDB) Restore(src string) error {
+	if s.Driver != SQLite {
+		return ErrBackupUnsupported
+	}
+	if err := VerifyBackup(src); err != nil {
+		return err
+	}
+
+	if err := s.copyFile(src, true); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"file":  src,
+		}).Info("Restore error")
+		return err
+	}
+
+	return nil
+}
+
+// VerifyBackup checks the integrity of the SQLite backup name and that it
+// holds a survivors schema this version can migrate
+func VerifyBackup(name string) error {
+	if _, err := os.Stat(name); err != nil {
+		return err
+	}
+	backup, err := sql.Open(SQLite, "file:"+name+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer backup.Close()
+
+	var result string
+	if err = backup.QueryRow(`PRAGMA integrity_check;`).Scan(&result); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("%w: %s", ErrInvalidBackup, result)
+	}
+
+	var version sql.NullInt64
+	if err = backup.QueryRow(`SELECT max(version) FROM schema_version;`).Scan(&version); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackup, err)
+	}
+	migrations, err := Migrations(SQLite)
+	if err != nil {
+		return err
+	}
+	if int(version.Int64) > migrations[len(migrations)-1].Version {
+		return ErrSchemaTooNew
+	}
+
+	return nil
+}
+
+// BackupTo backs up the database to a new timestamped file in dir and then
+// removes all but the last keep backups there. keep 0 keeps every backup.
+// It returns the name of the new backup
+func (s *SurvivorDB) BackupTo(dir string, keep int) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	name := filepath.Join(dir, backupPrefix+time.Now().UTC().Format(backupTimeFormat)+backupExt)
+	if err := s.Backup(name); err != nil {
+		return "", err
+	}
+
+	return name, rotateBackups(dir, keep)
+}
+
+// rotateBackups removes all but the last keep scheduled backups in dir
+func rotateBackups(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	backups := []string{}
+	for _, entry := range entries {
+		if name := entry.Name(); !entry.IsDir() && strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupExt) {
+			backups = append(backups, name)
+		}
+	}
+	sort.Strings(backups)
+	for len(backups) > keep {
+		if err = os.Remove(filepath.Join(dir, backups[0])); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+
+	return nil
+}
+
+// RunBackups backs up db to dir straight away and then every interval until
+// ctx is done, keeping the last keep backups
+func RunBackups(ctx context.Context, db *SurvivorDB, dir string, interval time.Duration, keep int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if name, err := db.BackupTo(dir, keep); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+			}).Info("Error backing up database")
+		} else {
+			logrus.WithFields(logrus.Fields{
+				"file": name,
+			}).Info("Backed up database")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
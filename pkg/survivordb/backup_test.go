@@ -0,0 +1,112 @@
+package survivordb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSurvivorDB_Backup checks if a backup holds the survivors and restores
+// them
+func TestSurvivorDB_Backup(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	if err := survivordb.Setup(); err != nil {
+		t.Fatalf("SurvivorDB.Setup(): Failed to setup database")
+	}
+	defer survivordb.DB.Close()
+	if err := survivordb.Save(&Survivor{Name: "Jane Doe", IdNumber: "HD138VOP34219"}); err != nil {
+		t.Fatalf("SurvivorDB.Save(): want: %v, got: %v", nil, err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "backup.db")
+	if err := survivordb.Backup(dest); err != nil {
+		t.Fatalf("SurvivorDB.Backup(): want: %v, got: %v", nil, err)
+	}
+	if err := VerifyBackup(dest); err != nil {
+		t.Errorf("VerifyBackup(): want: %v, got: %v", nil, err)
+	}
+	if _, err := os.Stat(dest + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("SurvivorDB.Backup(): want: no temporary file, got: %v", err)
+	}
+
+	if err := survivordb.Save(&Survivor{Name: "John Doe", IdNumber: "HD138VOP34220"}); err != nil {
+		t.Fatalf("SurvivorDB.Save(): want: %v, got: %v", nil, err)
+	}
+	if err := survivordb.Restore(dest); err != nil {
+		t.Fatalf("SurvivorDB.Restore(): want: %v, got: %v", nil, err)
+	}
+	if survivors := survivordb.GetAllSurvivors(); len(survivors) != 1 || survivors[0].IdNumber != "HD138VOP34219" {
+		t.Errorf("SurvivorDB.Restore(): want: %v, got: %v", "HD138VOP34219", survivors)
+	}
+}
+
+// TestVerifyBackup checks if files that are not survivor databases fail the
+// check
+func TestVerifyBackup(t *testing.T) {
+	dir := t.TempDir()
+	garbage := filepath.Join(dir, "garbage.db")
+	if err := os.WriteFile(garbage, []byte("not a database, just the robots' shopping list"), 0644); err != nil {
+		t.Fatalf("os.WriteFile(): want: %v, got: %v", nil, err)
+	}
+	if err := VerifyBackup(garbage); !errors.Is(err, ErrInvalidBackup) {
+		t.Errorf("VerifyBackup() - garbage: want: %v, got: %v", ErrInvalidBackup, err)
+	}
+
+	empty := Open(filepath.Join(dir, "empty.db"))
+	if _, err := empty.DB.Exec(`CREATE TABLE robots (id INTEGER);`); err != nil {
+		t.Fatalf("SurvivorDB.DB.Exec(): want: %v, got: %v", nil, err)
+	}
+	empty.DB.Close()
+	if err := VerifyBackup(filepath.Join(dir, "empty.db")); !errors.Is(err, ErrInvalidBackup) {
+		t.Errorf("VerifyBackup() - no schema: want: %v, got: %v", ErrInvalidBackup, err)
+	}
+
+	if err := VerifyBackup(filepath.Join(dir, "missing.db")); !os.IsNotExist(err) {
+		t.Errorf("VerifyBackup() - missing: want: %v, got: %v", os.ErrNotExist, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing.db")); !os.IsNotExist(err) {
+		t.Errorf("VerifyBackup() - missing: want: no file created, got: %v", err)
+	}
+}
+
+// TestSurvivorDB_BackupTo checks if only the last scheduled backups are kept
+func TestSurvivorDB_BackupTo(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	if err := survivordb.Setup(); err != nil {
+		t.Fatalf("SurvivorDB.Setup(): Failed to setup database")
+	}
+	defer survivordb.DB.Close()
+
+	dir := t.TempDir()
+	for _, name := range []string{"apocalypse-20210101T000000.000Z.db", "apocalypse-20210102T000000.000Z.db", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatalf("os.WriteFile(): want: %v, got: %v", nil, err)
+		}
+	}
+
+	name, err := survivordb.BackupTo(dir, 2)
+	if err != nil {
+		t.Fatalf("SurvivorDB.BackupTo(): want: %v, got: %v", nil, err)
+	}
+	if err = VerifyBackup(name); err != nil {
+		t.Errorf("VerifyBackup(): want: %v, got: %v", nil, err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	got := []string{}
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	want := []string{"apocalypse-20210102T000000.000Z.db", filepath.Base(name), "notes.txt"}
+	if len(got) != len(want) {
+		t.Fatalf("SurvivorDB.BackupTo(): want: %v, got: %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("SurvivorDB.BackupTo(): want: %v, got: %v", want, got)
+		}
+	}
+}
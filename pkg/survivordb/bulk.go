@@ -0,0 +1,423 @@
+package survivordb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Formats of the survivor import and export files
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// ErrUnknownFormat is returned when importing or exporting a format other
+// than FormatCSV and FormatNDJSON
+var ErrUnknownFormat = errors.New("unknown format, want csv or ndjson")
+
+// ErrInvalidImport is returned when an import file cannot be read as a whole,
+// e.g. its CSV header names unknown columns
+var ErrInvalidImport = errors.New("invalid import file")
+
+// maxNDJSONLine is the longest survivor line an NDJSON import may hold
+const maxNDJSONLine = 1048576
+
+// csvColumns are the columns of a CSV export, named after the json fields.
+// Imports may hold them in any order and leave out all but id; the
+// timestamp is set on import and ignored
+var csvColumns = []string{"name", "age", "gender", "id", "longitude", "latitude",
+	"water", "food", "medication", "ammunition", "infected", "timestamp"}
+
+// ImportRow defines a survivor read from an import file
+type ImportRow struct {
+	// Row is the number of the survivor in the file, from 1, not counting
+	// the CSV header and blank NDJSON lines
+	Row      int
+	Survivor Survivor
+}
+
+// ImportError defines why a row of an import file was not imported
+// swagger:model
+type ImportError struct {
+	// the number of the survivor in the file, from 1, not counting the CSV
+	// header and blank NDJSON lines
+	//
+	// required: true
+	Row int `json:"row"`
+
+	// the id number of the survivor when it could be read
+	//
+	// required: false
+	IdNumber string `json:"id,omitempty"`
+
+	// what is wrong with the row
+	//
+	// required: true
+	Message string `json:"message"`
+
+	// the invalid fields of the row
+	//
+	// required: false
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// ImportResult defines the outcome of an import. Nothing is imported when
+// any row has an error
+// swagger:model
+type ImportResult struct {
+	// the number of survivors in the file
+	//
+	// required: true
+	Rows int `json:"rows"`
+
+	// the number of survivors imported, or that would be on a dry run
+	//
+	// required: true
+	Imported int `json:"imported"`
+
+	// whether the import was only checked and nothing was stored
+	//
+	// required: true
+	DryRun bool `json:"dryRun"`
+
+	// the rows that could not be imported
+	//
+	// required: true
+	Errors []ImportError `json:"errors"`
+}
+
+// importError returns the ImportError of row for err
+func importError(row int, idNumber string, err error) ImportError {
+	importErr := ImportError{Row: row, IdNumber: idNumber, Message: err.Error()}
+	if validationErr, ok := err.(*ValidationError); ok {
+		importErr.Message = "invalid survivor"
+		importErr.Fields = validationErr.Errors
+	}
+	if err == ErrDuplicateIdNumber {
+		importErr.Fields = []FieldError{{Field: "id", Message: "is already registered"}}
+	}
+
+	return importErr
+}
+
+// DecodeSurvivors reads the survivors of an import file in format. Rows that
+// cannot be read are returned as ImportErrors; an error is returned when the
+// file as a whole cannot be read
+func DecodeSurvivors(r io.Reader, format string) ([]ImportRow, []ImportError, error) {
+	switch format {
+	case FormatCSV:
+		return decodeCSV(r)
+	case FormatNDJSON:
+		return decodeNDJSON(r)
+	default:
+		return nil, nil, ErrUnknownFormat
+	}
+}
+
+// decodeCSV reads survivors from CSV with a header row naming the columns
+func decodeCSV(r io.Reader) ([]ImportRow, []ImportError, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []ImportRow{}, []ImportError{}, nil
+	}
+	if err != nil {
+		return nil, nil, csvError(err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		known := false
+		for _, column := range csvColumns {
+			known = known || name == column
+		}
+		if !known {
+			return nil, nil, fmt.Errorf("%w: unknown csv column %q", ErrInvalidImport, name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["id"]; !ok {
+		return nil, nil, fmt.Errorf("%w: csv header has no id column", ErrInvalidImport)
+	}
+
+	rows := []ImportRow{}
+	rowErrors := []ImportError{}
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if parseErr, ok := err.(*csv.ParseError); ok && parseErr.Err == csv.ErrFieldCount {
+			rowErrors = append(rowErrors, ImportError{Row: row, Message: fmt.Sprintf("has %d columns, want %d", len(record), len(header))})
+			continue
+		}
+		if err != nil {
+			return nil, nil, csvError(err)
+		}
+
+		survivor, err := csvSurvivor(columns, record)
+		if err != nil {
+			rowErrors = append(rowErrors, importError(row, survivor.IdNumber, err))
+			continue
+		}
+		rows = append(rows, ImportRow{Row: row, Survivor: *survivor})
+	}
+
+	return rows, rowErrors, nil
+}
+
+// csvError wraps the CSV syntax errors with ErrInvalidImport
+func csvError(err error) error {
+	if _, ok := err.(*csv.ParseError); ok {
+		return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	return err
+}
+
+// csvSurvivor reads a survivor from a CSV record. It returns a
+// ValidationError listing the values that are not numbers or booleans
+func csvSurvivor(columns map[string]int, record []string) (*Survivor, error) {
+	v := &validator{}
+	value := func(column string) string {
+		if i, ok := columns[column]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	number := func(column string) float64 {
+		if value(column) == "" {
+			return 0
+		}
+		n, err := strconv.ParseFloat(value(column), 64)
+		if err != nil {
+			v.add(column, "must be a number")
+		}
+		return n
+	}
+	integer := func(column string) int {
+		if value(column) == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(value(column))
+		if err != nil {
+			v.add(column, "must be a whole number")
+		}
+		return n
+	}
+
+	survivor := &Survivor{
+		Name:     value("name"),
+		Age:      integer("age"),
+		Gender:   value("gender"),
+		IdNumber: value("id"),
+		LastLocation: LastLocation{
+			Longitude: number("longitude"),
+			Latitude:  number("latitude"),
+		},
+		Resources: Resources{
+			Water:      number("water"),
+			Food:       value("food"),
+			Medication: value("medication"),
+			Ammunition: integer("ammunition"),
+		},
+	}
+	if infected := value("infected"); infected != "" {
+		var err error
+		if survivor.Infected, err = strconv.ParseBool(infected); err != nil {
+			v.add("infected", "must be true or false")
+		}
+	}
+
+	return survivor, v.err()
+}
+
+// decodeNDJSON reads survivors from newline delimited JSON, one survivor
+// object per line
+func decodeNDJSON(r io.Reader) ([]ImportRow, []ImportError, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+
+	rows := []ImportRow{}
+	rowErrors := []ImportError{}
+	row := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		row++
+
+		survivor := Survivor{}
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&survivor); err != nil {
+			rowErrors = append(rowErrors, importError(row, survivor.IdNumber, err))
+			continue
+		}
+		rows = append(rows, ImportRow{Row: row, Survivor: survivor})
+	}
+
+	if err := scanner.Err(); err == bufio.ErrTooLong {
+		return nil, nil, fmt.Errorf("%w: row %d is longer than %d bytes", ErrInvalidImport, row+1, maxNDJSONLine)
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	return rows, rowErrors, nil
+}
+
+// EncodeSurvivors writes survivors in format
+func EncodeSurvivors(w io.Writer, format string, survivors []Survivor) error {
+	switch format {
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		writer.Write(csvColumns)
+		for _, s := range survivors {
+			writer.Write([]string{
+				s.Name,
+				strconv.Itoa(s.Age),
+				s.Gender,
+				s.IdNumber,
+				strconv.FormatFloat(s.Longitude, 'f', -1, 64),
+				strconv.FormatFloat(s.Latitude, 'f', -1, 64),
+				strconv.FormatFloat(s.Water, 'f', -1, 64),
+				s.Food,
+				s.Medication,
+				strconv.Itoa(s.Ammunition),
+				strconv.FormatBool(s.Infected),
+				s.LastUpdateTime.UTC().Format(time.RFC3339),
+			})
+		}
+		writer.Flush()
+		return writer.Error()
+	case FormatNDJSON:
+		encoder := json.NewEncoder(w)
+		for i := range survivors {
+			if err := encoder.Encode(&survivors[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return ErrUnknownFormat
+	}
+}
+
+// Import reads the survivors of an import file in format and imports them
+// into store in one go, see SurvivorStore.ImportSurvivors. When any row
+// cannot be read the rest are only checked
+func Import(store SurvivorStore, r io.Reader, format string, dryRun bool) (*ImportResult, error) {
+	rows, rowErrors, err := DecodeSurvivors(r, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return ImportRows(store, rows, rowErrors, dryRun)
+}
+
+// ImportRows imports the rows DecodeSurvivors read into store in one go,
+// reporting rowErrors, the rows it could not read, along with those of the
+// import. When there are any the rest are only checked
+func ImportRows(store SurvivorStore, rows []ImportRow, rowErrors []ImportError, dryRun bool) (*ImportResult, error) {
+	result, err := store.ImportSurvivors(rows, dryRun || len(rowErrors) > 0)
+	if err != nil {
+		return nil, err
+	}
+	result.Rows += len(rowErrors)
+	result.DryRun = dryRun
+	result.Errors = append(result.Errors, rowErrors...)
+	sort.SliceStable(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Row < result.Errors[j].Row
+	})
+	if len(result.Errors) > 0 {
+		result.Imported = 0
+	}
+
+	return result, nil
+}
+
+// Export writes every survivor of store in format
+func Export(store SurvivorStore, w io.Writer, format string) error {
+	if format != FormatCSV && format != FormatNDJSON {
+		return ErrUnknownFormat
+	}
+
+	return EncodeSurvivors(w, format, store.GetAllSurvivors())
+}
+
+// checkImportRow validates a row, sets its inventory and checks its id number
+// is not repeated in the file. seen holds the id numbers of the rows before it
+func checkImportRow(row *ImportRow, seen map[string]bool) error {
+	if err := row.Survivor.Validate(); err != nil {
+		return err
+	}
+	row.Survivor.SetItems(row.Survivor.Items())
+	if seen[row.Survivor.IdNumber] {
+		return ErrDuplicateIdNumber
+	}
+	seen[row.Survivor.IdNumber] = true
+
+	return nil
+}
+
+// ImportSurvivors stores the survivors of rows in one transaction. Every row
+// is checked and those that are invalid or whose id number is registered are
+// reported; nothing is stored when any row is, nor on a dry run
+func (s *SurvivorDB) ImportSurvivors(rows []ImportRow, dryRun bool) (*ImportResult, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result := &ImportResult{Rows: len(rows), DryRun: dryRun, Errors: []ImportError{}}
+	seen := map[string]bool{}
+	for i := range rows {
+		survivor := &rows[i].Survivor
+		err = checkImportRow(&rows[i], seen)
+		if err == nil {
+			err = s.saveTx(tx, survivor)
+		}
+		if err == nil {
+			result.Imported++
+			continue
+		}
+		if _, ok := err.(*ValidationError); !ok && err != ErrDuplicateIdNumber {
+			return nil, err
+		}
+		result.Errors = append(result.Errors, importError(rows[i].Row, survivor.IdNumber, err))
+	}
+	if len(result.Errors) > 0 {
+		result.Imported = 0
+		return result, nil
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return nil, err
+	}
+
+	return result, nil
+}
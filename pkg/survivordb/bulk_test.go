@@ -0,0 +1,110 @@
+package survivordb
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestDecodeSurvivors checks if rows that cannot be read are reported by
+// number and the others are read
+func TestDecodeSurvivors(t *testing.T) {
+	csvFile := "id,name,gender,age,water,infected\n" +
+		"A1,Jane Doe,Female,30,2.5,false\n" +
+		"A2,John Doe,Male,old,2,maybe\n" +
+		"A3,Short\n" +
+		"A4,Joe Doe,Male,20,,\n"
+	rows, rowErrors, err := DecodeSurvivors(strings.NewReader(csvFile), FormatCSV)
+	if err != nil || len(rows) != 2 || rows[0].Row != 1 || rows[0].Survivor.Water != 2.5 || rows[1].Row != 4 {
+		t.Errorf("DecodeSurvivors() - csv: got: %+v %v", rows, err)
+	}
+	if len(rowErrors) != 2 || rowErrors[0].Row != 2 || rowErrors[0].IdNumber != "A2" || len(rowErrors[0].Fields) != 2 ||
+		rowErrors[1].Row != 3 {
+		t.Errorf("DecodeSurvivors() - csv: got: %+v", rowErrors)
+	}
+
+	for _, header := range []string{"name,robot\n", "name,age\n"} {
+		if _, _, err = DecodeSurvivors(strings.NewReader(header), FormatCSV); !errors.Is(err, ErrInvalidImport) {
+			t.Errorf("DecodeSurvivors() - %q: want: %v, got: %v", header, ErrInvalidImport, err)
+		}
+	}
+
+	ndjsonFile := `{"id":"A1","name":"Jane Doe"}` + "\n\n" +
+		`{"id":"A2","name":"John Doe","robot":true}` + "\n" +
+		`{"id":"A3","age":"old"}` + "\n"
+	rows, rowErrors, err = DecodeSurvivors(strings.NewReader(ndjsonFile), FormatNDJSON)
+	if err != nil || len(rows) != 1 || rows[0].Survivor.IdNumber != "A1" {
+		t.Errorf("DecodeSurvivors() - ndjson: got: %+v %v", rows, err)
+	}
+	if len(rowErrors) != 2 || rowErrors[0].Row != 2 || rowErrors[1].Row != 3 {
+		t.Errorf("DecodeSurvivors() - ndjson: got: %+v", rowErrors)
+	}
+
+	if _, _, err = DecodeSurvivors(strings.NewReader(""), "xml"); err != ErrUnknownFormat {
+		t.Errorf("DecodeSurvivors() - xml: want: %v, got: %v", ErrUnknownFormat, err)
+	}
+}
+
+// TestSurvivorDB_Import checks if an export imports into another database and
+// if an import with errors stores nothing
+func TestSurvivorDB_Import(t *testing.T) {
+	for _, format := range []string{FormatCSV, FormatNDJSON} {
+		os.Remove("./test.db")
+		survivordb := Open("./test.db")
+		if err := survivordb.Setup(); err != nil {
+			t.Fatalf("SurvivorDB.Setup(): Failed to setup database")
+		}
+		saveSurvivors(t, survivordb,
+			&Survivor{Name: "Jane Doe", Age: 30, Gender: "Female", IdNumber: "A1",
+				LastLocation: LastLocation{Longitude: 1.5, Latitude: -2},
+				Resources:    Resources{Water: 2, Food: "Rice, Fish", Ammunition: 5}},
+			&Survivor{Name: "John, \"Doe\"", Gender: "Male", IdNumber: "A2", Infected: true},
+		)
+		var file bytes.Buffer
+		if err := Export(survivordb, &file, format); err != nil {
+			t.Fatalf("Export() - %s: want: %v, got: %v", format, nil, err)
+		}
+		exported := file.String()
+		survivordb.DB.Close()
+
+		os.Remove("./test.db")
+		survivordb = Open("./test.db")
+		if err := survivordb.Setup(); err != nil {
+			t.Fatalf("SurvivorDB.Setup(): Failed to setup database")
+		}
+		result, err := Import(survivordb, strings.NewReader(exported), format, false)
+		if err != nil || result.Rows != 2 || result.Imported != 2 || len(result.Errors) != 0 {
+			t.Errorf("Import() - %s: got: %+v %v", format, result, err)
+		}
+		survivor := survivordb.GetSurvivor("A1")
+		if survivor == nil || survivor.Age != 30 || survivor.Longitude != 1.5 || survivor.Latitude != -2 ||
+			survivor.Food != "Rice, Fish" || survivor.Ammunition != 5 {
+			t.Errorf("Import() - %s: got: %+v", format, survivor)
+		}
+		if survivor = survivordb.GetSurvivor("A2"); survivor == nil || survivor.Name != "John, \"Doe\"" || !survivor.Infected {
+			t.Errorf("Import() - %s: got: %+v", format, survivor)
+		}
+
+		result, err = Import(survivordb, strings.NewReader(exported), format, true)
+		if err != nil || !result.DryRun || result.Imported != 0 || len(result.Errors) != 2 {
+			t.Errorf("Import() - %s: got: %+v %v", format, result, err)
+		}
+		survivordb.DB.Close()
+	}
+
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	if err := survivordb.Setup(); err != nil {
+		t.Fatalf("SurvivorDB.Setup(): Failed to setup database")
+	}
+	defer survivordb.DB.Close()
+	result, err := Import(survivordb, strings.NewReader("id,name,gender,age\nA1,Jane Doe,Female,30\nA2,John Doe,Male,old\n"), FormatCSV, false)
+	if err != nil || result.Rows != 2 || result.Imported != 0 || result.DryRun || len(result.Errors) != 1 || result.Errors[0].Row != 2 {
+		t.Errorf("Import(): got: %+v %v", result, err)
+	}
+	if survivors := survivordb.GetAllSurvivors(); len(survivors) != 0 {
+		t.Errorf("Import(): want: %v, got: %+v", 0, survivors)
+	}
+}
@@ -0,0 +1,107 @@
+package survivordb
+
+import (
+	"time"
+)
+
+// Survivor event types
+const (
+	EventSurvivorCreated  = "survivor.created"
+	EventSurvivorInfected = "survivor.infected"
+	EventLocationUpdated  = "location.updated"
+	EventResourcesUpdated = "resources.updated"
+	EventSurvivorDeleted  = "survivor.deleted"
+)
+
+// EventTypes lists every survivor event type
+var EventTypes = []string{
+	EventSurvivorCreated,
+	EventSurvivorInfected,
+	EventLocationUpdated,
+	EventResourcesUpdated,
+	EventSurvivorDeleted,
+}
+
+// resourceFields json fields of a survivor that hold resources
+var resourceFields = []string{"water", "food", "medication", "ammunition", "inventory"}
+
+// SurvivorEvent defines something that happened to a survivor
+// swagger:model
+type SurvivorEvent struct {
+	// the id of the audit event the survivor event comes from
+	//
+	// required: true
+	ID int64 `json:"eventId"`
+
+	// survivor.created, survivor.infected, location.updated, resources.updated
+	// or survivor.deleted
+	//
+	// required: true
+	Type string `json:"type"`
+
+	// the id number of the survivor
+	//
+	// required: true
+	IdNumber string `json:"id"`
+
+	// who made the change
+	//
+	// required: true
+	Actor string `json:"actor"`
+
+	// the survivor fields that changed, keyed by field name
+	//
+	// required: true
+	Changes map[string]AuditChange `json:"changes"`
+
+	// the time of the change
+	//
+	// required: true
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SurvivorEvents returns the survivor events an audit event stands for. A
+// change may stand for several events, e.g. a patch that moves a survivor
+// and changes their resources. The changes of a delete hold the fields of the
+// survivor before it, with nothing after
+func (e *AuditEvent) SurvivorEvents() []SurvivorEvent {
+	changed := func(fields ...string) bool {
+		for _, field := range fields {
+			if _, ok := e.Diff[field]; ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	types := []string{}
+	if e.Action == AuditCreate {
+		types = append(types, EventSurvivorCreated)
+	}
+	if infected, ok := e.Diff["infected"]; ok && infected.After == true {
+		types = append(types, EventSurvivorInfected)
+	}
+	if e.Action == AuditUpdateLocation || (e.Action == AuditPatch && changed("longitude", "latitude")) {
+		types = append(types, EventLocationUpdated)
+	}
+	if e.Action == AuditUpdateResources || e.Action == AuditTrade || (e.Action == AuditPatch && changed(resourceFields...)) {
+		types = append(types, EventResourcesUpdated)
+	}
+	if e.Action == AuditDelete {
+		types = append(types, EventSurvivorDeleted)
+	}
+
+	events := make([]SurvivorEvent, 0, len(types))
+	for _, eventType := range types {
+		events = append(events, SurvivorEvent{
+			ID:        e.ID,
+			Type:      eventType,
+			IdNumber:  e.IdNumber,
+			Actor:     e.Actor,
+			Changes:   e.Diff,
+			Timestamp: e.Timestamp,
+		})
+	}
+
+	return events
+}
@@ -0,0 +1,144 @@
+package survivordb
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrDuplicateReport is returned when a reporter has already reported a survivor
+var ErrDuplicateReport = errors.New("survivor already reported by this reporter")
+
+// InfectionReport is the outcome of reporting a survivor as infected
+type InfectionReport struct {
+	IdNumber  string `json:"id"`
+	Reports   int    `json:"reports"`
+	Threshold int    `json:"threshold"`
+	Infected  bool   `json:"infected"`
+}
+
+type infectionReportStmts struct {
+	insertReportStmt  *sql.Stmt
+	countReporterStmt *sql.Stmt
+	countReportsStmt  *sql.Stmt
+}
+
+const (
+	insertReportSQL  = `INSERT INTO InfectionReports (reporter_id, reported_id) VALUES(?,?);`
+	countReporterSQL = `SELECT count(*) FROM InfectionReports WHERE reporter_id = ? AND reported_id = ?;`
+	countReportsSQL  = `SELECT count(*) FROM InfectionReports WHERE reported_id = ?;`
+)
+
+// setupInfectionReports prepares the statements of the InfectionReports table
+func (s *SurvivorDB) setupInfectionReports() error {
+	var err error
+	if s.insertReportStmt, err = s.prepare(insertReportSQL); err != nil {
+		return err
+	}
+	if s.countReporterStmt, err = s.prepare(countReporterSQL); err != nil {
+		return err
+	}
+	if s.countReportsStmt, err = s.prepare(countReportsSQL); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ReportInfection records one vote by reporter that the reported survivor is
+// infected. Once the number of independent reports reaches threshold the
+// survivor is flagged as infected.
+func (s *SurvivorDB) ReportInfection(reporterIdNumber, reportedIdNumber string, threshold int) (*InfectionReport, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, idNumber := range []string{reporterIdNumber, reportedIdNumber} {
+		count, err := countTx(tx, s.countByIdNumberStmt, countByIdNumberSQL, idNumber)
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, ErrNotFound
+		}
+	}
+
+	before, err := s.getSurvivorTx(tx, reportedIdNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := countTx(tx, s.countReporterStmt, countReporterSQL, reporterIdNumber, reportedIdNumber)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrDuplicateReport
+	}
+
+	_, err = tx.Stmt(s.insertReportStmt).Exec(reporterIdNumber, reportedIdNumber)
+	if uniqueViolation(err) {
+		return nil, ErrDuplicateReport
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   insertReportSQL,
+		}).Info("Sql error")
+		return nil, err
+	}
+
+	report := &InfectionReport{
+		IdNumber:  reportedIdNumber,
+		Threshold: threshold,
+	}
+	report.Reports, err = countTx(tx, s.countReportsStmt, countReportsSQL, reportedIdNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if report.Reports >= threshold {
+		_, err = tx.Stmt(s.updateInfectedStmt).Exec(reportedIdNumber)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   updateInfectedSQL,
+			}).Info("Sql error")
+			return nil, err
+		}
+		report.Infected = true
+	}
+	if err = s.auditTx(tx, AuditReportInfection, reportedIdNumber, before); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// countTx runs a count(*) statement inside a transaction
+func countTx(tx *sql.Tx, stmt *sql.Stmt, query string, args ...interface{}) (int, error) {
+	count := 0
+	err := tx.Stmt(stmt).QueryRow(args...).Scan(&count)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   query,
+		}).Info("Sql error")
+		return -1, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,66 @@
+package survivordb
+
+import (
+	"os"
+	"testing"
+)
+
+// TestSurvivorDB_ReportInfection checks a survivor is only flagged as infected
+// once enough independent reporters have reported them
+func TestSurvivorDB_ReportInfection(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	for _, idNumber := range []string{"HD138VOP34219", "HD138VOP34220", "HD138VOP34221", "HD138VOP34222"} {
+		err = survivordb.Save(&Survivor{Name: "Jane Doe", Age: 1, Gender: "Female", IdNumber: idNumber})
+		if err != nil {
+			t.Errorf("SurvivorDB.Save() - %q: want: %v, got: %v", idNumber, nil, err)
+		}
+	}
+
+	testCases := []struct {
+		reporter     string
+		reported     string
+		wantErr      error
+		wantReports  int
+		wantInfected bool
+	}{
+		{reporter: "HD138VOP34220", reported: "HD138VOP34219", wantReports: 1},
+		{reporter: "HD138VOP34220", reported: "HD138VOP34219", wantErr: ErrDuplicateReport},
+		{reporter: "HD138VOP34221", reported: "HD138VOP34219", wantReports: 2},
+		{reporter: "UNKNOWN", reported: "HD138VOP34219", wantErr: ErrNotFound},
+		{reporter: "HD138VOP34221", reported: "UNKNOWN", wantErr: ErrNotFound},
+		{reporter: "HD138VOP34222", reported: "HD138VOP34219", wantReports: 3, wantInfected: true},
+	}
+
+	for _, tc := range testCases {
+		report, err := survivordb.ReportInfection(tc.reporter, tc.reported, 3)
+		if err != tc.wantErr {
+			t.Errorf("SurvivorDB.ReportInfection(%q, %q): want: %v, got: %v", tc.reporter, tc.reported, tc.wantErr, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if report.Reports != tc.wantReports || report.Infected != tc.wantInfected {
+			t.Errorf("SurvivorDB.ReportInfection(%q, %q): want: %v/%v, got: %v/%v", tc.reporter, tc.reported,
+				tc.wantReports, tc.wantInfected, report.Reports, report.Infected)
+		}
+	}
+
+	// a concurrent duplicate report passes the count and fails on the insert
+	_, err = survivordb.DB.Exec(insertReportSQL, "HD138VOP34220", "HD138VOP34219")
+	if !uniqueViolation(err) {
+		t.Errorf("SurvivorDB.DB.Exec(): want: unique constraint error, got: %v", err)
+	}
+
+	newSurvivor := survivordb.GetSurvivor("HD138VOP34219")
+	if newSurvivor == nil || newSurvivor.Infected != true {
+		t.Errorf("SurvivorDB.GetSurvivor() - %q: want: infected, got: %v", "HD138VOP34219", newSurvivor)
+	}
+}
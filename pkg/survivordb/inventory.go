@@ -0,0 +1,494 @@
+package survivordb
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Inventory item types
+const (
+	ItemWater      = "water"
+	ItemFood       = "food"
+	ItemMedication = "medication"
+	ItemAmmunition = "ammunition"
+)
+
+// InventoryItem defines a quantity of one item a survivor has
+// swagger:model
+type InventoryItem struct {
+	// the type of item: water, food, medication or ammunition
+	//
+	// required: true
+	Type string `json:"type"`
+
+	// the name of the item
+	//
+	// required: true
+	// max length: 255
+	Name string `json:"name"`
+
+	// how much of the item the survivor has
+	//
+	// required: true
+	Quantity float64 `json:"quantity"`
+
+	// the unit the quantity is measured in
+	//
+	// required: false
+	Unit string `json:"unit,omitempty"`
+}
+
+// String formats the item as it appears in the legacy comma separated lists
+func (i InventoryItem) String() string {
+	if i.Quantity == 1 && i.Unit == "" {
+		return i.Name
+	}
+	quantity := strconv.FormatFloat(i.Quantity, 'f', -1, 64)
+	if i.Unit == "" {
+		return quantity + " " + i.Name
+	}
+
+	return quantity + " " + i.Unit + " " + i.Name
+}
+
+// itemUnits units recognised when parsing legacy item lists
+var itemUnits = map[string]bool{
+	"g": true, "kg": true, "lb": true, "oz": true,
+	"ml": true, "l": true,
+	"can": true, "cans": true, "box": true, "boxes": true,
+	"pack": true, "packs": true, "bottle": true, "bottles": true,
+	"tablet": true, "tablets": true, "dose": true, "doses": true,
+}
+
+// waterUnits holds the litres in one of each unit water may be measured in,
+// matched ignoring case. Water without a unit is in litres
+var waterUnits = map[string]float64{"": 1, "l": 1, "ml": 0.001}
+
+// litres returns the quantity of a water item in litres, and false when its
+// unit is not one of waterUnits
+func (i InventoryItem) litres() (float64, bool) {
+	perUnit, ok := waterUnits[strings.ToLower(i.Unit)]
+
+	return i.Quantity * perUnit, ok
+}
+
+var quantityRegexp = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s+(.+)$`)
+
+// parseItem parses a legacy item such as "Fish", "3 Fish" or "2 kg Rice"
+func parseItem(itemType, item string) InventoryItem {
+	parsed := InventoryItem{Type: itemType, Name: item, Quantity: 1}
+	match := quantityRegexp.FindStringSubmatch(item)
+	if match == nil {
+		return parsed
+	}
+	parsed.Quantity, _ = strconv.ParseFloat(match[1], 64)
+	parsed.Name = match[2]
+	if fields := strings.Fields(match[2]); len(fields) > 1 && itemUnits[strings.ToLower(fields[0])] {
+		parsed.Unit = fields[0]
+		parsed.Name = strings.Join(fields[1:], " ")
+	}
+
+	return parsed
+}
+
+// Items returns the resources as inventory items. The structured inventory
+// is used when present, otherwise the legacy fields are parsed
+func (r *Resources) Items() []InventoryItem {
+	if len(r.Inventory) > 0 {
+		return mergeItems(nil, r.Inventory...)
+	}
+
+	items := []InventoryItem{}
+	if r.Water > 0 {
+		items = mergeItems(items, InventoryItem{Type: ItemWater, Name: ItemWater, Quantity: r.Water})
+	}
+	for _, item := range splitItems(r.Food) {
+		items = mergeItems(items, parseItem(ItemFood, item))
+	}
+	for _, item := range splitItems(r.Medication) {
+		items = mergeItems(items, parseItem(ItemMedication, item))
+	}
+	if r.Ammunition > 0 {
+		items = mergeItems(items, InventoryItem{Type: ItemAmmunition, Name: ItemAmmunition, Quantity: float64(r.Ammunition), Unit: "rounds"})
+	}
+
+	return items
+}
+
+// SetItems replaces the inventory and rewrites the legacy fields from it. The
+// water field holds the litres of the water items, leaving out any in units
+// that are not waterUnits
+func (r *Resources) SetItems(items []InventoryItem) {
+	r.Inventory = mergeItems(nil, items...)
+	r.Water = 0
+	r.Ammunition = 0
+	food := []string{}
+	medication := []string{}
+	for _, item := range r.Inventory {
+		switch item.Type {
+		case ItemWater:
+			if litres, ok := item.litres(); ok {
+				r.Water += litres
+			}
+		case ItemAmmunition:
+			r.Ammunition += int(item.Quantity)
+		case ItemFood:
+			food = append(food, item.String())
+		case ItemMedication:
+			medication = append(medication, item.String())
+		}
+	}
+	r.Food = joinItems(food)
+	r.Medication = joinItems(medication)
+}
+
+// mergeItems adds items to an inventory, summing quantities of the same item
+// and dropping items that have run out
+func mergeItems(inventory []InventoryItem, items ...InventoryItem) []InventoryItem {
+	merged := make([]InventoryItem, 0, len(inventory)+len(items))
+	merged = append(merged, inventory...)
+	for _, item := range items {
+		found := false
+		for i := range merged {
+			if merged[i].Type == item.Type && merged[i].Unit == item.Unit && strings.EqualFold(merged[i].Name, item.Name) {
+				merged[i].Quantity += item.Quantity
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, item)
+		}
+	}
+
+	out := merged[:0]
+	for _, item := range merged {
+		if item.Quantity > 0 {
+			out = append(out, item)
+		}
+	}
+
+	return out
+}
+
+// maxInventoryLookup largest list of survivors whose inventory is read by id
+const maxInventoryLookup = 500
+
+type inventoryStmts struct {
+	insertItemStmt     *sql.Stmt
+	deleteItemsStmt    *sql.Stmt
+	selectItemsStmt    *sql.Stmt
+	selectAllItemsStmt *sql.Stmt
+	totalItemsStmt     *sql.Stmt
+}
+
+const (
+	insertItemSQL      = `INSERT INTO Inventory (survivor_id, item_type, item_name, quantity, unit) VALUES(?,?,?,?,?);`
+	deleteItemsSQL     = `DELETE FROM Inventory WHERE survivor_id = ?;`
+	selectItemsSQL     = `SELECT item_type, item_name, quantity, unit FROM Inventory WHERE survivor_id = ? ORDER BY id;`
+	selectAllItemsSQL  = `SELECT survivor_id, item_type, item_name, quantity, unit FROM Inventory ORDER BY id;`
+	selectSomeItemsSQL = `SELECT survivor_id, item_type, item_name, quantity, unit FROM Inventory WHERE survivor_id IN (%s) ORDER BY id;`
+	totalItemsSQL      = `SELECT item_type, min(item_name), sum(quantity), unit FROM Inventory GROUP BY item_type, lower(item_name), unit ORDER BY item_type, lower(item_name);`
+	selectLegacySQL    = `SELECT id_number, water, food, medication, ammunition FROM Survivors s WHERE NOT EXISTS (SELECT 1 FROM Inventory i WHERE i.survivor_id = s.id_number) ORDER BY id;`
+)
+
+// setupInventory prepares the statements of the Inventory table
+func (s *SurvivorDB) setupInventory() error {
+	var err error
+	if s.insertItemStmt, err = s.prepare(insertItemSQL); err != nil {
+		return err
+	}
+	if s.deleteItemsStmt, err = s.prepare(deleteItemsSQL); err != nil {
+		return err
+	}
+	if s.selectItemsStmt, err = s.prepare(selectItemsSQL); err != nil {
+		return err
+	}
+	if s.selectAllItemsStmt, err = s.prepare(selectAllItemsSQL); err != nil {
+		return err
+	}
+	if s.totalItemsStmt, err = s.prepare(totalItemsSQL); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// backfillInventory parses the legacy resource columns of the survivors that
+// have no rows in the Inventory table, those registered before it existed,
+// into their inventory
+func (s *SurvivorDB) backfillInventory() error {
+	rows, err := s.DB.Query(selectLegacySQL)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectLegacySQL,
+		}).Info("Sql error")
+		return err
+	}
+	type legacy struct {
+		idNumber  string
+		resources Resources
+	}
+	survivors := []legacy{}
+	for rows.Next() {
+		survivor := legacy{}
+		err = rows.Scan(&survivor.idNumber, &survivor.resources.Water, &survivor.resources.Food,
+			&survivor.resources.Medication, &survivor.resources.Ammunition)
+		if err != nil {
+			rows.Close()
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectLegacySQL,
+			}).Info("Sql error")
+			return err
+		}
+		survivors = append(survivors, survivor)
+	}
+	rows.Close()
+	if len(survivors) == 0 {
+		return nil
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+	defer tx.Rollback()
+
+	filled := 0
+	for _, survivor := range survivors {
+		items := survivor.resources.Items()
+		if len(items) == 0 {
+			continue
+		}
+		if err = s.saveInventoryTx(tx, survivor.idNumber, items); err != nil {
+			return err
+		}
+		filled++
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+	if filled > 0 {
+		logrus.WithFields(logrus.Fields{
+			"survivors": filled,
+		}).Info("Filled in inventories from the legacy resources")
+	}
+
+	return nil
+}
+
+// saveInventoryTx replaces the inventory of a survivor inside a transaction
+func (s *SurvivorDB) saveInventoryTx(tx *sql.Tx, idNumber string, items []InventoryItem) error {
+	_, err := tx.Stmt(s.deleteItemsStmt).Exec(idNumber)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   deleteItemsSQL,
+		}).Info("Sql error")
+		return err
+	}
+
+	insertItemStmt := tx.Stmt(s.insertItemStmt)
+	for _, item := range items {
+		_, err = insertItemStmt.Exec(idNumber, item.Type, item.Name, item.Quantity, item.Unit)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   insertItemSQL,
+			}).Info("Sql error")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateInventory replaces the inventory of a survivor and keeps the legacy
+// resource columns in the Survivors table in step
+func (s *SurvivorDB) UpdateInventory(idNumber string, items []InventoryItem) error {
+	resources := Resources{}
+	resources.SetItems(items)
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := s.getSurvivorTx(tx, idNumber)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Stmt(s.updateResourceStmt).Exec(
+		resources.Water,
+		resources.Food,
+		resources.Medication,
+		resources.Ammunition,
+		idNumber,
+	)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   updateResourceSQL,
+		}).Info("Sql error")
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+
+	if err = s.saveInventoryTx(tx, idNumber, resources.Inventory); err != nil {
+		return err
+	}
+	if err = s.auditTx(tx, AuditUpdateResources, idNumber, before); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+
+	return nil
+}
+
+// GetInventory selects the inventory of a survivor
+func (s *SurvivorDB) GetInventory(idNumber string) []InventoryItem {
+	rows, err := s.selectItemsStmt.Query(idNumber)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectItemsSQL,
+		}).Info("Sql error")
+		return nil
+	}
+	defer rows.Close()
+
+	items := []InventoryItem{}
+	for rows.Next() {
+		item := InventoryItem{}
+		err = rows.Scan(&item.Type, &item.Name, &item.Quantity, &item.Unit)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectItemsSQL,
+			}).Info("Sql error")
+			return nil
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectItemsSQL,
+		}).Info("Sql error")
+		return nil
+	}
+
+	return items
+}
+
+// attachInventory fills in the inventory of each survivor with a single
+// query. Small lists only read the rows of the survivors given
+func (s *SurvivorDB) attachInventory(survivors []Survivor) []Survivor {
+	if len(survivors) == 0 {
+		return survivors
+	}
+
+	var rows *sql.Rows
+	var err error
+	if len(survivors) <= maxInventoryLookup {
+		args := make([]interface{}, len(survivors))
+		for i := range survivors {
+			args[i] = survivors[i].IdNumber
+		}
+		rows, err = s.DB.Query(fmt.Sprintf(selectSomeItemsSQL, strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")), args...)
+	} else {
+		rows, err = s.selectAllItemsStmt.Query()
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectAllItemsSQL,
+		}).Info("Sql error")
+		return survivors
+	}
+	defer rows.Close()
+
+	inventories := map[string][]InventoryItem{}
+	for rows.Next() {
+		idNumber := ""
+		item := InventoryItem{}
+		err = rows.Scan(&idNumber, &item.Type, &item.Name, &item.Quantity, &item.Unit)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectAllItemsSQL,
+			}).Info("Sql error")
+			return survivors
+		}
+		inventories[idNumber] = append(inventories[idNumber], item)
+	}
+
+	for i := range survivors {
+		survivors[i].Inventory = inventories[survivors[i].IdNumber]
+	}
+
+	return survivors
+}
+
+// InventoryTotals sums the quantity of each item across all survivors
+func (s *SurvivorDB) InventoryTotals() []InventoryItem {
+	rows, err := s.totalItemsStmt.Query()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   totalItemsSQL,
+		}).Info("Sql error")
+		return nil
+	}
+	defer rows.Close()
+
+	totals := []InventoryItem{}
+	for rows.Next() {
+		item := InventoryItem{}
+		err = rows.Scan(&item.Type, &item.Name, &item.Quantity, &item.Unit)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   totalItemsSQL,
+			}).Info("Sql error")
+			return nil
+		}
+		totals = append(totals, item)
+	}
+	if err = rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   totalItemsSQL,
+		}).Info("Sql error")
+		return nil
+	}
+
+	return totals
+}
@@ -0,0 +1,164 @@
+package survivordb
+
+import (
+	"os"
+	"testing"
+)
+
+// TestResources_Items checks if legacy resources are parsed into inventory items
+func TestResources_Items(t *testing.T) {
+	resources := &Resources{
+		Water:      2000,
+		Food:       "Beef, 2 kg Rice, 3 Fish, fish",
+		Medication: "Antibiotics, 10 tablets Aspirin",
+		Ammunition: 20,
+	}
+
+	want := []InventoryItem{
+		{Type: ItemWater, Name: ItemWater, Quantity: 2000},
+		{Type: ItemFood, Name: "Beef", Quantity: 1},
+		{Type: ItemFood, Name: "Rice", Quantity: 2, Unit: "kg"},
+		{Type: ItemFood, Name: "Fish", Quantity: 4},
+		{Type: ItemMedication, Name: "Antibiotics", Quantity: 1},
+		{Type: ItemMedication, Name: "Aspirin", Quantity: 10, Unit: "tablets"},
+		{Type: ItemAmmunition, Name: ItemAmmunition, Quantity: 20, Unit: "rounds"},
+	}
+	got := resources.Items()
+	if len(got) != len(want) {
+		t.Errorf("Resources.Items(): want: %v, got: %v", want, got)
+		return
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Resources.Items()[%d]: want: %v, got: %v", i, want[i], got[i])
+		}
+	}
+
+	resources.SetItems(got)
+	if resources.Food != "Beef, 2 kg Rice, 4 Fish" || resources.Medication != "Antibiotics, 10 tablets Aspirin" {
+		t.Errorf("Resources.SetItems(): got: %q, %q", resources.Food, resources.Medication)
+	}
+	resources.SetItems([]InventoryItem{
+		{Type: ItemWater, Name: ItemWater, Quantity: 2, Unit: "L"},
+		{Type: ItemWater, Name: "spring water", Quantity: 500, Unit: "ml"},
+	})
+	if resources.Water != 2.5 {
+		t.Errorf("Resources.SetItems(): want: %v, got: %v", 2.5, resources.Water)
+	}
+	resources.SetItems([]InventoryItem{{Type: ItemWater, Name: ItemWater, Quantity: 500, Unit: "ml"}})
+	given, err := resources.take(&TradeOffer{Water: 0.25})
+	if err != nil || resources.Water != 0.25 || len(given) != 1 || given[0].Quantity != 250 {
+		t.Errorf("Resources.take(): want: %v, got: %v %v %v", 0.25, resources.Water, given, err)
+	}
+}
+
+// TestSurvivorDB_UpdateInventory checks if saving and totalling inventory works
+func TestSurvivorDB_UpdateInventory(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	survivors := []*Survivor{
+		{
+			Name:     "Jane Doe",
+			Age:      1,
+			Gender:   "Female",
+			IdNumber: "HD138VOP34219",
+			Resources: Resources{
+				Water: 10,
+				Food:  "2 kg Rice, Fish",
+			},
+		},
+		{
+			Name:     "John Doe",
+			Age:      1,
+			Gender:   "Male",
+			IdNumber: "HD138VOP34220",
+			Resources: Resources{
+				Water: 5,
+				Food:  "1 kg Rice",
+			},
+		},
+	}
+	for _, survivor := range survivors {
+		err = survivordb.Save(survivor)
+		if err != nil {
+			t.Errorf("SurvivorDB.Save() - %q: want: %v, got: %v", survivor.Name, nil, err)
+		}
+	}
+
+	err = survivordb.UpdateInventory("HD138VOP34220", []InventoryItem{
+		{Type: ItemWater, Name: ItemWater, Quantity: 6},
+		{Type: ItemFood, Name: "rice", Quantity: 3, Unit: "kg"},
+		{Type: ItemMedication, Name: "Aspirin", Quantity: 2},
+	})
+	if err != nil {
+		t.Errorf("SurvivorDB.UpdateInventory(): want: %v, got: %v", nil, err)
+	}
+	err = survivordb.UpdateInventory("UNKNOWN", nil)
+	if err != ErrNotFound {
+		t.Errorf("SurvivorDB.UpdateInventory(): want: %v, got: %v", ErrNotFound, err)
+	}
+
+	john := survivordb.GetSurvivor("HD138VOP34220")
+	if john == nil || john.Water != 6 || john.Food != "3 kg rice" || john.Medication != "2 Aspirin" || len(john.Inventory) != 3 {
+		t.Errorf("SurvivorDB.GetSurvivor() - %q: got: %v", "HD138VOP34220", john)
+	}
+
+	totals := map[string]float64{}
+	for _, item := range survivordb.InventoryTotals() {
+		totals[item.Type+"/"+item.Unit] += item.Quantity
+	}
+	want := map[string]float64{"water/": 16, "food/kg": 5, "food/": 1, "medication/": 2}
+	for key, quantity := range want {
+		if totals[key] != quantity {
+			t.Errorf("SurvivorDB.InventoryTotals() - %q: want: %v, got: %v", key, quantity, totals[key])
+		}
+	}
+}
+
+// TestSurvivorDB_BackfillInventory checks if survivors registered before the
+// Inventory table get their legacy resources as inventory on setup
+func TestSurvivorDB_BackfillInventory(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	_, err = survivordb.DB.Exec(createSQL, "Jane Doe", 1, "Female", "HD138VOP34219", 0, 0, 10, "2 kg Rice, Fish", "Aspirin", 5, false)
+	if err != nil {
+		t.Errorf("INSERT INTO Survivors: want: %v, got: %v", nil, err)
+		return
+	}
+	if err = survivordb.Setup(); err != nil {
+		t.Errorf("SurvivorDB.Setup(): want: %v, got: %v", nil, err)
+		return
+	}
+
+	jane := survivordb.GetSurvivor("HD138VOP34219")
+	if jane == nil || len(jane.Inventory) != 5 || jane.Food != "2 kg Rice, Fish" {
+		t.Errorf("SurvivorDB.GetSurvivor() - %q: got: %+v", "HD138VOP34219", jane)
+	}
+	totals := map[string]float64{}
+	for _, item := range survivordb.InventoryTotals() {
+		totals[item.Type] += item.Quantity
+	}
+	want := map[string]float64{ItemWater: 10, ItemFood: 3, ItemMedication: 1, ItemAmmunition: 5}
+	for key, quantity := range want {
+		if totals[key] != quantity {
+			t.Errorf("SurvivorDB.InventoryTotals() - %q: want: %v, got: %v", key, quantity, totals[key])
+		}
+	}
+
+	// a second setup leaves the filled in inventory alone
+	if err = survivordb.Setup(); err != nil || len(survivordb.GetInventory("HD138VOP34219")) != 5 {
+		t.Errorf("SurvivorDB.Setup(): want: %v, got: %v %v", 5, survivordb.GetInventory("HD138VOP34219"), err)
+	}
+}
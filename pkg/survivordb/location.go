@@ -0,0 +1,112 @@
+package survivordb
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sqliteTimeFormat is the layout SQLite uses for CURRENT_TIMESTAMP
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+// minTimestamp and maxTimestamp bound the time ranges that are left open.
+// They are valid timestamps, which PostgreSQL requires
+const (
+	minTimestamp = "0001-01-01 00:00:00"
+	maxTimestamp = "9999-12-31 23:59:59"
+)
+
+// LocationRecord defines a location a survivor reported at a point in time
+// swagger:model
+type LocationRecord struct {
+	LastLocation
+
+	// the time the location was recorded
+	//
+	// required: true
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type locationHistoryStmts struct {
+	insertLocationStmt  *sql.Stmt
+	selectLocationsStmt *sql.Stmt
+}
+
+const (
+	insertLocationSQL  = `INSERT INTO LocationHistory (survivor_id, longitude, latitude) VALUES(?,?,?);`
+	selectLocationsSQL = `SELECT longitude, latitude, recorded_ts FROM LocationHistory WHERE survivor_id = ? AND recorded_ts >= ? AND recorded_ts <= ? ORDER BY recorded_ts, id;`
+)
+
+// setupLocationHistory prepares the statements of the LocationHistory table
+func (s *SurvivorDB) setupLocationHistory() error {
+	var err error
+	if s.insertLocationStmt, err = s.prepare(insertLocationSQL); err != nil {
+		return err
+	}
+	if s.selectLocationsStmt, err = s.prepare(selectLocationsSQL); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// insertLocationTx appends a location to the history of a survivor inside a transaction
+func (s *SurvivorDB) insertLocationTx(tx *sql.Tx, idNumber string, longitude, latitude float64) error {
+	_, err := tx.Stmt(s.insertLocationStmt).Exec(idNumber, longitude, latitude)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   insertLocationSQL,
+		}).Info("Sql error")
+		return err
+	}
+
+	return nil
+}
+
+// GetLocationHistory selects the time ordered locations of a survivor recorded
+// between from and to. A zero from or to leaves that end of the range open
+func (s *SurvivorDB) GetLocationHistory(idNumber string, from, to time.Time) []LocationRecord {
+	fromParam := minTimestamp
+	if !from.IsZero() {
+		fromParam = from.UTC().Format(sqliteTimeFormat)
+	}
+	toParam := maxTimestamp
+	if !to.IsZero() {
+		toParam = to.UTC().Format(sqliteTimeFormat)
+	}
+
+	rows, err := s.selectLocationsStmt.Query(idNumber, fromParam, toParam)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectLocationsSQL,
+		}).Info("Sql error")
+		return nil
+	}
+	defer rows.Close()
+
+	locations := []LocationRecord{}
+	for rows.Next() {
+		location := LocationRecord{}
+		err = rows.Scan(&location.Longitude, &location.Latitude, &location.Timestamp)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectLocationsSQL,
+			}).Info("Sql error")
+			return nil
+		}
+		locations = append(locations, location)
+	}
+	if err = rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectLocationsSQL,
+		}).Info("Sql error")
+		return nil
+	}
+
+	return locations
+}
@@ -0,0 +1,60 @@
+package survivordb
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// TestSurvivorDB_GetLocationHistory checks if location updates are kept in order
+func TestSurvivorDB_GetLocationHistory(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	survivor := &Survivor{
+		Name:     "Jane Doe",
+		Age:      1,
+		Gender:   "Female",
+		IdNumber: "HD138VOP34219",
+	}
+	err = survivordb.Save(survivor)
+	if err != nil {
+		t.Errorf("SurvivorDB.Save() - %q: want: %v, got: %v", survivor.Name, nil, err)
+	}
+
+	for i := 1; i <= 2; i++ {
+		_, err = survivordb.UpdateLocation(survivor.IdNumber, float64(i), float64(i*2))
+		if err != nil {
+			t.Errorf("SurvivorDB.UpdateLocation() - %q: want: %v, got: %v", survivor.Name, nil, err)
+		}
+	}
+	_, err = survivordb.UpdateLocation("UNKNOWN", 1, 2)
+	if err != ErrNotFound {
+		t.Errorf("SurvivorDB.UpdateLocation() - %q: want: %v, got: %v", "UNKNOWN", ErrNotFound, err)
+	}
+
+	locations := survivordb.GetLocationHistory(survivor.IdNumber, time.Time{}, time.Time{})
+	if len(locations) != 3 {
+		t.Errorf("SurvivorDB.GetLocationHistory(): want: %v, got: %v", 3, len(locations))
+		return
+	}
+	for i, location := range locations {
+		if location.Longitude != float64(i) || location.Latitude != float64(i*2) {
+			t.Errorf("SurvivorDB.GetLocationHistory()[%d]: got: %v", i, location)
+		}
+	}
+
+	locations = survivordb.GetLocationHistory(survivor.IdNumber, time.Now().Add(time.Hour), time.Time{})
+	if locations == nil || len(locations) != 0 {
+		t.Errorf("SurvivorDB.GetLocationHistory(): want: %v, got: %v", 0, locations)
+	}
+	locations = survivordb.GetLocationHistory(survivor.IdNumber, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if len(locations) != 3 {
+		t.Errorf("SurvivorDB.GetLocationHistory(): want: %v, got: %v", 3, len(locations))
+	}
+}
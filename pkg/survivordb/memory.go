@@ -0,0 +1,1599 @@
+package survivordb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// errDuplicatePrefix is returned when an API key prefix is already stored
+var errDuplicatePrefix = errors.New("api key prefix already in use")
+
+// MemoryStore is a SurvivorStore that keeps everything in memory, for tests
+// and for running without a database. It behaves like SurvivorDB, including
+// the second precision of its timestamps
+type MemoryStore struct {
+	*memoryData
+
+	// actor is recorded in the audit events of changes, see WithActor
+	actor Actor
+}
+
+// memorySurvivor holds a survivor along with the row id that orders them
+type memorySurvivor struct {
+	id       int64
+	survivor Survivor
+}
+
+// memoryLocation holds a location in the history of a survivor
+type memoryLocation struct {
+	id       int64
+	idNumber string
+	LocationRecord
+}
+
+// memoryReport holds an infection report
+type memoryReport struct {
+	reporter string
+	reported string
+}
+
+// memoryRobot holds a robot along with the row id that orders them
+type memoryRobot struct {
+	id    int64
+	robot Robot
+}
+
+// memoryZoneEvent holds a zone event
+type memoryZoneEvent struct {
+	id int64
+	ZoneEvent
+}
+
+// memorySnapshot holds a stats snapshot
+type memorySnapshot struct {
+	id int64
+	StatsSnapshot
+}
+
+// memoryData is shared by a MemoryStore and the copies made by WithActor
+type memoryData struct {
+	mu sync.Mutex
+
+	// sequences holds the last id handed out per table
+	sequences map[string]int64
+
+	survivors  map[string]*memorySurvivor
+	locations  []memoryLocation
+	reports    []memoryReport
+	audit      []AuditEvent
+	snapshots  []memorySnapshot
+	zones      map[int64]*Zone
+	occupants  map[int64]map[string]time.Time
+	zoneEvents []memoryZoneEvent
+	webhooks   map[int64]*Webhook
+	deliveries []*WebhookDelivery
+	apiKeys    []*APIKey
+	robots     map[string]*memoryRobot
+
+	// webhookCursor is the id of the last audit event turned into deliveries
+	webhookCursor int64
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		memoryData: &memoryData{
+			sequences: map[string]int64{},
+			survivors: map[string]*memorySurvivor{},
+			zones:     map[int64]*Zone{},
+			occupants: map[int64]map[string]time.Time{},
+			webhooks:  map[int64]*Webhook{},
+			robots:    map[string]*memoryRobot{},
+		},
+	}
+}
+
+// memoryNow returns the current time with the precision of CURRENT_TIMESTAMP
+func memoryNow() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// nextID hands out the next id of a table
+func (m *memoryData) nextID(table string) int64 {
+	m.sequences[table]++
+
+	return m.sequences[table]
+}
+
+// WithActor returns a copy of the store that records actor in the audit
+// events of the changes it makes
+func (m *MemoryStore) WithActor(actor Actor) SurvivorStore {
+	return &MemoryStore{memoryData: m.memoryData, actor: actor}
+}
+
+// recordAudit records a change to a survivor. before is the survivor as it
+// was, or nil when it was created
+func (m *MemoryStore) recordAudit(action, idNumber string, before *Survivor) error {
+	var after *Survivor
+	if stored, ok := m.survivors[idNumber]; ok {
+		after = &stored.survivor
+	}
+	diff, err := diffSurvivors(before, after)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Error computing audit diff")
+		return err
+	}
+
+	actor := m.actor.Name
+	if actor == "" {
+		actor = systemActor
+	}
+	m.audit = append(m.audit, AuditEvent{
+		ID:         m.nextID("AuditEvents"),
+		IdNumber:   idNumber,
+		Action:     action,
+		Actor:      actor,
+		RemoteAddr: m.actor.RemoteAddr,
+		Diff:       diff,
+		Timestamp:  memoryNow(),
+	})
+
+	return nil
+}
+
+// insertLocation appends a location to the history of a survivor
+func (m *memoryData) insertLocation(idNumber string, longitude, latitude float64) {
+	m.locations = append(m.locations, memoryLocation{
+		id:       m.nextID("LocationHistory"),
+		idNumber: idNumber,
+		LocationRecord: LocationRecord{
+			LastLocation: LastLocation{Longitude: longitude, Latitude: latitude},
+			Timestamp:    memoryNow(),
+		},
+	})
+}
+
+// Save stores a survivor along with their first location, entering them into
+// the zones they are in. It returns ErrDuplicateIdNumber when the id number is already registered
+func (m *MemoryStore) Save(survivor *Survivor) error {
+	items := survivor.Items()
+	survivor.SetItems(items)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.save(survivor)
+}
+
+// save stores a survivor like Save with the lock held
+func (m *MemoryStore) save(survivor *Survivor) error {
+	if _, ok := m.survivors[survivor.IdNumber]; ok {
+		return ErrDuplicateIdNumber
+	}
+	stored := &memorySurvivor{id: m.nextID("Survivors"), survivor: *survivor.clone()}
+	stored.survivor.LastUpdateTime = memoryNow()
+	m.survivors[survivor.IdNumber] = stored
+	m.insertLocation(survivor.IdNumber, survivor.Longitude, survivor.Latitude)
+	m.occupy(m.zoneList(), survivor.IdNumber, survivor.Longitude, survivor.Latitude)
+
+	return m.recordAudit(AuditCreate, survivor.IdNumber, nil)
+}
+
+// ImportSurvivors stores the survivors of rows at once. Every row is checked
+// and those that are invalid or whose id number is registered are reported;
+// nothing is stored when any row is, nor on a dry run
+func (m *MemoryStore) ImportSurvivors(rows []ImportRow, dryRun bool) (*ImportResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := &ImportResult{Rows: len(rows), DryRun: dryRun, Errors: []ImportError{}}
+	seen := map[string]bool{}
+	for i := range rows {
+		err := checkImportRow(&rows[i], seen)
+		if _, ok := m.survivors[rows[i].Survivor.IdNumber]; ok && err == nil {
+			err = ErrDuplicateIdNumber
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, importError(rows[i].Row, rows[i].Survivor.IdNumber, err))
+		}
+	}
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+	result.Imported = len(rows)
+	if dryRun {
+		return result, nil
+	}
+
+	for i := range rows {
+		if err := m.save(&rows[i].Survivor); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// UpdateLocation updates the location of a survivor, appends it to their
+// location history and returns the zone events of the move
+func (m *MemoryStore) UpdateLocation(idNumber string, longitude, latitude float64) ([]ZoneEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.survivors[idNumber]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	before := stored.survivor.clone()
+	stored.survivor.Longitude = longitude
+	stored.survivor.Latitude = latitude
+	stored.survivor.LastUpdateTime = memoryNow()
+	m.insertLocation(idNumber, longitude, latitude)
+	events := m.occupy(m.zoneList(), idNumber, longitude, latitude)
+	if err := m.recordAudit(AuditUpdateLocation, idNumber, before); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// UpdateResource updates the resources of a survivor
+func (m *MemoryStore) UpdateResource(idNumber string, water float64, food, medication string, ammunition int) error {
+	resources := &Resources{
+		Water:      water,
+		Food:       food,
+		Medication: medication,
+		Ammunition: ammunition,
+	}
+
+	return m.UpdateInventory(idNumber, resources.Items())
+}
+
+// UpdateInventory replaces the inventory of a survivor and keeps the legacy
+// resource fields in step
+func (m *MemoryStore) UpdateInventory(idNumber string, items []InventoryItem) error {
+	resources := Resources{}
+	resources.SetItems(items)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.survivors[idNumber]
+	if !ok {
+		return ErrNotFound
+	}
+	before := stored.survivor.clone()
+	stored.survivor.Resources = resources
+	stored.survivor.LastUpdateTime = memoryNow()
+
+	return m.recordAudit(AuditUpdateResources, idNumber, before)
+}
+
+// UpdateInfected flags a survivor as infected
+func (m *MemoryStore) UpdateInfected(idNumber string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.survivors[idNumber]
+	if !ok {
+		return ErrNotFound
+	}
+	before := stored.survivor.clone()
+	stored.survivor.Infected = true
+	stored.survivor.LastUpdateTime = memoryNow()
+
+	return m.recordAudit(AuditUpdateInfected, idNumber, before)
+}
+
+// ReportInfection records one vote by reporter that the reported survivor is
+// infected. Once the number of independent reports reaches threshold the
+// survivor is flagged as infected.
+func (m *MemoryStore) ReportInfection(reporterIdNumber, reportedIdNumber string, threshold int) (*InfectionReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.survivors[reporterIdNumber]; !ok {
+		return nil, ErrNotFound
+	}
+	reported, ok := m.survivors[reportedIdNumber]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	report := &InfectionReport{
+		IdNumber:  reportedIdNumber,
+		Threshold: threshold,
+	}
+	for _, r := range m.reports {
+		if r.reported != reportedIdNumber {
+			continue
+		}
+		if r.reporter == reporterIdNumber {
+			return nil, ErrDuplicateReport
+		}
+		report.Reports++
+	}
+
+	before := reported.survivor.clone()
+	m.reports = append(m.reports, memoryReport{reporter: reporterIdNumber, reported: reportedIdNumber})
+	report.Reports++
+	if report.Reports >= threshold {
+		reported.survivor.Infected = true
+		reported.survivor.LastUpdateTime = memoryNow()
+		report.Infected = true
+	}
+	if err := m.recordAudit(AuditReportInfection, reportedIdNumber, before); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// Trade atomically moves the offered resources between two survivors
+func (m *MemoryStore) Trade(trade *Trade) ([]Survivor, error) {
+	if err := trade.validate(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fromStored, ok := m.survivors[trade.From.IdNumber]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	toStored, ok := m.survivors[trade.To.IdNumber]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	from, to := fromStored.survivor.clone(), toStored.survivor.clone()
+	if err := trade.exchange(from, to); err != nil {
+		return nil, err
+	}
+
+	fromBefore, toBefore := fromStored.survivor.clone(), toStored.survivor.clone()
+	now := memoryNow()
+	fromStored.survivor.Resources = from.clone().Resources
+	fromStored.survivor.LastUpdateTime = now
+	toStored.survivor.Resources = to.clone().Resources
+	toStored.survivor.LastUpdateTime = now
+	if err := m.recordAudit(AuditTrade, from.IdNumber, fromBefore); err != nil {
+		return nil, err
+	}
+	if err := m.recordAudit(AuditTrade, to.IdNumber, toBefore); err != nil {
+		return nil, err
+	}
+
+	return []Survivor{*from, *to}, nil
+}
+
+// PatchSurvivor applies a partial update to a survivor. Changing the id
+// number also moves everything that belongs to the survivor, and changing the
+// location checks it against the zones
+func (m *MemoryStore) PatchSurvivor(idNumber string, patch *SurvivorPatch) (*Survivor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.survivors[idNumber]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	survivor := stored.survivor.clone()
+	before := survivor.clone()
+	patch.Apply(survivor)
+
+	if survivor.IdNumber != idNumber {
+		if _, taken := m.survivors[survivor.IdNumber]; taken {
+			return nil, ErrDuplicateIdNumber
+		}
+		delete(m.survivors, idNumber)
+		m.survivors[survivor.IdNumber] = stored
+		m.renameSurvivor(idNumber, survivor.IdNumber)
+	}
+	survivor.LastUpdateTime = memoryNow()
+	stored.survivor = *survivor
+	if patch.LocationChanged() {
+		m.insertLocation(survivor.IdNumber, survivor.Longitude, survivor.Latitude)
+		m.occupy(m.zoneList(), survivor.IdNumber, survivor.Longitude, survivor.Latitude)
+	}
+	if err := m.recordAudit(AuditPatch, survivor.IdNumber, before); err != nil {
+		return nil, err
+	}
+
+	return m.getSurvivor(survivor.IdNumber), nil
+}
+
+// renameSurvivor moves everything that belongs to a survivor to a new id number
+func (m *memoryData) renameSurvivor(idNumber, newIdNumber string) {
+	for i := range m.locations {
+		if m.locations[i].idNumber == idNumber {
+			m.locations[i].idNumber = newIdNumber
+		}
+	}
+	for i := range m.reports {
+		if m.reports[i].reporter == idNumber {
+			m.reports[i].reporter = newIdNumber
+		}
+		if m.reports[i].reported == idNumber {
+			m.reports[i].reported = newIdNumber
+		}
+	}
+	for _, occupants := range m.occupants {
+		if entered, ok := occupants[idNumber]; ok {
+			delete(occupants, idNumber)
+			occupants[newIdNumber] = entered
+		}
+	}
+	for i := range m.zoneEvents {
+		if m.zoneEvents[i].IdNumber == idNumber {
+			m.zoneEvents[i].IdNumber = newIdNumber
+		}
+	}
+}
+
+// DeleteSurvivor deletes a survivor and everything that belongs to them
+func (m *MemoryStore) DeleteSurvivor(idNumber string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.survivors[idNumber]
+	if !ok {
+		return ErrNotFound
+	}
+	before := stored.survivor.clone()
+	delete(m.survivors, idNumber)
+
+	locations := m.locations[:0]
+	for _, location := range m.locations {
+		if location.idNumber != idNumber {
+			locations = append(locations, location)
+		}
+	}
+	m.locations = locations
+	reports := m.reports[:0]
+	for _, report := range m.reports {
+		if report.reporter != idNumber && report.reported != idNumber {
+			reports = append(reports, report)
+		}
+	}
+	m.reports = reports
+	for _, occupants := range m.occupants {
+		delete(occupants, idNumber)
+	}
+	zoneEvents := m.zoneEvents[:0]
+	for _, event := range m.zoneEvents {
+		if event.IdNumber != idNumber {
+			zoneEvents = append(zoneEvents, event)
+		}
+	}
+	m.zoneEvents = zoneEvents
+
+	return m.recordAudit(AuditDelete, idNumber, before)
+}
+
+// survivorRows returns the stored survivors that match in row id order
+func (m *memoryData) survivorRows(match func(*Survivor) bool) []*memorySurvivor {
+	rows := []*memorySurvivor{}
+	for _, stored := range m.survivors {
+		if match == nil || match(&stored.survivor) {
+			rows = append(rows, stored)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].id < rows[j].id
+	})
+
+	return rows
+}
+
+// listSurvivors returns copies of the stored survivors that match in row id order
+func (m *memoryData) listSurvivors(match func(*Survivor) bool) []Survivor {
+	survivors := []Survivor{}
+	for _, stored := range m.survivorRows(match) {
+		survivors = append(survivors, *stored.survivor.clone())
+	}
+
+	return survivors
+}
+
+// GetAllSurvivors returns all survivors
+func (m *MemoryStore) GetAllSurvivors() []Survivor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.listSurvivors(nil)
+}
+
+// GetSurvivors returns all infected or uninfected survivors
+func (m *MemoryStore) GetSurvivors(infected bool) []Survivor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.listSurvivors(func(survivor *Survivor) bool {
+		return survivor.Infected == infected
+	})
+}
+
+// CountSurvivors counts all infected or uninfected survivors
+func (m *MemoryStore) CountSurvivors(infected bool) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, stored := range m.survivors {
+		if stored.survivor.Infected == infected {
+			count++
+		}
+	}
+
+	return count
+}
+
+// GetSurvivor returns the survivor with an id number, nil when there is none
+func (m *MemoryStore) GetSurvivor(idNumber string) *Survivor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.getSurvivor(idNumber)
+}
+
+// getSurvivor returns a copy of a stored survivor, nil when there is none
+func (m *memoryData) getSurvivor(idNumber string) *Survivor {
+	stored, ok := m.survivors[idNumber]
+	if !ok {
+		return nil
+	}
+	survivor := stored.survivor.clone()
+	if survivor.Inventory == nil {
+		survivor.Inventory = []InventoryItem{}
+	}
+
+	return survivor
+}
+
+// compareSurvivors orders two survivors by a sort column and then by row id
+func compareSurvivors(a, b *memorySurvivor, column string) int {
+	switch column {
+	case "name":
+		if c := strings.Compare(a.survivor.Name, b.survivor.Name); c != 0 {
+			return c
+		}
+	case "age":
+		if a.survivor.Age != b.survivor.Age {
+			if a.survivor.Age < b.survivor.Age {
+				return -1
+			}
+			return 1
+		}
+	case "last_ts":
+		if !a.survivor.LastUpdateTime.Equal(b.survivor.LastUpdateTime) {
+			if a.survivor.LastUpdateTime.Before(b.survivor.LastUpdateTime) {
+				return -1
+			}
+			return 1
+		}
+	}
+	if a.id != b.id {
+		if a.id < b.id {
+			return -1
+		}
+		return 1
+	}
+
+	return 0
+}
+
+// GetSurvivorsPage returns a page of survivors in keyset order. It returns
+// the cursor for the next page, or "" when this is the last page
+func (m *MemoryStore) GetSurvivorsPage(q *SurvivorQuery) ([]Survivor, string, error) {
+	column, ok := sortColumns[q.Sort]
+	if !ok || q.Limit < 0 {
+		return nil, "", ErrInvalidQuery
+	}
+	direction := 1
+	if q.Desc {
+		direction = -1
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var cursor *memorySurvivor
+	if q.Cursor != "" {
+		parsed, err := parsePageCursor(q.Sort, q.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		cursor = &memorySurvivor{id: parsed.ID, survivor: Survivor{Name: parsed.Name, Age: parsed.Age}}
+		if parsed.LastTS != nil {
+			cursor.survivor.LastUpdateTime = *parsed.LastTS
+		}
+	}
+
+	rows := m.survivorRows(func(survivor *Survivor) bool {
+		return (q.Gender == "" || strings.EqualFold(survivor.Gender, q.Gender)) &&
+			(q.MinAge <= 0 || survivor.Age >= q.MinAge) &&
+			(q.MaxAge <= 0 || survivor.Age <= q.MaxAge) &&
+			(q.Infected == nil || survivor.Infected == *q.Infected)
+	})
+	sort.Slice(rows, func(i, j int) bool {
+		return compareSurvivors(rows[i], rows[j], column)*direction < 0
+	})
+
+	survivors := []Survivor{}
+	var last int64
+	next := ""
+	for _, stored := range rows {
+		if cursor != nil && compareSurvivors(stored, cursor, column)*direction <= 0 {
+			continue
+		}
+		if q.Limit > 0 && len(survivors) == q.Limit {
+			next = newPageCursor(q.Sort, &survivors[len(survivors)-1], last)
+			break
+		}
+		survivors = append(survivors, *stored.survivor.clone())
+		last = stored.id
+	}
+
+	return survivors, next, nil
+}
+
+// GetNearbySurvivors returns the survivors within radius km of a point,
+// nearest first. When infected is not nil only survivors with that infection
+// status are returned
+func (m *MemoryStore) GetNearbySurvivors(latitude, longitude, radius float64, infected *bool) []NearbySurvivor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	nearby := []NearbySurvivor{}
+	for _, survivor := range m.listSurvivors(nil) {
+		if infected != nil && survivor.Infected != *infected {
+			continue
+		}
+		distance := Distance(latitude, longitude, survivor.Latitude, survivor.Longitude)
+		if distance <= radius {
+			nearby = append(nearby, NearbySurvivor{Survivor: survivor, Distance: distance})
+		}
+	}
+	sort.SliceStable(nearby, func(i, j int) bool {
+		return nearby[i].Distance < nearby[j].Distance
+	})
+
+	return nearby
+}
+
+// GetInventory returns the inventory of a survivor
+func (m *MemoryStore) GetInventory(idNumber string) []InventoryItem {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	items := []InventoryItem{}
+	if stored, ok := m.survivors[idNumber]; ok {
+		items = append(items, stored.survivor.Inventory...)
+	}
+
+	return items
+}
+
+// inRange reports whether t is between from and to at the precision of the
+// stored timestamps. A zero from or to leaves that end of the range open
+func inRange(t, from, to time.Time) bool {
+	if !from.IsZero() && t.Before(from.UTC().Truncate(time.Second)) {
+		return false
+	}
+	if !to.IsZero() && t.After(to.UTC().Truncate(time.Second)) {
+		return false
+	}
+
+	return true
+}
+
+// GetLocationHistory returns the time ordered locations of a survivor recorded
+// between from and to. A zero from or to leaves that end of the range open
+func (m *MemoryStore) GetLocationHistory(idNumber string, from, to time.Time) []LocationRecord {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	locations := []LocationRecord{}
+	for _, location := range m.locations {
+		if location.idNumber == idNumber && inRange(location.Timestamp, from, to) {
+			locations = append(locations, location.LocationRecord)
+		}
+	}
+	sort.SliceStable(locations, func(i, j int) bool {
+		return locations[i].Timestamp.Before(locations[j].Timestamp)
+	})
+
+	return locations
+}
+
+// totalItems sums the quantity of each item held by the survivors that match,
+// in the order of the item type and name
+func (m *memoryData) totalItems(match func(*Survivor) bool) []InventoryItem {
+	totals := []InventoryItem{}
+	index := map[string]int{}
+	for _, stored := range m.survivorRows(match) {
+		for _, item := range stored.survivor.Inventory {
+			key := item.Type + "\x00" + strings.ToLower(item.Name) + "\x00" + item.Unit
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(totals)
+				totals = append(totals, item)
+				continue
+			}
+			totals[i].Quantity += item.Quantity
+			if item.Name < totals[i].Name {
+				totals[i].Name = item.Name
+			}
+		}
+	}
+	sort.SliceStable(totals, func(i, j int) bool {
+		if totals[i].Type != totals[j].Type {
+			return totals[i].Type < totals[j].Type
+		}
+		if a, b := strings.ToLower(totals[i].Name), strings.ToLower(totals[j].Name); a != b {
+			return a < b
+		}
+		return totals[i].Unit < totals[j].Unit
+	})
+
+	return totals
+}
+
+// InventoryTotals sums the quantity of each item across all survivors
+func (m *MemoryStore) InventoryTotals() []InventoryItem {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.totalItems(nil)
+}
+
+// ageBands are the age bands of the detailed stats, youngest first
+var ageBands = []struct {
+	band  string
+	below int
+}{
+	{"0-12", 13},
+	{"13-17", 18},
+	{"18-29", 30},
+	{"30-44", 45},
+	{"45-59", 60},
+}
+
+// ageBand returns the age band of an age
+func ageBand(age int) string {
+	for _, band := range ageBands {
+		if age < band.below {
+			return band.band
+		}
+	}
+
+	return "60+"
+}
+
+// GetDetailedStats computes the survivor statistics
+func (m *MemoryStore) GetDetailedStats() *DetailedStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := &DetailedStats{}
+	genders := map[string]*GroupStats{}
+	bands := map[string]*GroupStats{}
+	bandOrder := map[string]int{}
+	quantities := map[string]float64{}
+	for _, stored := range m.survivorRows(nil) {
+		survivor := &stored.survivor
+		infected := 0
+		if survivor.Infected {
+			infected = 1
+		} else {
+			for _, item := range survivor.Inventory {
+				if item.Type != ItemWater {
+					quantities[item.Type] += item.Quantity
+				}
+			}
+			quantities[ItemWater] += survivor.Water
+		}
+		stats.Survivors++
+		stats.Infected += infected
+
+		for _, group := range []struct {
+			groups map[string]*GroupStats
+			key    string
+		}{
+			{genders, strings.ToLower(survivor.Gender)},
+			{bands, ageBand(survivor.Age)},
+		} {
+			if group.groups[group.key] == nil {
+				group.groups[group.key] = &GroupStats{Group: group.key}
+			}
+			group.groups[group.key].Survivors++
+			group.groups[group.key].Infected += infected
+		}
+		if order, ok := bandOrder[ageBand(survivor.Age)]; !ok || survivor.Age < order {
+			bandOrder[ageBand(survivor.Age)] = survivor.Age
+		}
+	}
+
+	healthy := stats.Survivors - stats.Infected
+	stats.HealthyPercentage = percentage(healthy, stats.Survivors)
+	stats.InfectedPercentage = percentage(stats.Infected, stats.Survivors)
+	if healthy > 0 {
+		stats.AverageWater = quantities[ItemWater] / float64(healthy)
+		stats.AverageFood = quantities[ItemFood] / float64(healthy)
+		stats.AverageMedication = quantities[ItemMedication] / float64(healthy)
+		stats.AverageAmmunition = quantities[ItemAmmunition] / float64(healthy)
+	}
+	stats.LostResources = m.totalItems(func(survivor *Survivor) bool {
+		return survivor.Infected
+	})
+
+	groupList := func(groups map[string]*GroupStats, less func(a, b string) bool) []GroupStats {
+		list := []GroupStats{}
+		for _, group := range groups {
+			group.InfectedPercentage = percentage(group.Infected, group.Survivors)
+			list = append(list, *group)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			return less(list[i].Group, list[j].Group)
+		})
+		return list
+	}
+	stats.ByGender = groupList(genders, func(a, b string) bool {
+		return a < b
+	})
+	stats.ByAgeBand = groupList(bands, func(a, b string) bool {
+		return bandOrder[a] < bandOrder[b]
+	})
+
+	return stats
+}
+
+// SaveStatsSnapshot stores the current survivor counts and resource totals
+func (m *MemoryStore) SaveStatsSnapshot() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := memorySnapshot{
+		id:            m.nextID("StatsSnapshots"),
+		StatsSnapshot: StatsSnapshot{Timestamp: memoryNow(), Items: []InventoryItem{}},
+	}
+	for _, stored := range m.survivors {
+		if stored.survivor.Infected {
+			snapshot.Infected++
+		} else {
+			snapshot.Healthy++
+		}
+		snapshot.Water += stored.survivor.Water
+		snapshot.Ammunition += float64(stored.survivor.Ammunition)
+	}
+	for _, item := range m.totalItems(nil) {
+		if item.Type == ItemFood || item.Type == ItemMedication {
+			snapshot.Items = append(snapshot.Items, item)
+		}
+	}
+	m.snapshots = append(m.snapshots, snapshot)
+
+	return nil
+}
+
+// snapshotBucket returns the bucket a snapshot taken at t falls in, matching
+// the expressions of snapshotBuckets
+func snapshotBucket(t time.Time, bucket string) string {
+	switch bucket {
+	case "hour":
+		return t.Format("2006-01-02 15")
+	case "day":
+		return t.Format("2006-01-02")
+	case "week":
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%04d-%02d", year, week)
+	case "month":
+		return t.Format("2006-01")
+	}
+
+	return ""
+}
+
+// GetStatsHistory returns the snapshots taken between from and to. With a
+// bucket of hour, day, week or month only the last snapshot of each bucket
+// is returned. A zero from or to leaves that end of the range open
+func (m *MemoryStore) GetStatsHistory(from, to time.Time, bucket string) ([]StatsSnapshot, error) {
+	if _, ok := snapshotBuckets[bucket]; !ok {
+		return nil, ErrInvalidQuery
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	last := map[string]memorySnapshot{}
+	for _, snapshot := range m.snapshots {
+		if !inRange(snapshot.Timestamp, from, to) {
+			continue
+		}
+		key := snapshotBucket(snapshot.Timestamp, bucket)
+		if bucket == "" {
+			key = fmt.Sprint(snapshot.id)
+		}
+		last[key] = snapshot
+	}
+
+	selected := []memorySnapshot{}
+	for _, snapshot := range last {
+		selected = append(selected, snapshot)
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		if !selected[i].Timestamp.Equal(selected[j].Timestamp) {
+			return selected[i].Timestamp.Before(selected[j].Timestamp)
+		}
+		return selected[i].id < selected[j].id
+	})
+
+	snapshots := []StatsSnapshot{}
+	for _, snapshot := range selected {
+		snapshot.InfectedPercentage = percentage(snapshot.Infected, snapshot.Healthy+snapshot.Infected)
+		snapshots = append(snapshots, snapshot.StatsSnapshot)
+	}
+
+	return snapshots, nil
+}
+
+// GetAuditEvents returns at most limit audit events of a survivor, or of all
+// survivors when idNumber is empty, recorded at or after since with an id
+// greater than afterID in the order they happened
+func (m *MemoryStore) GetAuditEvents(idNumber string, since time.Time, afterID int64, limit int) []AuditEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := []AuditEvent{}
+	for _, event := range m.audit {
+		if len(events) == limit {
+			break
+		}
+		if (idNumber == "" || event.IdNumber == idNumber) && inRange(event.Timestamp, since, time.Time{}) && event.ID > afterID {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+// GetAuditEventsAfter returns at most limit audit events with an id greater
+// than afterID in the order they happened
+func (m *MemoryStore) GetAuditEventsAfter(afterID int64, limit int) ([]AuditEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.auditEventsAfter(afterID, limit), nil
+}
+
+// auditEventsAfter returns at most limit audit events after afterID, all of
+// them when limit is negative
+func (m *memoryData) auditEventsAfter(afterID int64, limit int) []AuditEvent {
+	events := []AuditEvent{}
+	for _, event := range m.audit {
+		if limit >= 0 && len(events) == limit {
+			break
+		}
+		if event.ID > afterID {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+// LastAuditEventID returns the id of the last audit event, 0 when there are none
+func (m *MemoryStore) LastAuditEventID() (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.audit) == 0 {
+		return 0, nil
+	}
+
+	return m.audit[len(m.audit)-1].ID, nil
+}
+
+// copyZone copies a zone so that changing its shape leaves the original alone
+func copyZone(zone *Zone) *Zone {
+	copied := *zone
+	if zone.Center != nil {
+		center := *zone.Center
+		copied.Center = &center
+	}
+	copied.Polygon = append([]LastLocation(nil), zone.Polygon...)
+
+	return &copied
+}
+
+// checkZoneName returns ErrDuplicateZone when another zone already has the name
+func (m *memoryData) checkZoneName(zone *Zone) error {
+	for id, stored := range m.zones {
+		if id != zone.ID && stored.Name == zone.Name {
+			return ErrDuplicateZone
+		}
+	}
+
+	return nil
+}
+
+// SaveZone stores a zone, sets its id and records every survivor already
+// inside it as entering it
+func (m *MemoryStore) SaveZone(zone *Zone) error {
+	if err := zone.Validate(); err != nil {
+		return err
+	}
+	zone.ID = 0
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkZoneName(zone); err != nil {
+		return err
+	}
+	zone.ID = m.nextID("Zones")
+	m.zones[zone.ID] = copyZone(zone)
+	m.occupyZone(m.zones[zone.ID])
+
+	return nil
+}
+
+// UpdateZone replaces the definition of a zone and records the survivors
+// the new shape takes in or leaves out as entering or leaving it
+func (m *MemoryStore) UpdateZone(zone *Zone) error {
+	if err := zone.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkZoneName(zone); err != nil {
+		return err
+	}
+	if _, ok := m.zones[zone.ID]; !ok {
+		return ErrZoneNotFound
+	}
+	m.zones[zone.ID] = copyZone(zone)
+	m.occupyZone(m.zones[zone.ID])
+
+	return nil
+}
+
+// DeleteZone deletes a zone along with its occupants and events
+func (m *MemoryStore) DeleteZone(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.zones[id]; !ok {
+		return ErrZoneNotFound
+	}
+	delete(m.zones, id)
+	delete(m.occupants, id)
+	zoneEvents := m.zoneEvents[:0]
+	for _, event := range m.zoneEvents {
+		if event.ZoneID != id {
+			zoneEvents = append(zoneEvents, event)
+		}
+	}
+	m.zoneEvents = zoneEvents
+
+	return nil
+}
+
+// zoneList returns the stored zones in id order
+func (m *memoryData) zoneList() []*Zone {
+	zones := []*Zone{}
+	for _, zone := range m.zones {
+		zones = append(zones, zone)
+	}
+	sort.Slice(zones, func(i, j int) bool {
+		return zones[i].ID < zones[j].ID
+	})
+
+	return zones
+}
+
+// GetZones returns all zones
+func (m *MemoryStore) GetZones() []Zone {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	zones := []Zone{}
+	for _, zone := range m.zoneList() {
+		zones = append(zones, *copyZone(zone))
+	}
+
+	return zones
+}
+
+// GetZone returns a zone by id, nil when there is none
+func (m *MemoryStore) GetZone(id int64) *Zone {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	zone, ok := m.zones[id]
+	if !ok {
+		return nil
+	}
+
+	return copyZone(zone)
+}
+
+// UpdateZoneOccupancy checks a survivor location against every zone and
+// records an enter or exit event for each zone the survivor crossed into or
+// out of
+func (m *MemoryStore) UpdateZoneOccupancy(idNumber string, longitude, latitude float64) ([]ZoneEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.occupy(m.zoneList(), idNumber, longitude, latitude), nil
+}
+
+// occupy checks a survivor location against zones like UpdateZoneOccupancy
+// with the lock held
+func (m *memoryData) occupy(zones []*Zone, idNumber string, longitude, latitude float64) []ZoneEvent {
+	now := time.Now().UTC()
+	events := []ZoneEvent{}
+	for _, zone := range zones {
+		_, occupied := m.occupants[zone.ID][idNumber]
+		inside := zone.Contains(longitude, latitude)
+		if inside == occupied {
+			continue
+		}
+
+		event := ZoneEvent{
+			ZoneID:       zone.ID,
+			ZoneName:     zone.Name,
+			IdNumber:     idNumber,
+			Event:        ZoneEnter,
+			LastLocation: LastLocation{Longitude: longitude, Latitude: latitude},
+			Timestamp:    now,
+		}
+		if inside {
+			if m.occupants[zone.ID] == nil {
+				m.occupants[zone.ID] = map[string]time.Time{}
+			}
+			m.occupants[zone.ID][idNumber] = now.Truncate(time.Second)
+		} else {
+			event.Event = ZoneExit
+			delete(m.occupants[zone.ID], idNumber)
+		}
+		stored := memoryZoneEvent{id: m.nextID("ZoneEvents"), ZoneEvent: event}
+		stored.Timestamp = now.Truncate(time.Second)
+		m.zoneEvents = append(m.zoneEvents, stored)
+		events = append(events, event)
+	}
+
+	return events
+}
+
+// occupyZone checks the location of every survivor against a zone with the
+// lock held
+func (m *memoryData) occupyZone(zone *Zone) {
+	for _, stored := range m.survivorRows(nil) {
+		m.occupy([]*Zone{zone}, stored.survivor.IdNumber, stored.survivor.Longitude, stored.survivor.Latitude)
+	}
+}
+
+// GetZoneOccupants returns the survivors currently inside a zone
+func (m *MemoryStore) GetZoneOccupants(id int64) []Survivor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	occupants := m.occupants[id]
+	rows := m.survivorRows(func(survivor *Survivor) bool {
+		_, ok := occupants[survivor.IdNumber]
+		return ok
+	})
+	sort.SliceStable(rows, func(i, j int) bool {
+		return occupants[rows[i].survivor.IdNumber].Before(occupants[rows[j].survivor.IdNumber])
+	})
+
+	survivors := []Survivor{}
+	for _, stored := range rows {
+		survivors = append(survivors, *stored.survivor.clone())
+	}
+
+	return survivors
+}
+
+// GetZoneEvents returns the enter and exit events of a zone in time order
+func (m *MemoryStore) GetZoneEvents(id int64) []ZoneEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	events := []ZoneEvent{}
+	zone, ok := m.zones[id]
+	if !ok {
+		return events
+	}
+	for _, event := range m.zoneEvents {
+		if event.ZoneID == id {
+			event.ZoneName = zone.Name
+			events = append(events, event.ZoneEvent)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+
+	return events
+}
+
+// copyWebhook copies a webhook so that changing its events leaves the original alone
+func copyWebhook(webhook *Webhook) *Webhook {
+	copied := *webhook
+	copied.Events = append([]string{}, webhook.Events...)
+
+	return &copied
+}
+
+// SaveWebhook stores a webhook, generating its secret when it has none
+func (m *MemoryStore) SaveWebhook(webhook *Webhook) error {
+	if err := webhook.Validate(); err != nil {
+		return err
+	}
+	if webhook.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+			}).Info("Error generating webhook secret")
+			return err
+		}
+		webhook.Secret = secret
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	webhook.ID = m.nextID("Webhooks")
+	m.webhooks[webhook.ID] = copyWebhook(webhook)
+
+	return nil
+}
+
+// UpdateWebhook replaces the url, events and disabled flag of a webhook. The
+// secret is only replaced when one is given
+func (m *MemoryStore) UpdateWebhook(webhook *Webhook) error {
+	if err := webhook.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.webhooks[webhook.ID]
+	if !ok {
+		return ErrWebhookNotFound
+	}
+	updated := copyWebhook(webhook)
+	if updated.Secret == "" {
+		updated.Secret = stored.Secret
+	}
+	m.webhooks[webhook.ID] = updated
+
+	return nil
+}
+
+// DeleteWebhook deletes a webhook along with its deliveries and attempts
+func (m *MemoryStore) DeleteWebhook(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.webhooks[id]; !ok {
+		return ErrWebhookNotFound
+	}
+	delete(m.webhooks, id)
+	deliveries := m.deliveries[:0]
+	for _, delivery := range m.deliveries {
+		if delivery.WebhookID != id {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	m.deliveries = deliveries
+
+	return nil
+}
+
+// webhookList returns the stored webhooks in id order
+func (m *memoryData) webhookList() []*Webhook {
+	webhooks := []*Webhook{}
+	for _, webhook := range m.webhooks {
+		webhooks = append(webhooks, webhook)
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].ID < webhooks[j].ID
+	})
+
+	return webhooks
+}
+
+// GetWebhooks returns all webhooks including their secrets
+func (m *MemoryStore) GetWebhooks() []Webhook {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	webhooks := []Webhook{}
+	for _, webhook := range m.webhookList() {
+		webhooks = append(webhooks, *copyWebhook(webhook))
+	}
+
+	return webhooks
+}
+
+// GetWebhook returns a webhook including its secret, nil when there is none
+func (m *MemoryStore) GetWebhook(id int64) *Webhook {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	webhook, ok := m.webhooks[id]
+	if !ok {
+		return nil
+	}
+
+	return copyWebhook(webhook)
+}
+
+// EnqueueWebhookDeliveries turns the survivor changes recorded since the
+// last call into pending deliveries for every subscribed webhook. At most
+// limit audit events are read per call. It returns the number of deliveries
+// added
+func (m *MemoryStore) EnqueueWebhookDeliveries(limit int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	auditEvents := m.auditEventsAfter(m.webhookCursor, limit)
+	if len(auditEvents) == 0 {
+		return 0, nil
+	}
+
+	webhooks := m.webhookList()
+	now := memoryNow()
+	deliveries := []*WebhookDelivery{}
+	for _, auditEvent := range auditEvents {
+		for _, event := range auditEvent.SurvivorEvents() {
+			payload, err := json.Marshal(event)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"body":  event,
+					"Error": err,
+				}).Error("Marshal")
+				return 0, err
+			}
+			for _, webhook := range webhooks {
+				if !webhook.Subscribed(event.Type) {
+					continue
+				}
+				deliveries = append(deliveries, &WebhookDelivery{
+					WebhookID:   webhook.ID,
+					EventID:     event.ID,
+					EventType:   event.Type,
+					Payload:     json.RawMessage(payload),
+					Status:      DeliveryPending,
+					NextAttempt: now,
+				})
+			}
+		}
+	}
+
+	for _, delivery := range deliveries {
+		delivery.ID = m.nextID("WebhookDeliveries")
+		m.deliveries = append(m.deliveries, delivery)
+	}
+	m.webhookCursor = auditEvents[len(auditEvents)-1].ID
+
+	return len(deliveries), nil
+}
+
+// GetDueWebhookDeliveries returns at most limit pending deliveries whose
+// next attempt is due at now, with the url and secret of their webhook
+func (m *MemoryStore) GetDueWebhookDeliveries(now time.Time, limit int) ([]WebhookDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now = now.UTC().Truncate(time.Second)
+	due := []WebhookDelivery{}
+	for _, delivery := range m.deliveries {
+		webhook, ok := m.webhooks[delivery.WebhookID]
+		if !ok || delivery.Status != DeliveryPending || delivery.NextAttempt.After(now) {
+			continue
+		}
+		copied := *delivery
+		copied.History = nil
+		copied.URL = webhook.URL
+		copied.Secret = webhook.Secret
+		due = append(due, copied)
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return due[i].NextAttempt.Before(due[j].NextAttempt)
+	})
+	if limit >= 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	return due, nil
+}
+
+// RecordWebhookAttempt stores an attempt to deliver an event and moves the
+// delivery to status, with its next attempt due at next when it is pending
+func (m *MemoryStore) RecordWebhookAttempt(deliveryID int64, attempt *WebhookAttempt, status string, next time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, delivery := range m.deliveries {
+		if delivery.ID != deliveryID {
+			continue
+		}
+		recorded := *attempt
+		recorded.Timestamp = attempt.Timestamp.UTC().Truncate(time.Second)
+		delivery.History = append(delivery.History, recorded)
+		delivery.Status = status
+		delivery.Attempts = attempt.Attempt
+		delivery.NextAttempt = next.UTC().Truncate(time.Second)
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries returns the last limit deliveries of a webhook, newest
+// first, with the history of their attempts
+func (m *MemoryStore) GetWebhookDeliveries(webhookID int64, limit int) []WebhookDelivery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deliveries := []WebhookDelivery{}
+	for i := len(m.deliveries) - 1; i >= 0; i-- {
+		if limit >= 0 && len(deliveries) == limit {
+			break
+		}
+		delivery := *m.deliveries[i]
+		if delivery.WebhookID != webhookID {
+			continue
+		}
+		delivery.History = append([]WebhookAttempt{}, delivery.History...)
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries
+}
+
+// copyAPIKey copies an API key so that revoking it leaves the original alone
+func copyAPIKey(key *APIKey) *APIKey {
+	copied := *key
+	copied.Hash = append([]byte(nil), key.Hash...)
+	if key.RevokedAt != nil {
+		revokedAt := *key.RevokedAt
+		copied.RevokedAt = &revokedAt
+	}
+
+	return &copied
+}
+
+// SaveAPIKey stores an API key and sets its id
+func (m *MemoryStore) SaveAPIKey(key *APIKey) error {
+	if err := key.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, stored := range m.apiKeys {
+		if stored.Prefix == key.Prefix {
+			return errDuplicatePrefix
+		}
+	}
+	key.ID = m.nextID("APIKeys")
+	stored := copyAPIKey(key)
+	stored.CreatedAt = memoryNow()
+	stored.RevokedAt = nil
+	m.apiKeys = append(m.apiKeys, stored)
+
+	return nil
+}
+
+// RevokeAPIKey marks an API key as revoked so it no longer authenticates
+func (m *MemoryStore) RevokeAPIKey(id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range m.apiKeys {
+		if key.ID == id && key.RevokedAt == nil {
+			revokedAt := memoryNow()
+			key.RevokedAt = &revokedAt
+			return nil
+		}
+	}
+
+	return ErrAPIKeyNotFound
+}
+
+// GetAPIKeys returns all API keys, including the revoked ones
+func (m *MemoryStore) GetAPIKeys() []APIKey {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := []APIKey{}
+	for _, key := range m.apiKeys {
+		keys = append(keys, *copyAPIKey(key))
+	}
+
+	return keys
+}
+
+// GetAPIKeyByPrefix returns the API key with the given public prefix, nil
+// when there is none
+func (m *MemoryStore) GetAPIKeyByPrefix(prefix string) *APIKey {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range m.apiKeys {
+		if key.Prefix == prefix {
+			return copyAPIKey(key)
+		}
+	}
+
+	return nil
+}
+
+// SaveRobots stores the robots, or updates those whose serial number is
+// stored and marks them as seen now. It returns ErrInvalidRobot, saving none,
+// when a robot has no serial number
+func (m *MemoryStore) SaveRobots(robots []Robot) error {
+	for _, robot := range robots {
+		if robot.SerialNumber == "" {
+			return ErrInvalidRobot
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := memoryNow()
+	for _, robot := range robots {
+		robot.ManufacturedDate = robot.ManufacturedDate.UTC().Truncate(time.Second)
+		robot.LastSeen = now
+		stored, ok := m.robots[robot.SerialNumber]
+		if !ok {
+			robot.FirstSeen = now
+			m.robots[robot.SerialNumber] = &memoryRobot{id: m.nextID("Robots"), robot: robot}
+			continue
+		}
+		robot.FirstSeen = stored.robot.FirstSeen
+		stored.robot = robot
+	}
+
+	return nil
+}
+
+// GetRobots returns all robots in the order they were first seen
+func (m *MemoryStore) GetRobots() []Robot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := make([]*memoryRobot, 0, len(m.robots))
+	for _, robot := range m.robots {
+		stored = append(stored, robot)
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].id < stored[j].id
+	})
+
+	robots := make([]Robot, 0, len(stored))
+	for _, robot := range stored {
+		robots = append(robots, robot.robot)
+	}
+
+	return robots
+}
@@ -0,0 +1,334 @@
+package survivordb
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// migrationFiles holds the migrations of each driver in migrations/<driver>,
+// as <version>_<name>.up.sql and <version>_<name>.down.sql files
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// ErrSchemaTooNew is returned when the database has migrations applied that
+// this version does not know, so it was last migrated by a newer version
+var ErrSchemaTooNew = errors.New("database schema is newer than this version")
+
+// ErrUnknownMigration is returned when migrating to a version beyond the
+// last migration
+var ErrUnknownMigration = errors.New("unknown migration version")
+
+const (
+	schemaVersionDDL = `CREATE TABLE IF NOT EXISTS schema_version (
+	version INTEGER PRIMARY KEY NOT NULL,
+	name TEXT NOT NULL,
+	applied_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+	);`
+	selectSchemaVersionsSQL = `SELECT version, applied_ts FROM schema_version ORDER BY version;`
+	insertSchemaVersionSQL  = `INSERT INTO schema_version (version, name) VALUES(?,?);`
+	deleteSchemaVersionSQL  = `DELETE FROM schema_version WHERE version = ?;`
+)
+
+// Migration defines a numbered change to the schema
+type Migration struct {
+	Version int
+	Name    string
+	// Up holds the statements that apply the change and Down those that
+	// undo it
+	Up   string
+	Down string
+}
+
+// MigrationStatus defines a migration and when it was applied, nil when it
+// is not applied
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// DroppedTables returns the tables that undoing the migration drops, in the
+// order its down statements drop them
+func (m Migration) DroppedTables() []string {
+	tables := []string{}
+	for _, statement := range strings.Split(m.Down, ";") {
+		fields := strings.Fields(statement)
+		if len(fields) < 3 || !strings.EqualFold(fields[0], "DROP") || !strings.EqualFold(fields[1], "TABLE") {
+			continue
+		}
+		fields = fields[2:]
+		if len(fields) > 2 && strings.EqualFold(fields[0], "IF") && strings.EqualFold(fields[1], "EXISTS") {
+			fields = fields[2:]
+		}
+		tables = append(tables, strings.Trim(fields[0], `"`))
+	}
+
+	return tables
+}
+
+// Migrations returns the migrations of driverName ordered by version
+func Migrations(driverName string) ([]Migration, error) {
+	dir := path.Join("migrations", driverName)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for database driver %q", driverName)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		file := entry.Name()
+		up := strings.HasSuffix(file, ".up.sql")
+		if !up && !strings.HasSuffix(file, ".down.sql") {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimSuffix(strings.TrimSuffix(file, ".up.sql"), ".down.sql"), "_", 2)
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || version <= 0 || len(parts) != 2 {
+			return nil, fmt.Errorf("invalid migration file name %s", file)
+		}
+		statements, err := migrationFiles.ReadFile(path.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: parts[1]}
+			byVersion[version] = migration
+		}
+		if migration.Name != parts[1] {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", migration.Name, parts[1], version)
+		}
+		if up {
+			migration.Up = string(statements)
+		} else {
+			migration.Down = string(statements)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// appliedMigrations returns when each applied migration was applied by
+// version, creating the schema_version table when there is none
+func (s *SurvivorDB) appliedMigrations() (map[int]time.Time, error) {
+	if _, err := s.DB.Exec(schemaVersionDDL); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   schemaVersionDDL,
+		}).Info("Sql error")
+		return nil, err
+	}
+
+	rows, err := s.DB.Query(selectSchemaVersionsSQL)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectSchemaVersionsSQL,
+		}).Info("Sql error")
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectSchemaVersionsSQL,
+			}).Info("Sql error")
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// migrationState returns the migrations of the database and those applied.
+// It returns ErrSchemaTooNew when a migration it does not know is applied
+func (s *SurvivorDB) migrationState() ([]Migration, map[int]time.Time, error) {
+	migrations, err := Migrations(s.Driver)
+	if err != nil {
+		return nil, nil, err
+	}
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	known := map[int]bool{}
+	for _, migration := range migrations {
+		known[migration.Version] = true
+	}
+	for version := range applied {
+		if !known[version] {
+			return nil, nil, ErrSchemaTooNew
+		}
+	}
+
+	return migrations, applied, nil
+}
+
+// SchemaVersion returns the version of the last migration applied, 0 when
+// none is
+func (s *SurvivorDB) SchemaVersion() (int, error) {
+	applied, err := s.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+
+	return version, nil
+}
+
+// MigrationStatus returns every migration and when it was applied
+func (s *SurvivorDB) MigrationStatus() ([]MigrationStatus, error) {
+	migrations, applied, err := s.migrationState()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []MigrationStatus{}
+	for _, migration := range migrations {
+		status := MigrationStatus{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// MigrateUp applies the migrations that are not applied up to version in
+// order, or all of them when version is 0. It returns the migrations applied
+func (s *SurvivorDB) MigrateUp(version int) ([]Migration, error) {
+	migrations, applied, err := s.migrationState()
+	if err != nil {
+		return nil, err
+	}
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+	if version == 0 {
+		version = latest
+	}
+	if version < 0 || version > latest {
+		return nil, ErrUnknownMigration
+	}
+
+	done := []Migration{}
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok || migration.Version > version {
+			continue
+		}
+		err = s.runMigration(migration.Up, insertSchemaVersionSQL, migration.Version, migration.Name)
+		if err != nil {
+			return done, err
+		}
+		logrus.WithFields(logrus.Fields{
+			"version": migration.Version,
+			"name":    migration.Name,
+		}).Info("Applied migration")
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// MigrateDown undoes the applied migrations above version, latest first, so
+// that 0 undoes all of them. It returns the migrations undone
+func (s *SurvivorDB) MigrateDown(version int) ([]Migration, error) {
+	if version < 0 {
+		return nil, ErrUnknownMigration
+	}
+	migrations, applied, err := s.migrationState()
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if _, ok := applied[migration.Version]; !ok || migration.Version <= version {
+			continue
+		}
+		if err = s.runMigration(migration.Down, deleteSchemaVersionSQL, migration.Version); err != nil {
+			return done, err
+		}
+		logrus.WithFields(logrus.Fields{
+			"version": migration.Version,
+			"name":    migration.Name,
+		}).Info("Undid migration")
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// runMigration runs the statements of a migration and records the change in
+// the schema_version table in one transaction
+func (s *SurvivorDB) runMigration(statements, record string, args ...interface{}) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec(statements); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   statements,
+		}).Info("Sql error")
+		return err
+	}
+	if _, err = tx.Exec(record, args...); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   record,
+		}).Info("Sql error")
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package survivordb
+
+import (
+	"os"
+	"testing"
+)
+
+// TestMigrations checks if every driver has the same numbered migrations
+func TestMigrations(t *testing.T) {
+	sqlite, err := Migrations(SQLite)
+	if err != nil || len(sqlite) == 0 {
+		t.Fatalf("Migrations() - %q: got: %v %v", SQLite, sqlite, err)
+	}
+	postgres, err := Migrations(Postgres)
+	if err != nil || len(postgres) != len(sqlite) {
+		t.Fatalf("Migrations() - %q: want: %v, got: %v %v", Postgres, len(sqlite), len(postgres), err)
+	}
+	for i := range sqlite {
+		if sqlite[i].Version != i+1 || postgres[i].Version != i+1 || sqlite[i].Name != postgres[i].Name {
+			t.Errorf("Migrations(): want: version %d, got: %d_%s and %d_%s", i+1,
+				sqlite[i].Version, sqlite[i].Name, postgres[i].Version, postgres[i].Name)
+		}
+	}
+
+	for _, migrations := range [][]Migration{sqlite, postgres} {
+		tables := migrations[0].DroppedTables()
+		if len(tables) == 0 || tables[len(tables)-1] != "Survivors" {
+			t.Errorf("Migration.DroppedTables(): want: %v last, got: %v", "Survivors", tables)
+		}
+	}
+
+	if _, err = Migrations("mysql"); err == nil {
+		t.Errorf("Migrations() - %q: want: an error, got: %v", "mysql", err)
+	}
+}
+
+// TestSurvivorDB_Migrate checks if migrations are applied and undone in order
+func TestSurvivorDB_Migrate(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	if err := survivordb.Setup(); err != nil {
+		t.Fatalf("SurvivorDB.Setup(): Failed to setup database")
+	}
+	defer survivordb.DB.Close()
+	migrations, _ := Migrations(SQLite)
+	latest := migrations[len(migrations)-1].Version
+
+	if version, err := survivordb.SchemaVersion(); err != nil || version != latest {
+		t.Errorf("SurvivorDB.SchemaVersion(): want: %v, got: %v %v", latest, version, err)
+	}
+	statuses, err := survivordb.MigrationStatus()
+	if err != nil || len(statuses) != len(migrations) {
+		t.Fatalf("SurvivorDB.MigrationStatus(): want: %v, got: %v %v", len(migrations), statuses, err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Errorf("SurvivorDB.MigrationStatus() - %d: want: applied, got: %v", status.Version, status.AppliedAt)
+		}
+	}
+
+	done, err := survivordb.MigrateDown(latest - 1)
+	if err != nil || len(done) != 1 || done[0].Version != latest {
+		t.Errorf("SurvivorDB.MigrateDown(): want: %v, got: %v %v", latest, done, err)
+	}
+	if statuses, _ = survivordb.MigrationStatus(); statuses[len(statuses)-1].AppliedAt != nil {
+		t.Errorf("SurvivorDB.MigrationStatus(): want: %v, got: %v", nil, statuses[len(statuses)-1].AppliedAt)
+	}
+	if done, err = survivordb.MigrateDown(0); err != nil || len(done) != latest-1 {
+		t.Errorf("SurvivorDB.MigrateDown(): want: %v, got: %v %v", latest-1, done, err)
+	}
+	var count int
+	if err = survivordb.DB.QueryRow(`SELECT count(*) FROM Survivors;`).Scan(&count); err == nil {
+		t.Errorf("SurvivorDB.MigrateDown(): the Survivors table was not dropped")
+	}
+	if version, err := survivordb.SchemaVersion(); err != nil || version != 0 {
+		t.Errorf("SurvivorDB.SchemaVersion(): want: %v, got: %v %v", 0, version, err)
+	}
+
+	if _, err = survivordb.MigrateUp(latest + 1); err != ErrUnknownMigration {
+		t.Errorf("SurvivorDB.MigrateUp(): want: %v, got: %v", ErrUnknownMigration, err)
+	}
+	if done, err = survivordb.MigrateUp(0); err != nil || len(done) != latest {
+		t.Errorf("SurvivorDB.MigrateUp(): want: %v, got: %v %v", latest, done, err)
+	}
+	if err = survivordb.Setup(); err != nil {
+		t.Errorf("SurvivorDB.Setup(): want: %v, got: %v", nil, err)
+	}
+	if err = survivordb.Save(&Survivor{Name: "Jane Doe", IdNumber: "HD138VOP34219"}); err != nil {
+		t.Errorf("SurvivorDB.Save(): want: %v, got: %v", nil, err)
+	}
+
+	if _, err = survivordb.DB.Exec(insertSchemaVersionSQL, latest+1, "from_the_future"); err != nil {
+		t.Fatalf("SurvivorDB.DB.Exec(): want: %v, got: %v", nil, err)
+	}
+	if err = survivordb.Setup(); err != ErrSchemaTooNew {
+		t.Errorf("SurvivorDB.Setup(): want: %v, got: %v", ErrSchemaTooNew, err)
+	}
+}
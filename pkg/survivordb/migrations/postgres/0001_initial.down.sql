@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS APIKeys;
+DROP TABLE IF EXISTS WebhookAttempts;
+DROP TABLE IF EXISTS WebhookDeliveries;
+DROP TABLE IF EXISTS WebhookCursor;
+DROP TABLE IF EXISTS Webhooks;
+DROP TABLE IF EXISTS AuditEvents;
+DROP TABLE IF EXISTS StatsSnapshots;
+DROP TABLE IF EXISTS ZoneEvents;
+DROP TABLE IF EXISTS ZoneOccupants;
+DROP TABLE IF EXISTS Zones;
+DROP TABLE IF EXISTS LocationHistory;
+DROP TABLE IF EXISTS Inventory;
+DROP TABLE IF EXISTS InfectionReports;
+DROP TABLE IF EXISTS Survivors;
+DROP FUNCTION IF EXISTS audit_events_append_only();
@@ -0,0 +1,162 @@
+-- The schema as it was before versioned migrations. Every statement leaves
+-- existing tables alone, so that databases created before schema_version
+-- adopt it unchanged.
+
+CREATE TABLE IF NOT EXISTS Survivors (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT COLLATE "C",
+	age INTEGER,
+	gender TEXT COLLATE "C",
+	id_number TEXT,
+	longitude TEXT,
+	latitude TEXT,
+	water TEXT,
+	food TEXT,
+	medication TEXT,
+	ammunition TEXT,
+	infected INTEGER,
+	last_ts TIMESTAMP(0) DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS InfectionReports (
+	id BIGSERIAL PRIMARY KEY,
+	reporter_id TEXT NOT NULL,
+	reported_id TEXT NOT NULL,
+	report_ts TIMESTAMP(0) DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	UNIQUE (reporter_id, reported_id)
+);
+
+CREATE TABLE IF NOT EXISTS Inventory (
+	id BIGSERIAL PRIMARY KEY,
+	survivor_id TEXT NOT NULL,
+	item_type TEXT COLLATE "C" NOT NULL,
+	item_name TEXT COLLATE "C" NOT NULL,
+	quantity DOUBLE PRECISION NOT NULL,
+	unit TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS inventory_survivor_id ON Inventory (survivor_id);
+
+CREATE TABLE IF NOT EXISTS LocationHistory (
+	id BIGSERIAL PRIMARY KEY,
+	survivor_id TEXT NOT NULL,
+	longitude DOUBLE PRECISION NOT NULL,
+	latitude DOUBLE PRECISION NOT NULL,
+	recorded_ts TIMESTAMP(0) DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS location_history_survivor_id ON LocationHistory (survivor_id, recorded_ts);
+
+CREATE TABLE IF NOT EXISTS Zones (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE,
+	kind TEXT NOT NULL,
+	latitude DOUBLE PRECISION,
+	longitude DOUBLE PRECISION,
+	radius DOUBLE PRECISION,
+	polygon TEXT
+);
+
+CREATE TABLE IF NOT EXISTS ZoneOccupants (
+	zone_id BIGINT NOT NULL,
+	survivor_id TEXT NOT NULL,
+	entered_ts TIMESTAMP(0) DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	PRIMARY KEY (zone_id, survivor_id)
+);
+
+CREATE TABLE IF NOT EXISTS ZoneEvents (
+	id BIGSERIAL PRIMARY KEY,
+	zone_id BIGINT NOT NULL,
+	survivor_id TEXT NOT NULL,
+	event TEXT NOT NULL,
+	longitude DOUBLE PRECISION NOT NULL,
+	latitude DOUBLE PRECISION NOT NULL,
+	event_ts TIMESTAMP(0) DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS StatsSnapshots (
+	id BIGSERIAL PRIMARY KEY,
+	healthy INTEGER NOT NULL,
+	infected INTEGER NOT NULL,
+	water DOUBLE PRECISION NOT NULL,
+	food DOUBLE PRECISION NOT NULL,
+	medication DOUBLE PRECISION NOT NULL,
+	ammunition DOUBLE PRECISION NOT NULL,
+	recorded_ts TIMESTAMP(0) DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS stats_snapshots_recorded_ts ON StatsSnapshots (recorded_ts);
+
+CREATE TABLE IF NOT EXISTS AuditEvents (
+	id BIGSERIAL PRIMARY KEY,
+	survivor_id TEXT NOT NULL,
+	action TEXT NOT NULL,
+	actor TEXT NOT NULL,
+	remote_addr TEXT NOT NULL,
+	diff TEXT NOT NULL,
+	recorded_ts TIMESTAMP(0) DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_survivor_id ON AuditEvents (survivor_id, recorded_ts);
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN RAISE EXCEPTION 'audit events are append-only'; END;
+$$ LANGUAGE plpgsql;
+DROP TRIGGER IF EXISTS audit_events_no_update ON AuditEvents;
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON AuditEvents
+FOR EACH ROW EXECUTE PROCEDURE audit_events_append_only();
+
+DROP TRIGGER IF EXISTS audit_events_no_delete ON AuditEvents;
+CREATE TRIGGER audit_events_no_delete BEFORE DELETE ON AuditEvents
+FOR EACH ROW EXECUTE PROCEDURE audit_events_append_only();
+
+CREATE TABLE IF NOT EXISTS Webhooks (
+	id BIGSERIAL PRIMARY KEY,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	events TEXT NOT NULL,
+	disabled INTEGER NOT NULL,
+	created_ts TIMESTAMP(0) DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS WebhookCursor (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	audit_id BIGINT NOT NULL
+);
+
+INSERT INTO WebhookCursor (id, audit_id) SELECT 1, coalesce(max(id), 0) FROM AuditEvents ON CONFLICT (id) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS WebhookDeliveries (
+	id BIGSERIAL PRIMARY KEY,
+	webhook_id BIGINT NOT NULL,
+	event_id BIGINT NOT NULL,
+	event_type TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	status TEXT NOT NULL,
+	attempts INTEGER NOT NULL,
+	next_attempt_ts TIMESTAMP(0) NOT NULL,
+	created_ts TIMESTAMP(0) DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due ON WebhookDeliveries (status, next_attempt_ts);
+
+CREATE TABLE IF NOT EXISTS WebhookAttempts (
+	id BIGSERIAL PRIMARY KEY,
+	delivery_id BIGINT NOT NULL,
+	attempt INTEGER NOT NULL,
+	status_code INTEGER NOT NULL,
+	error TEXT NOT NULL,
+	attempted_ts TIMESTAMP(0) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_attempts_delivery_id ON WebhookAttempts (delivery_id);
+
+CREATE TABLE IF NOT EXISTS APIKeys (
+	id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL,
+	role TEXT NOT NULL,
+	prefix TEXT NOT NULL UNIQUE,
+	hash BYTEA NOT NULL,
+	created_ts TIMESTAMP(0) DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	revoked_ts TIMESTAMP(0)
+);
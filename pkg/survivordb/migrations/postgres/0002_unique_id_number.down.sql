@@ -0,0 +1 @@
+DROP INDEX IF EXISTS survivors_id_number;
@@ -0,0 +1,8 @@
+ALTER TABLE StatsSnapshots ADD COLUMN IF NOT EXISTS food DOUBLE PRECISION NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS medication DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+UPDATE StatsSnapshots s SET
+	food = (SELECT coalesce(sum(quantity), 0) FROM StatsSnapshotItems i WHERE i.snapshot_id = s.id AND i.item_type = 'food'),
+	medication = (SELECT coalesce(sum(quantity), 0) FROM StatsSnapshotItems i WHERE i.snapshot_id = s.id AND i.item_type = 'medication');
+
+DROP TABLE IF EXISTS StatsSnapshotItems;
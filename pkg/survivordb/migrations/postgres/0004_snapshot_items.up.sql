@@ -0,0 +1,13 @@
+-- The food and medication of a snapshot are totalled by item and unit, as
+-- the quantities of different items in different units do not add up.
+CREATE TABLE IF NOT EXISTS StatsSnapshotItems (
+	snapshot_id BIGINT NOT NULL,
+	item_type TEXT NOT NULL,
+	item_name TEXT NOT NULL,
+	quantity DOUBLE PRECISION NOT NULL,
+	unit TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS stats_snapshot_items_snapshot_id ON StatsSnapshotItems (snapshot_id);
+
+ALTER TABLE StatsSnapshots DROP COLUMN IF EXISTS food, DROP COLUMN IF EXISTS medication;
@@ -0,0 +1,157 @@
+-- The schema as it was before versioned migrations. Every statement leaves
+-- existing tables alone, so that databases created before schema_version
+-- adopt it unchanged.
+
+CREATE TABLE IF NOT EXISTS Survivors (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	name TEXT,
+	age INTEGER,
+	gender TEXT,
+	id_number TEXT,
+	longitude TEXT,
+	latitude TEXT,
+	water TEXT,
+	food TEXT,
+	medication TEXT,
+	ammunition TEXT,
+	infected INTEGER,
+	last_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS InfectionReports (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	reporter_id TEXT NOT NULL,
+	reported_id TEXT NOT NULL,
+	report_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	UNIQUE (reporter_id, reported_id)
+);
+
+CREATE TABLE IF NOT EXISTS Inventory (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	survivor_id TEXT NOT NULL,
+	item_type TEXT NOT NULL,
+	item_name TEXT NOT NULL,
+	quantity REAL NOT NULL,
+	unit TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS inventory_survivor_id ON Inventory (survivor_id);
+
+CREATE TABLE IF NOT EXISTS LocationHistory (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	survivor_id TEXT NOT NULL,
+	longitude REAL NOT NULL,
+	latitude REAL NOT NULL,
+	recorded_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS location_history_survivor_id ON LocationHistory (survivor_id, recorded_ts);
+
+CREATE TABLE IF NOT EXISTS Zones (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	name TEXT NOT NULL UNIQUE,
+	kind TEXT NOT NULL,
+	latitude REAL,
+	longitude REAL,
+	radius REAL,
+	polygon TEXT
+);
+
+CREATE TABLE IF NOT EXISTS ZoneOccupants (
+	zone_id INTEGER NOT NULL,
+	survivor_id TEXT NOT NULL,
+	entered_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	PRIMARY KEY (zone_id, survivor_id)
+);
+
+CREATE TABLE IF NOT EXISTS ZoneEvents (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	zone_id INTEGER NOT NULL,
+	survivor_id TEXT NOT NULL,
+	event TEXT NOT NULL,
+	longitude REAL NOT NULL,
+	latitude REAL NOT NULL,
+	event_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS StatsSnapshots (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	healthy INTEGER NOT NULL,
+	infected INTEGER NOT NULL,
+	water REAL NOT NULL,
+	food REAL NOT NULL,
+	medication REAL NOT NULL,
+	ammunition REAL NOT NULL,
+	recorded_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS stats_snapshots_recorded_ts ON StatsSnapshots (recorded_ts);
+
+CREATE TABLE IF NOT EXISTS AuditEvents (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	survivor_id TEXT NOT NULL,
+	action TEXT NOT NULL,
+	actor TEXT NOT NULL,
+	remote_addr TEXT NOT NULL,
+	diff TEXT NOT NULL,
+	recorded_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_survivor_id ON AuditEvents (survivor_id, recorded_ts);
+
+CREATE TRIGGER IF NOT EXISTS audit_events_no_update BEFORE UPDATE ON AuditEvents
+BEGIN SELECT RAISE(ABORT, 'audit events are append-only'); END;
+
+CREATE TRIGGER IF NOT EXISTS audit_events_no_delete BEFORE DELETE ON AuditEvents
+BEGIN SELECT RAISE(ABORT, 'audit events are append-only'); END;
+
+CREATE TABLE IF NOT EXISTS Webhooks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	events TEXT NOT NULL,
+	disabled INTEGER NOT NULL,
+	created_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS WebhookCursor (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	audit_id INTEGER NOT NULL
+);
+
+INSERT OR IGNORE INTO WebhookCursor (id, audit_id) SELECT 1, coalesce(max(id), 0) FROM AuditEvents;
+
+CREATE TABLE IF NOT EXISTS WebhookDeliveries (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	webhook_id INTEGER NOT NULL,
+	event_id INTEGER NOT NULL,
+	event_type TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	status TEXT NOT NULL,
+	attempts INTEGER NOT NULL,
+	next_attempt_ts TIMESTAMP NOT NULL,
+	created_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due ON WebhookDeliveries (status, next_attempt_ts);
+
+CREATE TABLE IF NOT EXISTS WebhookAttempts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	delivery_id INTEGER NOT NULL,
+	attempt INTEGER NOT NULL,
+	status_code INTEGER NOT NULL,
+	error TEXT NOT NULL,
+	attempted_ts TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_attempts_delivery_id ON WebhookAttempts (delivery_id);
+
+CREATE TABLE IF NOT EXISTS APIKeys (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	name TEXT NOT NULL,
+	role TEXT NOT NULL,
+	prefix TEXT NOT NULL UNIQUE,
+	hash BLOB NOT NULL,
+	created_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	revoked_ts TIMESTAMP
+);
@@ -0,0 +1,6 @@
+-- Of each set of survivors registered twice under one id number, only the
+-- last registered is kept, since it holds the resources that were saved to
+-- the Inventory table last.
+DELETE FROM Survivors WHERE id NOT IN (SELECT max(id) FROM Survivors GROUP BY id_number);
+
+CREATE UNIQUE INDEX IF NOT EXISTS survivors_id_number ON Survivors (id_number);
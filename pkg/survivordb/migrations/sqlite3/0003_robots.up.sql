@@ -0,0 +1,10 @@
+-- The robots of the robot CPU system, kept so that they outlive its outages.
+CREATE TABLE IF NOT EXISTS Robots (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	serial_number TEXT NOT NULL UNIQUE,
+	model TEXT NOT NULL,
+	manufactured_date TIMESTAMP NOT NULL,
+	category TEXT NOT NULL,
+	first_seen_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	last_seen_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
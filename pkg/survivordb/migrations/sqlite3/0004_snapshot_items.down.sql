@@ -0,0 +1,21 @@
+CREATE TABLE StatsSnapshotsCopy (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	healthy INTEGER NOT NULL,
+	infected INTEGER NOT NULL,
+	water REAL NOT NULL,
+	food REAL NOT NULL,
+	medication REAL NOT NULL,
+	ammunition REAL NOT NULL,
+	recorded_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+INSERT INTO StatsSnapshotsCopy (id, healthy, infected, water, food, medication, ammunition, recorded_ts)
+	SELECT s.id, s.healthy, s.infected, s.water,
+		(SELECT coalesce(sum(quantity), 0) FROM StatsSnapshotItems i WHERE i.snapshot_id = s.id AND i.item_type = 'food'),
+		(SELECT coalesce(sum(quantity), 0) FROM StatsSnapshotItems i WHERE i.snapshot_id = s.id AND i.item_type = 'medication'),
+		s.ammunition, s.recorded_ts FROM StatsSnapshots s;
+DROP TABLE StatsSnapshots;
+ALTER TABLE StatsSnapshotsCopy RENAME TO StatsSnapshots;
+
+CREATE INDEX IF NOT EXISTS stats_snapshots_recorded_ts ON StatsSnapshots (recorded_ts);
+
+DROP TABLE IF EXISTS StatsSnapshotItems;
@@ -0,0 +1,28 @@
+-- The food and medication of a snapshot are totalled by item and unit, as
+-- the quantities of different items in different units do not add up.
+CREATE TABLE IF NOT EXISTS StatsSnapshotItems (
+	snapshot_id INTEGER NOT NULL,
+	item_type TEXT NOT NULL,
+	item_name TEXT NOT NULL,
+	quantity REAL NOT NULL,
+	unit TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS stats_snapshot_items_snapshot_id ON StatsSnapshotItems (snapshot_id);
+
+-- SQLite cannot drop columns, so the food and medication totals are dropped
+-- by copying the snapshots into a table without them.
+CREATE TABLE StatsSnapshotsCopy (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	healthy INTEGER NOT NULL,
+	infected INTEGER NOT NULL,
+	water REAL NOT NULL,
+	ammunition REAL NOT NULL,
+	recorded_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+INSERT INTO StatsSnapshotsCopy (id, healthy, infected, water, ammunition, recorded_ts)
+	SELECT id, healthy, infected, water, ammunition, recorded_ts FROM StatsSnapshots;
+DROP TABLE StatsSnapshots;
+ALTER TABLE StatsSnapshotsCopy RENAME TO StatsSnapshots;
+
+CREATE INDEX IF NOT EXISTS stats_snapshots_recorded_ts ON StatsSnapshots (recorded_ts);
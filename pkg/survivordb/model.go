@@ -23,13 +23,15 @@ type LastLocation struct {
 	// the gps longitude
 	//
 	// required: true
-	// max length: 255
+	// minimum: -180
+	// maximum: 180
 	Longitude float64 `json:"longitude"`
 
 	// the gps latitude
 	//
 	// required: true
-	// max length: 255
+	// minimum: -90
+	// maximum: 90
 	Latitude float64 `json:"latitude"`
 }
 
@@ -39,7 +41,7 @@ type Resources struct {
 	// the water the survivor currently has
 	//
 	// required: true
-	// max length: 255
+	// minimum: 0
 	Water float64 `json:"water"`
 
 	// the food the survivor currently has
@@ -57,8 +59,14 @@ type Resources struct {
 	// the ammunition the survivor currently has
 	//
 	// required: true
-	// max length: 255
+	// minimum: 0
 	Ammunition int `json:"ammunition"`
+
+	// the itemised resources the survivor currently has. When given it
+	// takes precedence over the water, food, medication and ammunition fields
+	//
+	// required: false
+	Inventory []InventoryItem `json:"inventory,omitempty"`
 }
 
 // Survivor defines the structure for a survivor
@@ -73,7 +81,8 @@ type Survivor struct {
 	// the age for this survivor
 	//
 	// required: true
-	// max length: 3
+	// minimum: 0
+	// maximum: 150
 	Age int `json:"age"`
 
 	// the gender for this survivor
@@ -82,7 +91,7 @@ type Survivor struct {
 	// max length: 16
 	Gender string `json:"gender"`
 
-	// the id number for this survivor
+	// the id number for this survivor, which may not contain /, ? or #
 	//
 	// required: true
 	// max length: 30
@@ -121,6 +130,14 @@ type survivorResponseWrapper struct {
 	Body Survivor
 }
 
+// The fields of the payload that failed validation
+// swagger:response validationErrorResponse
+type validationErrorResponseWrapper struct {
+	// Every invalid field with the reason it is invalid
+	// in: body
+	Body ValidationError
+}
+
 // No content is returned by this API endpoint
 // swagger:response noContentResponse
 type noContentResponseWrapper struct {
@@ -135,16 +152,34 @@ type survivorParamsWrapper struct {
 	Body Survivor
 }
 
+// swagger:parameters patchSurvivor
+type survivorPatchParamsWrapper struct {
+	// The fields of the survivor to change
+	// in: body
+	// required: true
+	Body SurvivorPatch
+}
+
 // swagger:parameters setInfected
 type survivorIDParamsWrapper struct {
 	// The id of the survivor for which the operation relates
+	// and the id of the survivor reporting the infection
 	// in: body
 	// required: true
 	Payload struct {
-		IdNumber int `json:"id"`
+		IdNumber int    `json:"id"`
+		Reporter string `json:"reporter"`
 	}
 }
 
+// Data structure representing the infection reports against a survivor
+// swagger:response infectionReportResponse
+type infectionReportResponseWrapper struct {
+	// Current report count and infection status
+	// in: body
+	Body InfectionReport
+}
+
 // swagger:parameters updateLocation
 type survivorLocationParamsWrapper struct {
 	// The id of the survivor for which the operation relates
@@ -167,13 +202,152 @@ type survivorResourceParamsWrapper struct {
 	}
 }
 
+// swagger:parameters createTrade
+type tradeParamsWrapper struct {
+	// The resources each survivor gives up
+	// in: body
+	// required: true
+	Body Trade
+}
+
+// A time ordered list of locations
+// swagger:response locationsResponse
+type locationsResponseWrapper struct {
+	// The locations of the survivor
+	// in: body
+	Body []LocationRecord
+}
+
+// A list of survivors near a point, nearest first
+// swagger:response nearbyResponse
+type nearbyResponseWrapper struct {
+	// The survivors and their distance in km
+	// in: body
+	Body []NearbySurvivor
+}
+
+// A list of safe zones
+// swagger:response zonesResponse
+type zonesResponseWrapper struct {
+	// All safe zones
+	// in: body
+	Body []Zone
+}
+
+// Data structure representing a single safe zone
+// swagger:response zoneResponse
+type zoneResponseWrapper struct {
+	// The safe zone
+	// in: body
+	Body Zone
+}
+
+// A list of safe zone enter and exit events
+// swagger:response zoneEventsResponse
+type zoneEventsResponseWrapper struct {
+	// The events in time order
+	// in: body
+	Body []ZoneEvent
+}
+
+// swagger:parameters createZone updateZone
+type zoneParamsWrapper struct {
+	// Safe zone, either a circle with a center and radius in km or a polygon
+	// in: body
+	// required: true
+	Body Zone
+}
+
 // Data structure representing infected survivor stats
 // swagger:response statsResponse
 type survivorStatsResponseWrapper struct {
 	// Newly created survivor
 	// in: body
 	Stats struct {
-		HealthyPercentage  float64 `json:"healthyPercentage"`
-		InfectedPercentage float64 `json:"infectedPercentage"`
+		HealthyPercentage  float64         `json:"healthyPercentage"`
+		InfectedPercentage float64         `json:"infectedPercentage"`
+		Inventory          []InventoryItem `json:"inventory"`
 	}
 }
+
+// Data structure representing detailed survivor stats
+// swagger:response detailedStatsResponse
+type detailedStatsResponseWrapper struct {
+	// Averages, lost resources and breakdowns by gender and age band
+	// in: body
+	Body DetailedStats
+}
+
+// A time ordered list of stats snapshots
+// swagger:response statsHistoryResponse
+type statsHistoryResponseWrapper struct {
+	// The snapshots, one per bucket when a bucket is given
+	// in: body
+	Body []StatsSnapshot
+}
+
+// A list of changes made to survivors
+// swagger:response auditResponse
+type auditResponseWrapper struct {
+	// The audit events in the order they happened
+	// in: body
+	Body []AuditEvent
+}
+
+// A list of webhooks
+// swagger:response webhooksResponse
+type webhooksResponseWrapper struct {
+	// The webhooks without their secrets
+	// in: body
+	Body []Webhook
+}
+
+// A webhook
+// swagger:response webhookResponse
+type webhookResponseWrapper struct {
+	// The webhook, with its secret when it was just created
+	// in: body
+	Body Webhook
+}
+
+// A list of webhook deliveries
+// swagger:response webhookDeliveriesResponse
+type webhookDeliveriesResponseWrapper struct {
+	// The deliveries, newest first, with their attempts
+	// in: body
+	Body []WebhookDelivery
+}
+
+// swagger:parameters createWebhook updateWebhook
+type webhookParamsWrapper struct {
+	// Webhook url, the event types to send and an optional secret
+	// in: body
+	Body Webhook
+}
+
+// A stream of survivor events in the Server-Sent Events format, e.g.
+//
+//	id: 42
+//	event: location.updated
+//	data: {"eventId": 42, "type": "location.updated", ...}
+//
+// swagger:response eventsResponse
+type eventsResponseWrapper struct {
+	// in: body
+	Body SurvivorEvent
+}
+
+// The outcome of a survivor import
+// swagger:response importResponse
+type importResponseWrapper struct {
+	// How many survivors were imported and the rows that could not be
+	// in: body
+	Body ImportResult
+}
+
+// Every survivor in CSV, with a header row, or in newline delimited JSON
+// swagger:response exportResponse
+type exportResponseWrapper struct {
+	// in: body
+	Body []Survivor
+}
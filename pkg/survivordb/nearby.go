@@ -0,0 +1,125 @@
+package survivordb
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+// earthRadiusKm mean radius of the earth used by the haversine formula
+const earthRadiusKm = 6371.0
+
+// NearbySurvivor defines a survivor and their distance from a point
+// swagger:model
+type NearbySurvivor struct {
+	Survivor
+
+	// the distance of the survivor from the point searched, in km
+	//
+	// required: true
+	Distance float64 `json:"distance"`
+}
+
+const (
+	selectNearbySQL = `SELECT name, age, gender, id_number, longitude, latitude, water, food, medication, ammunition, infected, last_ts FROM Survivors
+	WHERE CAST(latitude AS REAL) BETWEEN ? AND ? AND CAST(longitude AS REAL) BETWEEN ? AND ? AND (? IS NULL OR infected = ?);`
+)
+
+// Distance returns the great circle distance in km between two points using
+// the haversine formula
+func Distance(latitude1, longitude1, latitude2, longitude2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+	dLatitude := toRadians(latitude2 - latitude1)
+	dLongitude := toRadians(longitude2 - longitude1)
+	h := math.Sin(dLatitude/2)*math.Sin(dLatitude/2) +
+		math.Cos(toRadians(latitude1))*math.Cos(toRadians(latitude2))*math.Sin(dLongitude/2)*math.Sin(dLongitude/2)
+
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// boundingBox returns the latitude and longitude ranges that contain every
+// point within radius km of a point
+func boundingBox(latitude, longitude, radius float64) (minLatitude, maxLatitude, minLongitude, maxLongitude float64) {
+	dLatitude := radius / earthRadiusKm * 180 / math.Pi
+	minLatitude = math.Max(-90, latitude-dLatitude)
+	maxLatitude = math.Min(90, latitude+dLatitude)
+
+	minLongitude, maxLongitude = -180, 180
+	cosLatitude := math.Cos(latitude * math.Pi / 180)
+	if maxLatitude < 90 && minLatitude > -90 && cosLatitude > 0 {
+		dLongitude := dLatitude / cosLatitude
+		if longitude-dLongitude >= -180 && longitude+dLongitude <= 180 {
+			minLongitude, maxLongitude = longitude-dLongitude, longitude+dLongitude
+		}
+	}
+
+	return minLatitude, maxLatitude, minLongitude, maxLongitude
+}
+
+// GetNearbySurvivors selects the survivors within radius km of a point,
+// nearest first. When infected is not nil only survivors with that infection
+// status are returned
+func (s *SurvivorDB) GetNearbySurvivors(latitude, longitude, radius float64, infected *bool) []NearbySurvivor {
+	minLatitude, maxLatitude, minLongitude, maxLongitude := boundingBox(latitude, longitude, radius)
+	var infectedParam sql.NullBool
+	if infected != nil {
+		infectedParam = sql.NullBool{Bool: *infected, Valid: true}
+	}
+
+	rows, err := s.selectNearbyStmt.Query(minLatitude, maxLatitude, minLongitude, maxLongitude, infectedParam, infectedParam)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectNearbySQL,
+		}).Info("Sql error")
+		return nil
+	}
+	defer rows.Close()
+
+	survivors := []Survivor{}
+	for rows.Next() {
+		survivor := Survivor{}
+		err = rows.Scan(&survivor.Name,
+			&survivor.Age,
+			&survivor.Gender,
+			&survivor.IdNumber,
+			&survivor.Longitude,
+			&survivor.Latitude,
+			&survivor.Water,
+			&survivor.Food,
+			&survivor.Medication,
+			&survivor.Ammunition,
+			&survivor.Infected,
+			&survivor.LastUpdateTime)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectNearbySQL,
+			}).Info("Sql error")
+			return nil
+		}
+		survivors = append(survivors, survivor)
+	}
+	if err = rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectNearbySQL,
+		}).Info("Sql error")
+		return nil
+	}
+
+	nearby := []NearbySurvivor{}
+	for _, survivor := range s.attachInventory(survivors) {
+		distance := Distance(latitude, longitude, survivor.Latitude, survivor.Longitude)
+		if distance <= radius {
+			nearby = append(nearby, NearbySurvivor{Survivor: survivor, Distance: distance})
+		}
+	}
+	sort.SliceStable(nearby, func(i, j int) bool {
+		return nearby[i].Distance < nearby[j].Distance
+	})
+
+	return nearby
+}
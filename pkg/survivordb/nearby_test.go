@@ -0,0 +1,72 @@
+package survivordb
+
+import (
+	"math"
+	"os"
+	"testing"
+)
+
+// TestDistance checks the haversine distance between points
+func TestDistance(t *testing.T) {
+	testCases := []struct {
+		latitude1, longitude1, latitude2, longitude2 float64
+		want                                         float64
+	}{
+		{0, 0, 0, 0, 0},
+		{0, 0, 1, 0, 111.19},
+		{0, 179.5, 0, -179.5, 111.19},
+		{-17.8292, 31.0522, -20.1325, 28.6265, 361.8},
+	}
+
+	for _, tc := range testCases {
+		got := Distance(tc.latitude1, tc.longitude1, tc.latitude2, tc.longitude2)
+		if math.Abs(got-tc.want) > 0.5 {
+			t.Errorf("Distance(%v, %v, %v, %v): want: %v, got: %v", tc.latitude1, tc.longitude1, tc.latitude2, tc.longitude2, tc.want, got)
+		}
+	}
+}
+
+// TestSurvivorDB_GetNearbySurvivors checks if searching survivors near a point works
+func TestSurvivorDB_GetNearbySurvivors(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	survivors := []*Survivor{
+		{Name: "Far", IdNumber: "HD138VOP34219", LastLocation: LastLocation{Latitude: 0.1}},
+		{Name: "Near", IdNumber: "HD138VOP34220", LastLocation: LastLocation{Latitude: 0.01}},
+		{Name: "Here", IdNumber: "HD138VOP34221", LastLocation: LastLocation{Latitude: 0, Longitude: 0}},
+		{Name: "Infected", IdNumber: "HD138VOP34222", LastLocation: LastLocation{Latitude: 0.02}, Infected: true},
+	}
+	for _, survivor := range survivors {
+		err = survivordb.Save(survivor)
+		if err != nil {
+			t.Errorf("SurvivorDB.Save() - %q: want: %v, got: %v", survivor.Name, nil, err)
+		}
+	}
+
+	nearby := survivordb.GetNearbySurvivors(0, 0, 5, nil)
+	want := []string{"Here", "Near", "Infected"}
+	if len(nearby) != len(want) {
+		t.Errorf("SurvivorDB.GetNearbySurvivors(): want: %v, got: %v", want, nearby)
+		return
+	}
+	for i := range want {
+		if nearby[i].Name != want[i] {
+			t.Errorf("SurvivorDB.GetNearbySurvivors()[%d]: want: %v, got: %v", i, want[i], nearby[i].Name)
+		}
+	}
+	if math.Abs(nearby[1].Distance-1.11) > 0.01 {
+		t.Errorf("SurvivorDB.GetNearbySurvivors()[1].Distance: want: %v, got: %v", 1.11, nearby[1].Distance)
+	}
+
+	healthy := false
+	nearby = survivordb.GetNearbySurvivors(0, 0, 5, &healthy)
+	if len(nearby) != 2 {
+		t.Errorf("SurvivorDB.GetNearbySurvivors(infected=false): want: %v, got: %v", 2, len(nearby))
+	}
+}
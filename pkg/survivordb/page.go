@@ -0,0 +1,211 @@
+package survivordb
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInvalidQuery is returned when a survivor query has an unknown sort column
+var ErrInvalidQuery = errors.New("invalid survivor query")
+
+// sortColumns maps the sort keys a client may use to Survivors columns
+var sortColumns = map[string]string{
+	"":        "id",
+	"name":    "name",
+	"age":     "age",
+	"last_ts": "last_ts",
+}
+
+// cursorTimeFormat formats the last_ts of a cursor as a query parameter. It
+// keeps the microseconds of PostgreSQL timestamps, which SQLite does not store
+const cursorTimeFormat = "2006-01-02 15:04:05.999999"
+
+// pageCursor holds the sort value and row id of the last survivor of a page,
+// so that the next page starts after them even once that survivor is deleted
+// or changed
+type pageCursor struct {
+	Sort   string     `json:"sort"`
+	Name   string     `json:"name,omitempty"`
+	Age    int        `json:"age,omitempty"`
+	LastTS *time.Time `json:"lastTs,omitempty"`
+	ID     int64      `json:"id"`
+}
+
+// newPageCursor returns the opaque cursor of the page that ends with survivor,
+// stored with row id id
+func newPageCursor(sort string, survivor *Survivor, id int64) string {
+	cursor := pageCursor{Sort: sort, ID: id}
+	switch sortColumns[sort] {
+	case "name":
+		cursor.Name = survivor.Name
+	case "age":
+		cursor.Age = survivor.Age
+	case "last_ts":
+		lastTS := survivor.LastUpdateTime.UTC()
+		cursor.LastTS = &lastTS
+	}
+	data, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// parsePageCursor reads a cursor returned with a page sorted by sort. It
+// returns ErrInvalidQuery when the cursor is malformed or from another sort
+func parsePageCursor(sort, value string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidQuery
+	}
+	cursor := &pageCursor{}
+	if err = json.Unmarshal(data, cursor); err != nil || cursor.Sort != sort || cursor.ID <= 0 {
+		return nil, ErrInvalidQuery
+	}
+	if sortColumns[sort] == "last_ts" && cursor.LastTS == nil {
+		return nil, ErrInvalidQuery
+	}
+
+	return cursor, nil
+}
+
+// SurvivorQuery defines a page of survivors to select
+type SurvivorQuery struct {
+	// Limit is the maximum number of survivors to return, 0 for all
+	Limit int
+	// Cursor is the opaque cursor returned with the previous page, empty for
+	// the first page
+	Cursor string
+	// Sort is the column to sort by: name, age or last_ts. Ties and the
+	// default order use the row id
+	Sort string
+	// Desc sorts in descending order
+	Desc bool
+	// Gender only selects survivors of this gender, ignoring case
+	Gender string
+	// MinAge and MaxAge only select survivors in this age range when non zero
+	MinAge int
+	MaxAge int
+	// Infected only selects survivors with this infection status when not nil
+	Infected *bool
+}
+
+// GetSurvivorsPage selects a page of survivors in keyset order. It returns
+// the cursor for the next page, or "" when this is the last page
+func (s *SurvivorDB) GetSurvivorsPage(q *SurvivorQuery) ([]Survivor, string, error) {
+	column, ok := sortColumns[q.Sort]
+	if !ok || q.Limit < 0 {
+		return nil, "", ErrInvalidQuery
+	}
+	direction, comparison := "ASC", ">"
+	if q.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	where := []string{}
+	args := []interface{}{}
+	if q.Cursor != "" {
+		cursor, err := parsePageCursor(q.Sort, q.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		switch column {
+		case "id":
+			where = append(where, fmt.Sprintf("id %s ?", comparison))
+		case "name":
+			where = append(where, fmt.Sprintf("(name, id) %s (?, ?)", comparison))
+			args = append(args, cursor.Name)
+		case "age":
+			where = append(where, fmt.Sprintf("(age, id) %s (?, ?)", comparison))
+			args = append(args, cursor.Age)
+		case "last_ts":
+			where = append(where, fmt.Sprintf("(last_ts, id) %s (?, ?)", comparison))
+			args = append(args, cursor.LastTS.UTC().Format(cursorTimeFormat))
+		}
+		args = append(args, cursor.ID)
+	}
+	if q.Gender != "" {
+		where = append(where, "lower(gender) = lower(?)")
+		args = append(args, q.Gender)
+	}
+	if q.MinAge > 0 {
+		where = append(where, "age >= ?")
+		args = append(args, q.MinAge)
+	}
+	if q.MaxAge > 0 {
+		where = append(where, "age <= ?")
+		args = append(args, q.MaxAge)
+	}
+	if q.Infected != nil {
+		where = append(where, "infected = ?")
+		args = append(args, *q.Infected)
+	}
+
+	query := `SELECT id, name, age, gender, id_number, longitude, latitude, water, food, medication, ammunition, infected, last_ts FROM Survivors`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s", column, direction, direction)
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit+1)
+	}
+
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   query,
+		}).Info("Sql error")
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	survivors := []Survivor{}
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		survivor := Survivor{}
+		err = rows.Scan(&id,
+			&survivor.Name,
+			&survivor.Age,
+			&survivor.Gender,
+			&survivor.IdNumber,
+			&survivor.Longitude,
+			&survivor.Latitude,
+			&survivor.Water,
+			&survivor.Food,
+			&survivor.Medication,
+			&survivor.Ammunition,
+			&survivor.Infected,
+			&survivor.LastUpdateTime)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   query,
+			}).Info("Sql error")
+			return nil, "", err
+		}
+		survivors = append(survivors, survivor)
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   query,
+		}).Info("Sql error")
+		return nil, "", err
+	}
+
+	next := ""
+	if q.Limit > 0 && len(survivors) > q.Limit {
+		survivors = survivors[:q.Limit]
+		next = newPageCursor(q.Sort, &survivors[q.Limit-1], ids[q.Limit-1])
+	}
+
+	return s.attachInventory(survivors), next, nil
+}
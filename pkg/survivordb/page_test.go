@@ -0,0 +1,82 @@
+package survivordb
+
+import (
+	"os"
+	"testing"
+)
+
+// TestSurvivorDB_GetSurvivorsPage checks if paging, sorting and filtering survivors works
+func TestSurvivorDB_GetSurvivorsPage(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	survivors := []*Survivor{
+		{Name: "Eve", Age: 30, Gender: "Female", IdNumber: "HD138VOP34219"},
+		{Name: "Bob", Age: 20, Gender: "Male", IdNumber: "HD138VOP34220"},
+		{Name: "Dan", Age: 40, Gender: "Male", IdNumber: "HD138VOP34221", Infected: true},
+		{Name: "Amy", Age: 20, Gender: "Female", IdNumber: "HD138VOP34222"},
+		{Name: "Cat", Age: 10, Gender: "Female", IdNumber: "HD138VOP34223"},
+	}
+	for _, survivor := range survivors {
+		if err = survivordb.Save(survivor); err != nil {
+			t.Errorf("SurvivorDB.Save() - %q: want: %v, got: %v", survivor.Name, nil, err)
+		}
+	}
+
+	healthy := false
+	testCases := []struct {
+		name  string
+		query SurvivorQuery
+		want  []string
+	}{
+		{name: "default", query: SurvivorQuery{}, want: []string{"Eve", "Bob", "Dan", "Amy", "Cat"}},
+		{name: "by name", query: SurvivorQuery{Sort: "name", Limit: 2}, want: []string{"Amy", "Bob", "Cat", "Dan", "Eve"}},
+		{name: "by age desc", query: SurvivorQuery{Sort: "age", Desc: true, Limit: 2}, want: []string{"Dan", "Eve", "Amy", "Bob", "Cat"}},
+		{name: "by age ties", query: SurvivorQuery{Sort: "age", Limit: 1}, want: []string{"Cat", "Bob", "Amy", "Eve", "Dan"}},
+		{name: "by last_ts", query: SurvivorQuery{Sort: "last_ts", Limit: 2}, want: []string{"Eve", "Bob", "Dan", "Amy", "Cat"}},
+		{name: "female", query: SurvivorQuery{Gender: "female", Limit: 2}, want: []string{"Eve", "Amy", "Cat"}},
+		{name: "age range", query: SurvivorQuery{MinAge: 20, MaxAge: 30, Sort: "name"}, want: []string{"Amy", "Bob", "Eve"}},
+		{name: "healthy", query: SurvivorQuery{Infected: &healthy, MinAge: 20}, want: []string{"Eve", "Bob", "Amy"}},
+	}
+
+	for _, tc := range testCases {
+		got := []string{}
+		q := tc.query
+		for page := 0; page < 10; page++ {
+			survivors, next, err := survivordb.GetSurvivorsPage(&q)
+			if err != nil {
+				t.Errorf("SurvivorDB.GetSurvivorsPage() - %q: want: %v, got: %v", tc.name, nil, err)
+				break
+			}
+			if q.Limit > 0 && len(survivors) > q.Limit {
+				t.Errorf("SurvivorDB.GetSurvivorsPage() - %q: want at most: %v, got: %v", tc.name, q.Limit, len(survivors))
+			}
+			for _, survivor := range survivors {
+				got = append(got, survivor.Name)
+			}
+			if next == "" {
+				break
+			}
+			q.Cursor = next
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("SurvivorDB.GetSurvivorsPage() - %q: want: %v, got: %v", tc.name, tc.want, got)
+			continue
+		}
+		for i := range tc.want {
+			if got[i] != tc.want[i] {
+				t.Errorf("SurvivorDB.GetSurvivorsPage() - %q: want: %v, got: %v", tc.name, tc.want, got)
+				break
+			}
+		}
+	}
+
+	if _, _, err = survivordb.GetSurvivorsPage(&SurvivorQuery{Sort: "id_number; DROP TABLE Survivors"}); err != ErrInvalidQuery {
+		t.Errorf("SurvivorDB.GetSurvivorsPage(): want: %v, got: %v", ErrInvalidQuery, err)
+	}
+}
@@ -0,0 +1,247 @@
+package survivordb
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SurvivorPatch defines a partial update of a survivor. Only the fields
+// that are not nil are changed
+// swagger:model
+type SurvivorPatch struct {
+	Name       *string          `json:"name,omitempty"`
+	Age        *int             `json:"age,omitempty"`
+	Gender     *string          `json:"gender,omitempty"`
+	IdNumber   *string          `json:"id,omitempty"`
+	Longitude  *float64         `json:"longitude,omitempty"`
+	Latitude   *float64         `json:"latitude,omitempty"`
+	Water      *float64         `json:"water,omitempty"`
+	Food       *string          `json:"food,omitempty"`
+	Medication *string          `json:"medication,omitempty"`
+	Ammunition *int             `json:"ammunition,omitempty"`
+	Inventory  *[]InventoryItem `json:"inventory,omitempty"`
+	Infected   *bool            `json:"infected,omitempty"`
+}
+
+// LocationChanged reports whether the patch moves the survivor
+func (p *SurvivorPatch) LocationChanged() bool {
+	return p.Longitude != nil || p.Latitude != nil
+}
+
+// Apply copies the fields of the patch that are set onto survivor
+func (p *SurvivorPatch) Apply(survivor *Survivor) {
+	if p.Name != nil {
+		survivor.Name = *p.Name
+	}
+	if p.Age != nil {
+		survivor.Age = *p.Age
+	}
+	if p.Gender != nil {
+		survivor.Gender = *p.Gender
+	}
+	if p.IdNumber != nil {
+		survivor.IdNumber = *p.IdNumber
+	}
+	if p.Longitude != nil {
+		survivor.Longitude = *p.Longitude
+	}
+	if p.Latitude != nil {
+		survivor.Latitude = *p.Latitude
+	}
+	if p.Infected != nil {
+		survivor.Infected = *p.Infected
+	}
+
+	if p.Inventory != nil {
+		survivor.SetItems(*p.Inventory)
+		return
+	}
+	if p.Water == nil && p.Food == nil && p.Medication == nil && p.Ammunition == nil {
+		return
+	}
+	if p.Water != nil {
+		survivor.Water = *p.Water
+	}
+	if p.Food != nil {
+		survivor.Food = *p.Food
+	}
+	if p.Medication != nil {
+		survivor.Medication = *p.Medication
+	}
+	if p.Ammunition != nil {
+		survivor.Ammunition = *p.Ammunition
+	}
+	survivor.Inventory = nil
+	survivor.SetItems(survivor.Items())
+}
+
+// survivorTables tables holding rows that belong to a survivor by id number
+var survivorTables = []struct {
+	table  string
+	column string
+}{
+	{"Inventory", "survivor_id"},
+	{"LocationHistory", "survivor_id"},
+	{"InfectionReports", "reporter_id"},
+	{"InfectionReports", "reported_id"},
+	{"ZoneOccupants", "survivor_id"},
+	{"ZoneEvents", "survivor_id"},
+}
+
+const (
+	updateSurvivorSQL = `UPDATE Survivors SET name = ?, age = ?, gender = ?, id_number = ?, longitude = ?, latitude = ?, water = ?, food = ?, medication = ?, ammunition = ?, infected = ?, last_ts = CURRENT_TIMESTAMP WHERE id_number = ?;`
+	deleteSurvivorSQL = `DELETE FROM Survivors WHERE id_number = ?;`
+)
+
+// PatchSurvivor applies a partial update to a survivor. Changing the id
+// number also moves every row that belongs to the survivor, and changing the
+// location checks it against the zones
+func (s *SurvivorDB) PatchSurvivor(idNumber string, patch *SurvivorPatch) (*Survivor, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	survivor, err := s.getSurvivorTx(tx, idNumber)
+	if err != nil {
+		return nil, err
+	}
+	before := survivor.clone()
+	patch.Apply(survivor)
+
+	if survivor.IdNumber != idNumber {
+		count, err := countTx(tx, s.countByIdNumberStmt, countByIdNumberSQL, survivor.IdNumber)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, ErrDuplicateIdNumber
+		}
+	}
+
+	_, err = tx.Exec(updateSurvivorSQL,
+		survivor.Name,
+		survivor.Age,
+		survivor.Gender,
+		survivor.IdNumber,
+		survivor.Longitude,
+		survivor.Latitude,
+		survivor.Water,
+		survivor.Food,
+		survivor.Medication,
+		survivor.Ammunition,
+		survivor.Infected,
+		idNumber)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   updateSurvivorSQL,
+		}).Info("Sql error")
+		return nil, err
+	}
+
+	if survivor.IdNumber != idNumber {
+		for _, t := range survivorTables {
+			query := fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?;", t.table, t.column, t.column)
+			if _, err = tx.Exec(query, survivor.IdNumber, idNumber); err != nil {
+				logrus.WithFields(logrus.Fields{
+					"Error": err,
+					"sql":   query,
+				}).Info("Sql error")
+				return nil, err
+			}
+		}
+	}
+	if patch.Inventory != nil || patch.Water != nil || patch.Food != nil || patch.Medication != nil || patch.Ammunition != nil {
+		if err = s.saveInventoryTx(tx, survivor.IdNumber, survivor.Inventory); err != nil {
+			return nil, err
+		}
+	}
+	if patch.LocationChanged() {
+		if err = s.insertLocationTx(tx, survivor.IdNumber, survivor.Longitude, survivor.Latitude); err != nil {
+			return nil, err
+		}
+		if _, err = s.moveTx(tx, survivor.IdNumber, survivor.Longitude, survivor.Latitude); err != nil {
+			return nil, err
+		}
+	}
+	if err = s.auditTx(tx, AuditPatch, survivor.IdNumber, before); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return nil, err
+	}
+
+	return s.GetSurvivor(survivor.IdNumber), nil
+}
+
+// DeleteSurvivor deletes a survivor and every row that belongs to them
+func (s *SurvivorDB) DeleteSurvivor(idNumber string) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := s.getSurvivorTx(tx, idNumber)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(deleteSurvivorSQL, idNumber)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   deleteSurvivorSQL,
+		}).Info("Sql error")
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrNotFound
+	}
+
+	if err = deleteSurvivorRowsTx(tx, idNumber); err != nil {
+		return err
+	}
+	if err = s.auditTx(tx, AuditDelete, idNumber, before); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+
+	return nil
+}
+
+// deleteSurvivorRowsTx deletes every row that belongs to a survivor inside a transaction
+func deleteSurvivorRowsTx(tx *sql.Tx, idNumber string) error {
+	for _, t := range survivorTables {
+		query := fmt.Sprintf("DELETE FROM %s WHERE %s = ?;", t.table, t.column)
+		if _, err := tx.Exec(query, idNumber); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   query,
+			}).Info("Sql error")
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package survivordb
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// TestSurvivorDB_PatchSurvivor checks if partially updating a survivor works
+func TestSurvivorDB_PatchSurvivor(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	for _, survivor := range []*Survivor{
+		{Name: "Jane Doe", Age: 1, Gender: "Female", IdNumber: "HD138VOP34219", Resources: Resources{Water: 2, Food: "Fish"}},
+		{Name: "John Doe", Age: 1, Gender: "Male", IdNumber: "HD138VOP34220"},
+	} {
+		if err = survivordb.Save(survivor); err != nil {
+			t.Errorf("SurvivorDB.Save() - %q: want: %v, got: %v", survivor.Name, nil, err)
+		}
+	}
+
+	name, age, food, idNumber, latitude := "Jane Smith", 31, "2 Fish, Rice", "HD138VOP34221", 5.0
+	patched, err := survivordb.PatchSurvivor("HD138VOP34219", &SurvivorPatch{
+		Name:     &name,
+		Age:      &age,
+		Food:     &food,
+		IdNumber: &idNumber,
+		Latitude: &latitude,
+	})
+	if err != nil || patched == nil {
+		t.Errorf("SurvivorDB.PatchSurvivor(): want: %v, got: %v", nil, err)
+		return
+	}
+	if patched.Name != name || patched.Age != age || patched.Gender != "Female" || patched.Water != 2 ||
+		patched.Food != food || patched.Latitude != latitude || len(patched.Inventory) != 3 {
+		t.Errorf("SurvivorDB.PatchSurvivor(): got: %v", patched)
+	}
+	if survivordb.GetSurvivor("HD138VOP34219") != nil {
+		t.Errorf("SurvivorDB.GetSurvivor() - %q: want: nil", "HD138VOP34219")
+	}
+	if locations := survivordb.GetLocationHistory(idNumber, time.Time{}, time.Time{}); len(locations) != 2 {
+		t.Errorf("SurvivorDB.GetLocationHistory() - %q: want: %v, got: %v", idNumber, 2, locations)
+	}
+
+	taken := "HD138VOP34220"
+	if _, err = survivordb.PatchSurvivor(idNumber, &SurvivorPatch{IdNumber: &taken}); err != ErrDuplicateIdNumber {
+		t.Errorf("SurvivorDB.PatchSurvivor(): want: %v, got: %v", ErrDuplicateIdNumber, err)
+	}
+	if _, err = survivordb.PatchSurvivor("UNKNOWN", &SurvivorPatch{Name: &name}); err != ErrNotFound {
+		t.Errorf("SurvivorDB.PatchSurvivor(): want: %v, got: %v", ErrNotFound, err)
+	}
+}
+
+// TestSurvivorDB_DeleteSurvivor checks if deleting a survivor removes their rows
+func TestSurvivorDB_DeleteSurvivor(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	survivor := &Survivor{Name: "Jane Doe", Age: 1, Gender: "Female", IdNumber: "HD138VOP34219", Resources: Resources{Water: 2}}
+	if err = survivordb.Save(survivor); err != nil {
+		t.Errorf("SurvivorDB.Save() - %q: want: %v, got: %v", survivor.Name, nil, err)
+	}
+
+	if err = survivordb.DeleteSurvivor(survivor.IdNumber); err != nil {
+		t.Errorf("SurvivorDB.DeleteSurvivor(): want: %v, got: %v", nil, err)
+	}
+	if survivordb.GetSurvivor(survivor.IdNumber) != nil || len(survivordb.GetInventory(survivor.IdNumber)) != 0 ||
+		len(survivordb.GetLocationHistory(survivor.IdNumber, time.Time{}, time.Time{})) != 0 {
+		t.Errorf("SurvivorDB.DeleteSurvivor(): survivor rows were not deleted")
+	}
+	if err = survivordb.DeleteSurvivor(survivor.IdNumber); err != ErrNotFound {
+		t.Errorf("SurvivorDB.DeleteSurvivor(): want: %v, got: %v", ErrNotFound, err)
+	}
+}
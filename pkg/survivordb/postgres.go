@@ -0,0 +1,149 @@
+package survivordb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// postgresConnector connects to PostgreSQL through lib/pq so that the
+// statements written for SQLite run unchanged where the dialects agree: it
+// rebinds ? placeholders to $n, binds booleans as the 0 and 1 stored in the
+// INTEGER flag columns and keeps sessions in UTC, so that CURRENT_TIMESTAMP
+// stores UTC like SQLite does
+type postgresConnector struct {
+	*pq.Connector
+}
+
+func (c postgresConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = conn.(driver.ExecerContext).ExecContext(ctx, "SET TIME ZONE 'UTC';", nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return postgresConn{conn}, nil
+}
+
+// openPostgres opens the PostgreSQL database of the connection string dsn
+func openPostgres(dsn string) (*sql.DB, error) {
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return sql.OpenDB(postgresConnector{connector}), nil
+}
+
+// postgresConn is a lib/pq connection that rebinds the statements it runs
+type postgresConn struct {
+	driver.Conn
+}
+
+func (c postgresConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(rebind(query))
+}
+
+// ExecContext runs statements without arguments through the simple query
+// protocol, so that a data definition may hold several statements
+func (c postgresConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, rebind(query), args)
+}
+
+func (c postgresConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, rebind(query), args)
+}
+
+func (c postgresConn) CheckNamedValue(nv *driver.NamedValue) error {
+	value, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	if flag, ok := value.(bool); ok {
+		value = int64(0)
+		if flag {
+			value = int64(1)
+		}
+	}
+	nv.Value = value
+
+	return nil
+}
+
+// rebind numbers the ? placeholders of query as $1, $2... leaving quoted
+// strings and identifiers alone
+func rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	var quote rune
+	for _, r := range query {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?':
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// dialect returns the statement to run for query on the database. The
+// statements are written for SQLite, with PostgreSQL versions of those where
+// the dialects differ
+func (s *SurvivorDB) dialect(query string) string {
+	if s.Driver == Postgres {
+		if postgresQuery, ok := postgresSQL[query]; ok {
+			return postgresQuery
+		}
+	}
+
+	return query
+}
+
+// postgresSnapshotBuckets are the snapshotBuckets of PostgreSQL. Weeks are
+// ISO weeks like those of SQLite
+var postgresSnapshotBuckets = map[string]string{
+	"":      "id",
+	"hour":  "to_char(recorded_ts, 'YYYY-MM-DD HH24')",
+	"day":   "to_char(recorded_ts, 'YYYY-MM-DD')",
+	"week":  "to_char(recorded_ts, 'IYYY-IW')",
+	"month": "to_char(recorded_ts, 'YYYY-MM')",
+}
+
+// postgresSQL maps the statements that PostgreSQL does not run as SQLite
+// does to their PostgreSQL versions. Inserts whose id is needed return it,
+// as PostgreSQL has no last insert id. The schema of PostgreSQL is in its
+// own migrations
+var postgresSQL = map[string]string{
+	selectNearbySQL: `SELECT name, age, gender, id_number, longitude, latitude, water, food, medication, ammunition, infected, last_ts FROM Survivors
+	WHERE CAST(latitude AS DOUBLE PRECISION) BETWEEN ? AND ? AND CAST(longitude AS DOUBLE PRECISION) BETWEEN ? AND ?
+	AND (CAST(? AS INTEGER) IS NULL OR infected = ?);`,
+	insertSnapshotSQL: `INSERT INTO StatsSnapshots (healthy, infected, water, ammunition)
+	SELECT
+		(SELECT count(*) FROM Survivors WHERE infected = 0),
+		(SELECT count(*) FROM Survivors WHERE infected = 1),
+		(SELECT coalesce(sum(water), 0) FROM Survivors),
+		(SELECT coalesce(sum(ammunition), 0) FROM Survivors)
+	RETURNING id;`,
+	insertSnapshotItemsSQL: `INSERT INTO StatsSnapshotItems (snapshot_id, item_type, item_name, quantity, unit)
+	SELECT CAST(? AS BIGINT), item_type, min(item_name), sum(quantity), unit FROM Inventory WHERE item_type IN ('food', 'medication')
+	GROUP BY item_type, lower(item_name), unit;`,
+	insertZoneSQL:    `INSERT INTO Zones (name, kind, latitude, longitude, radius, polygon) VALUES(?,?,?,?,?,?) RETURNING id;`,
+	insertWebhookSQL: `INSERT INTO Webhooks (url, secret, events, disabled) VALUES(?,?,?,?) RETURNING id;`,
+	insertAPIKeySQL:  `INSERT INTO APIKeys (name, role, prefix, hash) VALUES(?,?,?,?) RETURNING id;`,
+}
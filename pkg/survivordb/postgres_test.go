@@ -0,0 +1,63 @@
+package survivordb
+
+import (
+	"os"
+	"testing"
+)
+
+// postgresDSNEnv names the connection string of the PostgreSQL database the
+// tests run against. The database is emptied by every test
+const postgresDSNEnv = "APOCALYPSE_TEST_POSTGRES_DSN"
+
+// postgresRequiredEnv makes the PostgreSQL tests fail rather than skip when
+// postgresDSNEnv is not set, for runs that are meant to cover PostgreSQL
+const postgresRequiredEnv = "APOCALYPSE_TEST_POSTGRES_REQUIRED"
+
+// TestPostgres_Conformance checks if SurvivorDB on PostgreSQL behaves as a
+// SurvivorStore
+func TestPostgres_Conformance(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		if os.Getenv(postgresRequiredEnv) != "" {
+			t.Fatalf("%s is set but %s is not", postgresRequiredEnv, postgresDSNEnv)
+		}
+		t.Skipf("%s is not set", postgresDSNEnv)
+	}
+
+	storeConformance(t, func(t *testing.T) SurvivorStore {
+		survivordb := OpenDriver(Postgres, dsn)
+		if survivordb == nil {
+			t.Fatalf("OpenDriver(): Failed to open database")
+		}
+		t.Cleanup(func() {
+			survivordb.DB.Close()
+		})
+		_, err := survivordb.DB.Exec(`DROP TABLE IF EXISTS Survivors, InfectionReports, Inventory, LocationHistory,
+			Zones, ZoneOccupants, ZoneEvents, StatsSnapshots, AuditEvents, Webhooks, WebhookCursor,
+			WebhookDeliveries, WebhookAttempts, APIKeys, Robots, schema_version;`)
+		if err != nil {
+			t.Fatalf("DROP TABLE: want: %v, got: %v", nil, err)
+		}
+		if err = survivordb.Setup(); err != nil {
+			t.Fatalf("SurvivorDB.Setup(): Failed to setup database")
+		}
+
+		return survivordb
+	})
+}
+
+// TestRebind checks if ? placeholders are numbered outside of quotes
+func TestRebind(t *testing.T) {
+	for _, test := range []struct {
+		query string
+		want  string
+	}{
+		{`SELECT 1;`, `SELECT 1;`},
+		{`UPDATE t SET a = ?, b = ? WHERE c = ?;`, `UPDATE t SET a = $1, b = $2 WHERE c = $3;`},
+		{`SELECT '?', "a?b" FROM t WHERE a = ? AND b = 'it''s?';`, `SELECT '?', "a?b" FROM t WHERE a = $1 AND b = 'it''s?';`},
+	} {
+		if got := rebind(test.query); got != test.want {
+			t.Errorf("rebind() - %q: want: %q, got: %q", test.query, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package survivordb
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInvalidRobot is returned when saving a robot without a serial number
+var ErrInvalidRobot = errors.New("robot has no serial number")
+
+// Robot defines a robot of the robot CPU system and when it was polled
+// swagger:model
+type Robot struct {
+	// the model of the robot
+	Model string `json:"model"`
+
+	// the serial number the robot is kept under
+	//
+	// required: true
+	SerialNumber string `json:"serialNumber"`
+
+	// when the robot was manufactured
+	ManufacturedDate time.Time `json:"manufacturedDate"`
+
+	// Flying or Land
+	Category string `json:"category"`
+
+	// when the robot was first polled from the robot CPU system
+	FirstSeen time.Time `json:"firstSeen"`
+
+	// when the robot was last polled from the robot CPU system
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+type robotStmts struct {
+	upsertRobotStmt  *sql.Stmt
+	selectRobotsStmt *sql.Stmt
+}
+
+const (
+	upsertRobotSQL = `INSERT INTO Robots (serial_number, model, manufactured_date, category) VALUES(?,?,?,?)
+	ON CONFLICT (serial_number) DO UPDATE SET model = excluded.model, manufactured_date = excluded.manufactured_date,
+	category = excluded.category, last_seen_ts = CURRENT_TIMESTAMP;`
+	selectRobotsSQL = `SELECT serial_number, model, manufactured_date, category, first_seen_ts, last_seen_ts FROM Robots ORDER BY id;`
+)
+
+// setupRobots prepares the statements of the Robots table
+func (s *SurvivorDB) setupRobots() error {
+	var err error
+	if s.upsertRobotStmt, err = s.prepare(upsertRobotSQL); err != nil {
+		return err
+	}
+	if s.selectRobotsStmt, err = s.prepare(selectRobotsSQL); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveRobots inserts the robots into the Robots table in one transaction, or
+// updates those whose serial number is stored and marks them as seen now. It
+// returns ErrInvalidRobot, saving none, when a robot has no serial number
+func (s *SurvivorDB) SaveRobots(robots []Robot) error {
+	for _, robot := range robots {
+		if robot.SerialNumber == "" {
+			return ErrInvalidRobot
+		}
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := tx.Stmt(s.upsertRobotStmt)
+	for _, robot := range robots {
+		_, err = stmt.Exec(robot.SerialNumber, robot.Model, robot.ManufacturedDate.UTC().Format(sqliteTimeFormat), robot.Category)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   upsertRobotSQL,
+			}).Info("Sql error")
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+
+	return nil
+}
+
+// GetRobots selects all robots in the order they were first seen
+func (s *SurvivorDB) GetRobots() []Robot {
+	rows, err := s.selectRobotsStmt.Query()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectRobotsSQL,
+		}).Info("Sql error")
+		return nil
+	}
+	defer rows.Close()
+
+	robots := []Robot{}
+	for rows.Next() {
+		robot := Robot{}
+		err = rows.Scan(&robot.SerialNumber, &robot.Model, &robot.ManufacturedDate, &robot.Category, &robot.FirstSeen, &robot.LastSeen)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectRobotsSQL,
+			}).Info("Sql error")
+			return nil
+		}
+		robots = append(robots, robot)
+	}
+	if err = rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectRobotsSQL,
+		}).Info("Sql error")
+		return nil
+	}
+
+	return robots
+}
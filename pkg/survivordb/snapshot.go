@@ -0,0 +1,274 @@
+package survivordb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// StatsSnapshot defines the survivor counts and resource totals at a point in time
+// swagger:model
+type StatsSnapshot struct {
+	// the number of survivors that were not infected
+	//
+	// required: true
+	Healthy int `json:"healthy"`
+
+	// the number of infected survivors
+	//
+	// required: true
+	Infected int `json:"infected"`
+
+	// the percentage of survivors that were infected
+	//
+	// required: true
+	InfectedPercentage float64 `json:"infectedPercentage"`
+
+	// the total litres of water held by all survivors
+	//
+	// required: true
+	Water float64 `json:"water"`
+
+	// the total rounds of ammunition held by all survivors
+	//
+	// required: true
+	Ammunition float64 `json:"ammunition"`
+
+	// the food and medication held by all survivors, totalled by item and unit
+	//
+	// required: true
+	Items []InventoryItem `json:"items"`
+
+	// the time the snapshot was taken
+	//
+	// required: true
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// snapshotBuckets maps the bucket sizes a client may use to the expression
+// that groups the snapshots. An empty bucket returns every snapshot. Weeks
+// are ISO weeks, keyed by the year and day of the year of their Thursday, so
+// that the week spanning New Year is one bucket
+var snapshotBuckets = map[string]string{
+	"":      "id",
+	"hour":  "strftime('%Y-%m-%d %H', recorded_ts)",
+	"day":   "strftime('%Y-%m-%d', recorded_ts)",
+	"week":  "strftime('%Y-%j', recorded_ts, '-3 days', 'weekday 4')",
+	"month": "strftime('%Y-%m', recorded_ts)",
+}
+
+type statsSnapshotStmts struct {
+	insertSnapshotStmt      *sql.Stmt
+	insertSnapshotItemsStmt *sql.Stmt
+}
+
+// The water and ammunition totals come from the Survivors table, which holds
+// them in litres and rounds whatever unit the inventory uses
+const (
+	insertSnapshotSQL = `INSERT INTO StatsSnapshots (healthy, infected, water, ammunition)
+	SELECT
+		(SELECT count(*) FROM Survivors WHERE infected = 0),
+		(SELECT count(*) FROM Survivors WHERE infected = 1),
+		(SELECT coalesce(sum(water), 0) FROM Survivors),
+		(SELECT coalesce(sum(ammunition), 0) FROM Survivors);`
+	insertSnapshotItemsSQL = `INSERT INTO StatsSnapshotItems (snapshot_id, item_type, item_name, quantity, unit)
+	SELECT ?, item_type, min(item_name), sum(quantity), unit FROM Inventory WHERE item_type IN ('food', 'medication')
+	GROUP BY item_type, lower(item_name), unit;`
+	selectSnapshotsSQL = `SELECT id, healthy, infected, water, ammunition, recorded_ts FROM StatsSnapshots
+	WHERE id IN (SELECT max(id) FROM StatsSnapshots WHERE recorded_ts >= ? AND recorded_ts <= ? GROUP BY %s)
+	ORDER BY recorded_ts, id;`
+	selectSnapshotItemsSQL = `SELECT snapshot_id, item_type, item_name, quantity, unit FROM StatsSnapshotItems
+	WHERE snapshot_id IN (SELECT max(id) FROM StatsSnapshots WHERE recorded_ts >= ? AND recorded_ts <= ? GROUP BY %s)
+	ORDER BY snapshot_id, item_type, lower(item_name), unit;`
+)
+
+// setupStatsSnapshots prepares the statements of the StatsSnapshots table
+func (s *SurvivorDB) setupStatsSnapshots() error {
+	var err error
+	if s.insertSnapshotStmt, err = s.prepare(insertSnapshotSQL); err != nil {
+		return err
+	}
+	if s.insertSnapshotItemsStmt, err = s.prepare(insertSnapshotItemsSQL); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveStatsSnapshot stores the current survivor counts and resource totals
+// in the StatsSnapshots table and the food and medication totals in the
+// StatsSnapshotItems table
+func (s *SurvivorDB) SaveStatsSnapshot() error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+	defer tx.Rollback()
+
+	id, err := s.insert(tx.Stmt(s.insertSnapshotStmt))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   insertSnapshotSQL,
+		}).Info("Sql error")
+		return err
+	}
+	if _, err = tx.Stmt(s.insertSnapshotItemsStmt).Exec(id); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   insertSnapshotItemsSQL,
+		}).Info("Sql error")
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+
+	return nil
+}
+
+// GetStatsHistory selects the snapshots taken between from and to. With a
+// bucket of hour, day, week or month only the last snapshot of each bucket
+// is returned. A zero from or to leaves that end of the range open
+func (s *SurvivorDB) GetStatsHistory(from, to time.Time, bucket string) ([]StatsSnapshot, error) {
+	buckets := snapshotBuckets
+	if s.Driver == Postgres {
+		buckets = postgresSnapshotBuckets
+	}
+	group, ok := buckets[bucket]
+	if !ok {
+		return nil, ErrInvalidQuery
+	}
+
+	fromParam := minTimestamp
+	if !from.IsZero() {
+		fromParam = from.UTC().Format(sqliteTimeFormat)
+	}
+	toParam := maxTimestamp
+	if !to.IsZero() {
+		toParam = to.UTC().Format(sqliteTimeFormat)
+	}
+
+	query := fmt.Sprintf(selectSnapshotsSQL, group)
+	rows, err := s.DB.Query(query, fromParam, toParam)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   query,
+		}).Info("Sql error")
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int64{}
+	snapshots := []StatsSnapshot{}
+	for rows.Next() {
+		var id int64
+		snapshot := StatsSnapshot{Items: []InventoryItem{}}
+		err = rows.Scan(&id,
+			&snapshot.Healthy,
+			&snapshot.Infected,
+			&snapshot.Water,
+			&snapshot.Ammunition,
+			&snapshot.Timestamp)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   query,
+			}).Info("Sql error")
+			return nil, err
+		}
+		snapshot.InfectedPercentage = percentage(snapshot.Infected, snapshot.Healthy+snapshot.Infected)
+		ids = append(ids, id)
+		snapshots = append(snapshots, snapshot)
+	}
+	if err = rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   query,
+		}).Info("Sql error")
+		return nil, err
+	}
+
+	items, err := s.getSnapshotItems(group, fromParam, toParam)
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range ids {
+		if snapshotItems, ok := items[id]; ok {
+			snapshots[i].Items = snapshotItems
+		}
+	}
+
+	return snapshots, nil
+}
+
+// getSnapshotItems selects the food and medication totals of the snapshots
+// GetStatsHistory returns, by snapshot id
+func (s *SurvivorDB) getSnapshotItems(group, fromParam, toParam string) (map[int64][]InventoryItem, error) {
+	query := fmt.Sprintf(selectSnapshotItemsSQL, group)
+	rows, err := s.DB.Query(query, fromParam, toParam)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   query,
+		}).Info("Sql error")
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := map[int64][]InventoryItem{}
+	for rows.Next() {
+		var id int64
+		item := InventoryItem{}
+		err = rows.Scan(&id, &item.Type, &item.Name, &item.Quantity, &item.Unit)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   query,
+			}).Info("Sql error")
+			return nil, err
+		}
+		items[id] = append(items[id], item)
+	}
+	if err = rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   query,
+		}).Info("Sql error")
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// RunStatsSnapshots takes a snapshot of store straight away and then every
+// interval until ctx is done
+func RunStatsSnapshots(ctx context.Context, store SurvivorStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := store.SaveStatsSnapshot(); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+			}).Info("Error saving stats snapshot")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
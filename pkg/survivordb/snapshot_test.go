@@ -0,0 +1,121 @@
+package survivordb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestSurvivorDB_SaveStatsSnapshot checks if snapshots record the current counts and totals
+func TestSurvivorDB_SaveStatsSnapshot(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	for _, survivor := range []*Survivor{
+		{Name: "Jane Doe", Age: 1, Gender: "Female", IdNumber: "HD138VOP34219", Resources: Resources{Water: 2, Food: "2 kg Rice", Ammunition: 10}},
+		{Name: "John Doe", Age: 1, Gender: "Male", IdNumber: "HD138VOP34220", Resources: Resources{Water: 4, Food: "Fish, 1 kg rice"}, Infected: true},
+	} {
+		if err = survivordb.Save(survivor); err != nil {
+			t.Errorf("SurvivorDB.Save() - %q: want: %v, got: %v", survivor.Name, nil, err)
+		}
+	}
+
+	if err = survivordb.SaveStatsSnapshot(); err != nil {
+		t.Errorf("SurvivorDB.SaveStatsSnapshot(): want: %v, got: %v", nil, err)
+	}
+	snapshots, err := survivordb.GetStatsHistory(time.Time{}, time.Time{}, "")
+	if err != nil || len(snapshots) != 1 {
+		t.Errorf("SurvivorDB.GetStatsHistory(): want: %v, got: %v %v", 1, snapshots, err)
+		return
+	}
+	snapshot := snapshots[0]
+	if snapshot.Healthy != 1 || snapshot.Infected != 1 || snapshot.InfectedPercentage != 50 ||
+		snapshot.Water != 6 || snapshot.Ammunition != 10 || snapshot.Timestamp.IsZero() {
+		t.Errorf("SurvivorDB.GetStatsHistory(): got: %v", snapshot)
+	}
+	// food is totalled by item and unit
+	want := []InventoryItem{
+		{Type: ItemFood, Name: "Fish", Quantity: 1},
+		{Type: ItemFood, Name: "Rice", Quantity: 3, Unit: "kg"},
+	}
+	if len(snapshot.Items) != len(want) {
+		t.Errorf("SurvivorDB.GetStatsHistory(): want: %v, got: %v", want, snapshot.Items)
+	} else {
+		for i := range want {
+			if snapshot.Items[i] != want[i] {
+				t.Errorf("SurvivorDB.GetStatsHistory()[%d]: want: %v, got: %v", i, want[i], snapshot.Items[i])
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	RunStatsSnapshots(ctx, survivordb, time.Hour)
+	if snapshots, _ = survivordb.GetStatsHistory(time.Time{}, time.Time{}, ""); len(snapshots) != 2 {
+		t.Errorf("RunStatsSnapshots(): want: %v, got: %v", 2, len(snapshots))
+	}
+}
+
+// TestSurvivorDB_GetStatsHistory checks if snapshots are filtered by time and bucketed
+func TestSurvivorDB_GetStatsHistory(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	for i, recorded := range []string{
+		"2022-03-10 08:00:00",
+		"2022-03-10 20:00:00",
+		"2022-03-11 08:00:00",
+		"2022-03-12 08:00:00",
+		"2024-12-31 08:00:00",
+		"2025-01-02 08:00:00",
+	} {
+		_, err = survivordb.DB.Exec(`INSERT INTO StatsSnapshots (healthy, infected, water, ammunition, recorded_ts) VALUES(?,0,0,0,?);`, i, recorded)
+		if err != nil {
+			t.Errorf("SurvivorDB.DB.Exec(): want: %v, got: %v", nil, err)
+			return
+		}
+	}
+
+	from := time.Date(2022, 3, 10, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2022, 3, 11, 23, 59, 59, 0, time.UTC)
+	testCases := []struct {
+		from, to time.Time
+		bucket   string
+		want     []int // healthy counts, which identify the snapshots
+	}{
+		{time.Time{}, time.Time{}, "", []int{0, 1, 2, 3, 4, 5}},
+		{from, to, "", []int{0, 1, 2}},
+		{from, to, "day", []int{1, 2}},
+		{time.Time{}, time.Time{}, "month", []int{3, 4, 5}},
+		{time.Time{}, time.Time{}, "hour", []int{0, 1, 2, 3, 4, 5}},
+		// the ISO week that spans New Year is one bucket
+		{time.Time{}, time.Time{}, "week", []int{3, 5}},
+	}
+	for _, tc := range testCases {
+		snapshots, err := survivordb.GetStatsHistory(tc.from, tc.to, tc.bucket)
+		if err != nil || len(snapshots) != len(tc.want) {
+			t.Errorf("SurvivorDB.GetStatsHistory() - %q: want: %v, got: %v %v", tc.bucket, tc.want, snapshots, err)
+			continue
+		}
+		for i, healthy := range tc.want {
+			if snapshots[i].Healthy != healthy {
+				t.Errorf("SurvivorDB.GetStatsHistory() - %q: want: %v, got: %v", tc.bucket, healthy, snapshots[i].Healthy)
+			}
+		}
+	}
+
+	if _, err = survivordb.GetStatsHistory(time.Time{}, time.Time{}, "year"); err != ErrInvalidQuery {
+		t.Errorf("SurvivorDB.GetStatsHistory(): want: %v, got: %v", ErrInvalidQuery, err)
+	}
+}
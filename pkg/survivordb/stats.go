@@ -0,0 +1,292 @@
+package survivordb
+
+import (
+	"database/sql"
+
+	"github.com/sirupsen/logrus"
+)
+
+// GroupStats defines the survivor counts of one group of survivors
+// swagger:model
+type GroupStats struct {
+	// the gender or age band of the group
+	//
+	// required: true
+	Group string `json:"group"`
+
+	// the number of survivors in the group
+	//
+	// required: true
+	Survivors int `json:"survivors"`
+
+	// the number of infected survivors in the group
+	//
+	// required: true
+	Infected int `json:"infected"`
+
+	// the percentage of the group that is infected
+	//
+	// required: true
+	InfectedPercentage float64 `json:"infectedPercentage"`
+}
+
+// DetailedStats defines the statistics of all survivors
+// swagger:model
+type DetailedStats struct {
+	// the number of survivors
+	//
+	// required: true
+	Survivors int `json:"survivors"`
+
+	// the number of infected survivors
+	//
+	// required: true
+	Infected int `json:"infected"`
+
+	// the percentage of survivors that are not infected
+	//
+	// required: true
+	HealthyPercentage float64 `json:"healthyPercentage"`
+
+	// the percentage of survivors that are infected
+	//
+	// required: true
+	InfectedPercentage float64 `json:"infectedPercentage"`
+
+	// the average quantity of water per non infected survivor
+	//
+	// required: true
+	AverageWater float64 `json:"averageWater"`
+
+	// the average quantity of food per non infected survivor
+	//
+	// required: true
+	AverageFood float64 `json:"averageFood"`
+
+	// the average quantity of medication per non infected survivor
+	//
+	// required: true
+	AverageMedication float64 `json:"averageMedication"`
+
+	// the average rounds of ammunition per non infected survivor
+	//
+	// required: true
+	AverageAmmunition float64 `json:"averageAmmunition"`
+
+	// the resources held by infected survivors, which can no longer be traded
+	//
+	// required: true
+	LostResources []InventoryItem `json:"lostResources"`
+
+	// the survivor counts per gender
+	//
+	// required: true
+	ByGender []GroupStats `json:"byGender"`
+
+	// the survivor counts per age band
+	//
+	// required: true
+	ByAgeBand []GroupStats `json:"byAgeBand"`
+}
+
+type statsStmts struct {
+	countAllStmt      *sql.Stmt
+	healthyItemsStmt  *sql.Stmt
+	infectedItemsStmt *sql.Stmt
+	genderStatsStmt   *sql.Stmt
+	ageBandStatsStmt  *sql.Stmt
+}
+
+const (
+	countAllSQL      = `SELECT count(*), coalesce(sum(infected), 0) FROM Survivors;`
+	healthyItemsSQL  = `SELECT 'water', coalesce(sum(water), 0) FROM Survivors WHERE infected = 0 UNION ALL SELECT i.item_type, sum(i.quantity) FROM Inventory i JOIN Survivors s ON s.id_number = i.survivor_id WHERE s.infected = 0 AND i.item_type <> 'water' GROUP BY i.item_type;`
+	infectedItemsSQL = `SELECT i.item_type, min(i.item_name), sum(i.quantity), i.unit FROM Inventory i JOIN Survivors s ON s.id_number = i.survivor_id WHERE s.infected = 1 GROUP BY i.item_type, lower(i.item_name), i.unit ORDER BY i.item_type, lower(i.item_name);`
+	genderStatsSQL   = `SELECT lower(gender), count(*), coalesce(sum(infected), 0) FROM Survivors GROUP BY lower(gender) ORDER BY lower(gender);`
+	ageBandStatsSQL  = `SELECT CASE
+		WHEN age < 13 THEN '0-12'
+		WHEN age < 18 THEN '13-17'
+		WHEN age < 30 THEN '18-29'
+		WHEN age < 45 THEN '30-44'
+		WHEN age < 60 THEN '45-59'
+		ELSE '60+' END AS band, count(*), coalesce(sum(infected), 0)
+		FROM Survivors GROUP BY band ORDER BY min(age);`
+)
+
+// setupStats prepares the aggregate statistics statements
+func (s *SurvivorDB) setupStats() error {
+	var err error
+	if s.countAllStmt, err = s.prepare(countAllSQL); err != nil {
+		return err
+	}
+	if s.healthyItemsStmt, err = s.prepare(healthyItemsSQL); err != nil {
+		return err
+	}
+	if s.infectedItemsStmt, err = s.prepare(infectedItemsSQL); err != nil {
+		return err
+	}
+	if s.genderStatsStmt, err = s.prepare(genderStatsSQL); err != nil {
+		return err
+	}
+	if s.ageBandStatsStmt, err = s.prepare(ageBandStatsSQL); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// percentage returns part as a percentage of total, or 0 when total is 0
+func percentage(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(part) / float64(total) * 100
+}
+
+// GetDetailedStats computes the survivor statistics with aggregate queries
+func (s *SurvivorDB) GetDetailedStats() *DetailedStats {
+	stats := &DetailedStats{}
+	err := s.countAllStmt.QueryRow().Scan(&stats.Survivors, &stats.Infected)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   countAllSQL,
+		}).Info("Sql error")
+		return nil
+	}
+	healthy := stats.Survivors - stats.Infected
+	stats.HealthyPercentage = percentage(healthy, stats.Survivors)
+	stats.InfectedPercentage = percentage(stats.Infected, stats.Survivors)
+
+	if healthy > 0 && !s.averageItems(stats, healthy) {
+		return nil
+	}
+	if stats.LostResources = s.lostResources(); stats.LostResources == nil {
+		return nil
+	}
+	if stats.ByGender = s.groupStats(s.genderStatsStmt, genderStatsSQL); stats.ByGender == nil {
+		return nil
+	}
+	if stats.ByAgeBand = s.groupStats(s.ageBandStatsStmt, ageBandStatsSQL); stats.ByAgeBand == nil {
+		return nil
+	}
+
+	return stats
+}
+
+// averageItems sets the average quantity of each item type per non infected
+// survivor. Water is averaged in litres, from the water column
+func (s *SurvivorDB) averageItems(stats *DetailedStats, healthy int) bool {
+	rows, err := s.healthyItemsStmt.Query()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   healthyItemsSQL,
+		}).Info("Sql error")
+		return false
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var itemType string
+		var quantity float64
+		if err = rows.Scan(&itemType, &quantity); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   healthyItemsSQL,
+			}).Info("Sql error")
+			return false
+		}
+		average := quantity / float64(healthy)
+		switch itemType {
+		case ItemWater:
+			stats.AverageWater = average
+		case ItemFood:
+			stats.AverageFood = average
+		case ItemMedication:
+			stats.AverageMedication = average
+		case ItemAmmunition:
+			stats.AverageAmmunition = average
+		}
+	}
+	if err = rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   healthyItemsSQL,
+		}).Info("Sql error")
+		return false
+	}
+
+	return true
+}
+
+// lostResources sums the items held by infected survivors
+func (s *SurvivorDB) lostResources() []InventoryItem {
+	rows, err := s.infectedItemsStmt.Query()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   infectedItemsSQL,
+		}).Info("Sql error")
+		return nil
+	}
+	defer rows.Close()
+
+	items := []InventoryItem{}
+	for rows.Next() {
+		item := InventoryItem{}
+		if err = rows.Scan(&item.Type, &item.Name, &item.Quantity, &item.Unit); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   infectedItemsSQL,
+			}).Info("Sql error")
+			return nil
+		}
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   infectedItemsSQL,
+		}).Info("Sql error")
+		return nil
+	}
+
+	return items
+}
+
+// groupStats reads rows of group, survivor count and infected count
+func (s *SurvivorDB) groupStats(stmt *sql.Stmt, query string) []GroupStats {
+	rows, err := stmt.Query()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   query,
+		}).Info("Sql error")
+		return nil
+	}
+	defer rows.Close()
+
+	groups := []GroupStats{}
+	for rows.Next() {
+		group := GroupStats{}
+		if err = rows.Scan(&group.Group, &group.Survivors, &group.Infected); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   query,
+			}).Info("Sql error")
+			return nil
+		}
+		group.InfectedPercentage = percentage(group.Infected, group.Survivors)
+		groups = append(groups, group)
+	}
+	if err = rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   query,
+		}).Info("Sql error")
+		return nil
+	}
+
+	return groups
+}
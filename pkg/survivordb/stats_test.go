@@ -0,0 +1,78 @@
+package survivordb
+
+import (
+	"os"
+	"testing"
+)
+
+// TestSurvivorDB_GetDetailedStats checks if the aggregate statistics are computed
+func TestSurvivorDB_GetDetailedStats(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	stats := survivordb.GetDetailedStats()
+	if stats == nil || stats.Survivors != 0 || stats.AverageWater != 0 || len(stats.ByGender) != 0 {
+		t.Errorf("SurvivorDB.GetDetailedStats(): want: empty stats, got: %v", stats)
+		return
+	}
+
+	for _, survivor := range []*Survivor{
+		{Name: "Jane Doe", Age: 10, Gender: "Female", IdNumber: "HD138VOP34219", Resources: Resources{Water: 2, Ammunition: 10, Food: "Fish"}},
+		{Name: "John Doe", Age: 35, Gender: "Male", IdNumber: "HD138VOP34220", Resources: Resources{Water: 4, Ammunition: 20}},
+		{Name: "Jill Doe", Age: 40, Gender: "female", IdNumber: "HD138VOP34221", Resources: Resources{Water: 8, Food: "2 Fish"}, Infected: true},
+	} {
+		if err = survivordb.Save(survivor); err != nil {
+			t.Errorf("SurvivorDB.Save() - %q: want: %v, got: %v", survivor.Name, nil, err)
+		}
+	}
+
+	stats = survivordb.GetDetailedStats()
+	if stats == nil {
+		t.Errorf("SurvivorDB.GetDetailedStats(): want: stats, got: %v", stats)
+		return
+	}
+	if stats.Survivors != 3 || stats.Infected != 1 {
+		t.Errorf("SurvivorDB.GetDetailedStats(): want: %v/%v, got: %v/%v", 3, 1, stats.Survivors, stats.Infected)
+	}
+	if stats.AverageWater != 3 || stats.AverageAmmunition != 15 || stats.AverageFood != 0.5 {
+		t.Errorf("SurvivorDB.GetDetailedStats(): want averages: %v %v %v, got: %v %v %v", 3, 15, 0.5,
+			stats.AverageWater, stats.AverageAmmunition, stats.AverageFood)
+	}
+	if len(stats.LostResources) != 2 || stats.LostResources[0].Type != ItemFood || stats.LostResources[0].Quantity != 2 ||
+		stats.LostResources[1].Type != ItemWater || stats.LostResources[1].Quantity != 8 {
+		t.Errorf("SurvivorDB.GetDetailedStats(): lost resources: got: %v", stats.LostResources)
+	}
+
+	wantGender := []GroupStats{
+		{Group: "female", Survivors: 2, Infected: 1, InfectedPercentage: 50},
+		{Group: "male", Survivors: 1},
+	}
+	if len(stats.ByGender) != len(wantGender) {
+		t.Errorf("SurvivorDB.GetDetailedStats(): by gender: want: %v, got: %v", wantGender, stats.ByGender)
+	} else {
+		for i := range wantGender {
+			if stats.ByGender[i] != wantGender[i] {
+				t.Errorf("SurvivorDB.GetDetailedStats(): by gender: want: %v, got: %v", wantGender[i], stats.ByGender[i])
+			}
+		}
+	}
+
+	wantAgeBand := []GroupStats{
+		{Group: "0-12", Survivors: 1},
+		{Group: "30-44", Survivors: 2, Infected: 1, InfectedPercentage: 50},
+	}
+	if len(stats.ByAgeBand) != len(wantAgeBand) {
+		t.Errorf("SurvivorDB.GetDetailedStats(): by age band: want: %v, got: %v", wantAgeBand, stats.ByAgeBand)
+	} else {
+		for i := range wantAgeBand {
+			if stats.ByAgeBand[i] != wantAgeBand[i] {
+				t.Errorf("SurvivorDB.GetDetailedStats(): by age band: want: %v, got: %v", wantAgeBand[i], stats.ByAgeBand[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package survivordb
+
+import (
+	"time"
+)
+
+// SurvivorStore stores the survivors and everything recorded about them. The
+// handlers depend on it rather than on a database, so that SurvivorDB and
+// MemoryStore can be used in place of one another
+type SurvivorStore interface {
+	// WithActor returns a copy of the store that records actor in the audit
+	// events of the changes it makes
+	WithActor(actor Actor) SurvivorStore
+
+	// Survivors
+	Save(survivor *Survivor) error
+	ImportSurvivors(rows []ImportRow, dryRun bool) (*ImportResult, error)
+	UpdateLocation(idNumber string, longitude, latitude float64) ([]ZoneEvent, error)
+	UpdateResource(idNumber string, water float64, food, medication string, ammunition int) error
+	UpdateInventory(idNumber string, items []InventoryItem) error
+	UpdateInfected(idNumber string) error
+	ReportInfection(reporterIdNumber, reportedIdNumber string, threshold int) (*InfectionReport, error)
+	Trade(trade *Trade) ([]Survivor, error)
+	PatchSurvivor(idNumber string, patch *SurvivorPatch) (*Survivor, error)
+	DeleteSurvivor(idNumber string) error
+	GetAllSurvivors() []Survivor
+	GetSurvivors(infected bool) []Survivor
+	GetSurvivor(idNumber string) *Survivor
+	GetSurvivorsPage(q *SurvivorQuery) ([]Survivor, string, error)
+	GetNearbySurvivors(latitude, longitude, radius float64, infected *bool) []NearbySurvivor
+	GetInventory(idNumber string) []InventoryItem
+	GetLocationHistory(idNumber string, from, to time.Time) []LocationRecord
+	CountSurvivors(infected bool) int
+
+	// Statistics
+	InventoryTotals() []InventoryItem
+	GetDetailedStats() *DetailedStats
+	SaveStatsSnapshot() error
+	GetStatsHistory(from, to time.Time, bucket string) ([]StatsSnapshot, error)
+
+	// Audit events
+	GetAuditEvents(idNumber string, since time.Time, afterID int64, limit int) []AuditEvent
+	GetAuditEventsAfter(afterID int64, limit int) ([]AuditEvent, error)
+	LastAuditEventID() (int64, error)
+
+	// Safe zones
+	SaveZone(zone *Zone) error
+	UpdateZone(zone *Zone) error
+	DeleteZone(id int64) error
+	GetZones() []Zone
+	GetZone(id int64) *Zone
+	UpdateZoneOccupancy(idNumber string, longitude, latitude float64) ([]ZoneEvent, error)
+	GetZoneOccupants(id int64) []Survivor
+	GetZoneEvents(id int64) []ZoneEvent
+
+	// Webhooks
+	SaveWebhook(webhook *Webhook) error
+	UpdateWebhook(webhook *Webhook) error
+	DeleteWebhook(id int64) error
+	GetWebhooks() []Webhook
+	GetWebhook(id int64) *Webhook
+	EnqueueWebhookDeliveries(limit int) (int, error)
+	GetDueWebhookDeliveries(now time.Time, limit int) ([]WebhookDelivery, error)
+	RecordWebhookAttempt(deliveryID int64, attempt *WebhookAttempt, status string, next time.Time) error
+	GetWebhookDeliveries(webhookID int64, limit int) []WebhookDelivery
+
+	// API keys
+	SaveAPIKey(key *APIKey) error
+	RevokeAPIKey(id int64) error
+	GetAPIKeys() []APIKey
+	GetAPIKeyByPrefix(prefix string) *APIKey
+
+	// Robots
+	SaveRobots(robots []Robot) error
+	GetRobots() []Robot
+}
+
+// SurvivorDB and MemoryStore implement SurvivorStore
+var (
+	_ SurvivorStore = (*SurvivorDB)(nil)
+	_ SurvivorStore = (*MemoryStore)(nil)
+)
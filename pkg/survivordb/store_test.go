@@ -0,0 +1,716 @@
+package survivordb
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// storeConformance runs the behaviour every SurvivorStore must have against
+// the stores newStore returns, a new empty store per test
+func storeConformance(t *testing.T, newStore func(t *testing.T) SurvivorStore) {
+	for _, test := range []struct {
+		name string
+		run  func(t *testing.T, store SurvivorStore)
+	}{
+		{"Survivors", testStoreSurvivors},
+		{"ImportSurvivors", testStoreImportSurvivors},
+		{"ReportInfection", testStoreReportInfection},
+		{"Trade", testStoreTrade},
+		{"PatchSurvivor", testStorePatchSurvivor},
+		{"DeleteSurvivor", testStoreDeleteSurvivor},
+		{"GetSurvivorsPage", testStoreGetSurvivorsPage},
+		{"GetNearbySurvivors", testStoreGetNearbySurvivors},
+		{"Stats", testStoreStats},
+		{"AuditEvents", testStoreAuditEvents},
+		{"Zones", testStoreZones},
+		{"Webhooks", testStoreWebhooks},
+		{"APIKeys", testStoreAPIKeys},
+		{"Robots", testStoreRobots},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(t, newStore(t))
+		})
+	}
+}
+
+// TestSurvivorDB_Conformance checks if SurvivorDB behaves as a SurvivorStore
+func TestSurvivorDB_Conformance(t *testing.T) {
+	storeConformance(t, func(t *testing.T) SurvivorStore {
+		os.Remove("./test.db")
+		survivordb := Open("./test.db")
+		if err := survivordb.Setup(); err != nil {
+			t.Fatalf("SurvivorDB.Setup(): Failed to setup database")
+		}
+		t.Cleanup(func() {
+			survivordb.DB.Close()
+		})
+		return survivordb
+	})
+}
+
+// TestMemoryStore_Conformance checks if MemoryStore behaves as a SurvivorStore
+func TestMemoryStore_Conformance(t *testing.T) {
+	storeConformance(t, func(t *testing.T) SurvivorStore {
+		return NewMemoryStore()
+	})
+}
+
+// saveSurvivors saves survivors into store, failing the test on an error
+func saveSurvivors(t *testing.T, store SurvivorStore, survivors ...*Survivor) {
+	for _, survivor := range survivors {
+		if err := store.Save(survivor); err != nil {
+			t.Fatalf("SurvivorStore.Save() - %q: want: %v, got: %v", survivor.IdNumber, nil, err)
+		}
+	}
+}
+
+func testStoreSurvivors(t *testing.T, store SurvivorStore) {
+	saveSurvivors(t, store,
+		&Survivor{Name: "Jane Doe", Age: 30, Gender: "Female", IdNumber: "A1",
+			LastLocation: LastLocation{Longitude: 1, Latitude: 2},
+			Resources:    Resources{Water: 2, Food: "2 kg Rice, Fish", Ammunition: 5}},
+		&Survivor{Name: "John Doe", Age: 40, Gender: "Male", IdNumber: "A2"},
+	)
+	if err := store.Save(&Survivor{Name: "Jane Doe", IdNumber: "A1"}); err != ErrDuplicateIdNumber {
+		t.Errorf("SurvivorStore.Save(): want: %v, got: %v", ErrDuplicateIdNumber, err)
+	}
+
+	survivor := store.GetSurvivor("A1")
+	if survivor == nil || survivor.Name != "Jane Doe" || survivor.Age != 30 || survivor.Longitude != 1 ||
+		survivor.Latitude != 2 || survivor.Food != "2 kg Rice, Fish" || len(survivor.Inventory) != 4 ||
+		survivor.LastUpdateTime.IsZero() {
+		t.Errorf("SurvivorStore.GetSurvivor(): got: %+v", survivor)
+	}
+	if survivor := store.GetSurvivor("A3"); survivor != nil {
+		t.Errorf("SurvivorStore.GetSurvivor(): want: %v, got: %+v", nil, survivor)
+	}
+	if survivors := store.GetAllSurvivors(); len(survivors) != 2 || survivors[0].IdNumber != "A1" || survivors[1].IdNumber != "A2" {
+		t.Errorf("SurvivorStore.GetAllSurvivors(): got: %+v", survivors)
+	}
+
+	if _, err := store.UpdateLocation("A1", 3, 4); err != nil {
+		t.Errorf("SurvivorStore.UpdateLocation(): want: %v, got: %v", nil, err)
+	}
+	if locations := store.GetLocationHistory("A1", time.Time{}, time.Time{}); len(locations) != 2 ||
+		locations[1].Longitude != 3 || locations[1].Latitude != 4 {
+		t.Errorf("SurvivorStore.GetLocationHistory(): got: %+v", locations)
+	}
+	if locations := store.GetLocationHistory("A1", time.Now().Add(time.Hour), time.Time{}); len(locations) != 0 {
+		t.Errorf("SurvivorStore.GetLocationHistory(): want: %v, got: %+v", 0, locations)
+	}
+
+	if err := store.UpdateResource("A2", 1, "Bread", "Aspirin", 0); err != nil {
+		t.Errorf("SurvivorStore.UpdateResource(): want: %v, got: %v", nil, err)
+	}
+	if items := store.GetInventory("A2"); len(items) != 3 || items[1].Name != "Bread" || items[2].Type != ItemMedication {
+		t.Errorf("SurvivorStore.GetInventory(): got: %+v", items)
+	}
+	if err := store.UpdateInventory("A2", []InventoryItem{{Type: ItemWater, Name: ItemWater, Quantity: 3}}); err != nil {
+		t.Errorf("SurvivorStore.UpdateInventory(): want: %v, got: %v", nil, err)
+	}
+	if survivor := store.GetSurvivor("A2"); survivor == nil || survivor.Water != 3 || survivor.Food != "" || len(survivor.Inventory) != 1 {
+		t.Errorf("SurvivorStore.UpdateInventory(): got: %+v", survivor)
+	}
+
+	if err := store.UpdateInfected("A2"); err != nil {
+		t.Errorf("SurvivorStore.UpdateInfected(): want: %v, got: %v", nil, err)
+	}
+	if infected := store.GetSurvivors(true); len(infected) != 1 || infected[0].IdNumber != "A2" {
+		t.Errorf("SurvivorStore.GetSurvivors(): got: %+v", infected)
+	}
+	if store.CountSurvivors(true) != 1 || store.CountSurvivors(false) != 1 {
+		t.Errorf("SurvivorStore.CountSurvivors(): want: %v, got: %v %v", 1, store.CountSurvivors(true), store.CountSurvivors(false))
+	}
+
+	_, err := store.UpdateLocation("A3", 1, 1)
+	for name, err := range map[string]error{
+		"UpdateLocation":  err,
+		"UpdateResource":  store.UpdateResource("A3", 1, "", "", 0),
+		"UpdateInventory": store.UpdateInventory("A3", nil),
+		"UpdateInfected":  store.UpdateInfected("A3"),
+		"DeleteSurvivor":  store.DeleteSurvivor("A3"),
+	} {
+		if err != ErrNotFound {
+			t.Errorf("SurvivorStore.%s(): want: %v, got: %v", name, ErrNotFound, err)
+		}
+	}
+}
+
+func testStoreImportSurvivors(t *testing.T, store SurvivorStore) {
+	saveSurvivors(t, store, &Survivor{Name: "Jane Doe", Gender: "Female", IdNumber: "A1"})
+	rows := []ImportRow{
+		{Row: 1, Survivor: Survivor{Name: "John Doe", Gender: "Male", IdNumber: "A2", Resources: Resources{Water: 2}}},
+		{Row: 2, Survivor: Survivor{Name: "Jane Doe", Gender: "Female", IdNumber: "A1"}},
+		{Row: 3, Survivor: Survivor{Gender: "Male", IdNumber: "A3"}},
+		{Row: 4, Survivor: Survivor{Name: "John Doe", Gender: "Male", IdNumber: "A2"}},
+	}
+
+	result, err := store.ImportSurvivors(rows, false)
+	if err != nil || result.Rows != 4 || result.Imported != 0 || len(result.Errors) != 3 ||
+		result.Errors[0].Row != 2 || result.Errors[1].Row != 3 || result.Errors[1].Fields[0].Field != "name" ||
+		result.Errors[2].Row != 4 || result.Errors[2].IdNumber != "A2" {
+		t.Errorf("SurvivorStore.ImportSurvivors(): got: %+v %v", result, err)
+	}
+	if survivors := store.GetAllSurvivors(); len(survivors) != 1 {
+		t.Errorf("SurvivorStore.ImportSurvivors(): want: %v, got: %+v", 1, survivors)
+	}
+
+	result, err = store.ImportSurvivors(rows[:1], true)
+	if err != nil || !result.DryRun || result.Imported != 1 || len(result.Errors) != 0 {
+		t.Errorf("SurvivorStore.ImportSurvivors() - dry run: got: %+v %v", result, err)
+	}
+	if survivor := store.GetSurvivor("A2"); survivor != nil {
+		t.Errorf("SurvivorStore.ImportSurvivors() - dry run: want: %v, got: %+v", nil, survivor)
+	}
+
+	result, err = store.ImportSurvivors(rows[:1], false)
+	if err != nil || result.Imported != 1 || len(result.Errors) != 0 {
+		t.Errorf("SurvivorStore.ImportSurvivors(): got: %+v %v", result, err)
+	}
+	if survivor := store.GetSurvivor("A2"); survivor == nil || survivor.Water != 2 || len(survivor.Inventory) != 1 {
+		t.Errorf("SurvivorStore.ImportSurvivors(): got: %+v", survivor)
+	}
+	if events := store.GetAuditEvents("A2", time.Time{}, 0, 100); len(events) != 1 || events[0].Action != AuditCreate {
+		t.Errorf("SurvivorStore.GetAuditEvents(): got: %+v", events)
+	}
+}
+
+func testStoreReportInfection(t *testing.T, store SurvivorStore) {
+	saveSurvivors(t, store,
+		&Survivor{Name: "Jane Doe", IdNumber: "A1"},
+		&Survivor{Name: "John Doe", IdNumber: "A2"},
+		&Survivor{Name: "Mary Roe", IdNumber: "A3"},
+	)
+
+	report, err := store.ReportInfection("A2", "A1", 2)
+	if err != nil || report.Reports != 1 || report.Threshold != 2 || report.Infected {
+		t.Errorf("SurvivorStore.ReportInfection(): got: %+v %v", report, err)
+	}
+	if _, err = store.ReportInfection("A2", "A1", 2); err != ErrDuplicateReport {
+		t.Errorf("SurvivorStore.ReportInfection(): want: %v, got: %v", ErrDuplicateReport, err)
+	}
+	if _, err = store.ReportInfection("A9", "A1", 2); err != ErrNotFound {
+		t.Errorf("SurvivorStore.ReportInfection(): want: %v, got: %v", ErrNotFound, err)
+	}
+	report, err = store.ReportInfection("A3", "A1", 2)
+	if err != nil || report.Reports != 2 || !report.Infected {
+		t.Errorf("SurvivorStore.ReportInfection(): got: %+v %v", report, err)
+	}
+	if survivor := store.GetSurvivor("A1"); survivor == nil || !survivor.Infected {
+		t.Errorf("SurvivorStore.ReportInfection(): got: %+v", survivor)
+	}
+}
+
+func testStoreTrade(t *testing.T, store SurvivorStore) {
+	saveSurvivors(t, store,
+		&Survivor{Name: "Jane Doe", IdNumber: "A1", Resources: Resources{Water: 1, Food: "Fish"}},
+		&Survivor{Name: "John Doe", IdNumber: "A2", Resources: Resources{Ammunition: 10}},
+		&Survivor{Name: "Mary Roe", IdNumber: "A3", Infected: true, Resources: Resources{Ammunition: 10}},
+	)
+
+	for _, test := range []struct {
+		trade Trade
+		err   error
+	}{
+		{Trade{From: TradeOffer{IdNumber: "A1", Water: 1}, To: TradeOffer{IdNumber: "A2", Ammunition: 3}}, ErrUnbalancedTrade},
+		{Trade{From: TradeOffer{IdNumber: "A1", Water: 2}, To: TradeOffer{IdNumber: "A2", Ammunition: 8}}, ErrInsufficientResources},
+		{Trade{From: TradeOffer{IdNumber: "A1", Water: 1}, To: TradeOffer{IdNumber: "A3", Ammunition: 4}}, ErrInfectedSurvivor},
+		{Trade{From: TradeOffer{IdNumber: "A1", Water: 1}, To: TradeOffer{IdNumber: "A9", Ammunition: 4}}, ErrNotFound},
+		{Trade{From: TradeOffer{IdNumber: "A1"}, To: TradeOffer{IdNumber: "A2"}}, ErrInvalidTrade},
+	} {
+		if _, err := store.Trade(&test.trade); err != test.err {
+			t.Errorf("SurvivorStore.Trade() - %+v: want: %v, got: %v", test.trade, test.err, err)
+		}
+	}
+
+	survivors, err := store.Trade(&Trade{
+		From: TradeOffer{IdNumber: "A1", Water: 1, Food: []string{"fish"}},
+		To:   TradeOffer{IdNumber: "A2", Ammunition: 7},
+	})
+	if err != nil || len(survivors) != 2 || survivors[0].Ammunition != 7 || survivors[1].Water != 1 {
+		t.Errorf("SurvivorStore.Trade(): got: %+v %v", survivors, err)
+	}
+	from, to := store.GetSurvivor("A1"), store.GetSurvivor("A2")
+	if from == nil || from.Water != 0 || from.Food != "" || from.Ammunition != 7 {
+		t.Errorf("SurvivorStore.Trade(): got: %+v", from)
+	}
+	if to == nil || to.Water != 1 || to.Food != "Fish" || to.Ammunition != 3 {
+		t.Errorf("SurvivorStore.Trade(): got: %+v", to)
+	}
+}
+
+func testStorePatchSurvivor(t *testing.T, store SurvivorStore) {
+	saveSurvivors(t, store,
+		&Survivor{Name: "Jane Doe", IdNumber: "A1", Resources: Resources{Water: 1}},
+		&Survivor{Name: "John Doe", IdNumber: "A2"},
+	)
+	if _, err := store.ReportInfection("A2", "A1", 3); err != nil {
+		t.Errorf("SurvivorStore.ReportInfection(): want: %v, got: %v", nil, err)
+	}
+
+	taken := "A2"
+	if _, err := store.PatchSurvivor("A1", &SurvivorPatch{IdNumber: &taken}); err != ErrDuplicateIdNumber {
+		t.Errorf("SurvivorStore.PatchSurvivor(): want: %v, got: %v", ErrDuplicateIdNumber, err)
+	}
+	if _, err := store.PatchSurvivor("A9", &SurvivorPatch{}); err != ErrNotFound {
+		t.Errorf("SurvivorStore.PatchSurvivor(): want: %v, got: %v", ErrNotFound, err)
+	}
+
+	name, idNumber, longitude, food := "Jane Roe", "B1", 5.0, "Bread"
+	survivor, err := store.PatchSurvivor("A1", &SurvivorPatch{Name: &name, IdNumber: &idNumber, Longitude: &longitude, Food: &food})
+	if err != nil || survivor == nil || survivor.Name != name || survivor.IdNumber != idNumber ||
+		survivor.Longitude != longitude || survivor.Water != 1 || survivor.Food != food || len(survivor.Inventory) != 2 {
+		t.Errorf("SurvivorStore.PatchSurvivor(): got: %+v %v", survivor, err)
+	}
+	if store.GetSurvivor("A1") != nil {
+		t.Errorf("SurvivorStore.PatchSurvivor(): the old id number is still in use")
+	}
+	if locations := store.GetLocationHistory("B1", time.Time{}, time.Time{}); len(locations) != 2 {
+		t.Errorf("SurvivorStore.PatchSurvivor(): want: %v, got: %+v", 2, locations)
+	}
+	if _, err = store.ReportInfection("A2", "B1", 3); err != ErrDuplicateReport {
+		t.Errorf("SurvivorStore.PatchSurvivor(): want: %v, got: %v", ErrDuplicateReport, err)
+	}
+}
+
+func testStoreDeleteSurvivor(t *testing.T, store SurvivorStore) {
+	saveSurvivors(t, store,
+		&Survivor{Name: "Jane Doe", IdNumber: "A1"},
+		&Survivor{Name: "John Doe", IdNumber: "A2"},
+	)
+	if _, err := store.ReportInfection("A1", "A2", 3); err != nil {
+		t.Errorf("SurvivorStore.ReportInfection(): want: %v, got: %v", nil, err)
+	}
+
+	if err := store.DeleteSurvivor("A1"); err != nil {
+		t.Errorf("SurvivorStore.DeleteSurvivor(): want: %v, got: %v", nil, err)
+	}
+	if store.GetSurvivor("A1") != nil || len(store.GetAllSurvivors()) != 1 {
+		t.Errorf("SurvivorStore.DeleteSurvivor(): the survivor was not deleted")
+	}
+	if locations := store.GetLocationHistory("A1", time.Time{}, time.Time{}); len(locations) != 0 {
+		t.Errorf("SurvivorStore.DeleteSurvivor(): want: %v, got: %+v", 0, locations)
+	}
+
+	// the report of the deleted survivor no longer counts
+	saveSurvivors(t, store, &Survivor{Name: "Jane Doe", IdNumber: "A1"})
+	if report, err := store.ReportInfection("A1", "A2", 3); err != nil || report.Reports != 1 {
+		t.Errorf("SurvivorStore.DeleteSurvivor(): got: %+v %v", report, err)
+	}
+	events := store.GetAuditEvents("A1", time.Time{}, 0, 100)
+	if len(events) != 3 || events[1].Action != AuditDelete || events[1].Diff["name"].After != nil {
+		t.Errorf("SurvivorStore.DeleteSurvivor(): got: %+v", events)
+	}
+}
+
+func testStoreGetSurvivorsPage(t *testing.T, store SurvivorStore) {
+	saveSurvivors(t, store,
+		&Survivor{Name: "Carl", Age: 30, Gender: "Male", IdNumber: "A1"},
+		&Survivor{Name: "Anna", Age: 20, Gender: "Female", IdNumber: "A2"},
+		&Survivor{Name: "Erin", Age: 50, Gender: "female", IdNumber: "A3", Infected: true},
+		&Survivor{Name: "Bert", Age: 30, Gender: "Male", IdNumber: "A4"},
+		&Survivor{Name: "Dora", Age: 40, Gender: "Female", IdNumber: "A5"},
+	)
+
+	for _, test := range []struct {
+		query SurvivorQuery
+		want  []string
+	}{
+		{SurvivorQuery{}, []string{"A1", "A2", "A3", "A4", "A5"}},
+		{SurvivorQuery{Limit: 2, Sort: "name"}, []string{"A2", "A4", "A1", "A5", "A3"}},
+		{SurvivorQuery{Limit: 2, Sort: "name", Desc: true}, []string{"A3", "A5", "A1", "A4", "A2"}},
+		{SurvivorQuery{Limit: 3, Sort: "age"}, []string{"A2", "A1", "A4", "A5", "A3"}},
+		{SurvivorQuery{Limit: 1, Gender: "FEMALE", MaxAge: 45}, []string{"A2", "A5"}},
+		{SurvivorQuery{Limit: 10, Infected: new(bool), MinAge: 30}, []string{"A1", "A4", "A5"}},
+	} {
+		got := []string{}
+		query := test.query
+		for pages := 0; pages < 10; pages++ {
+			survivors, next, err := store.GetSurvivorsPage(&query)
+			if err != nil {
+				t.Errorf("SurvivorStore.GetSurvivorsPage() - %+v: want: %v, got: %v", test.query, nil, err)
+				break
+			}
+			for _, survivor := range survivors {
+				got = append(got, survivor.IdNumber)
+			}
+			if next == "" {
+				break
+			}
+			query.Cursor = next
+		}
+		if len(got) != len(test.want) {
+			t.Errorf("SurvivorStore.GetSurvivorsPage() - %+v: want: %v, got: %v", test.query, test.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("SurvivorStore.GetSurvivorsPage() - %+v: want: %v, got: %v", test.query, test.want, got)
+				break
+			}
+		}
+	}
+
+	if _, _, err := store.GetSurvivorsPage(&SurvivorQuery{Sort: "gender"}); err != ErrInvalidQuery {
+		t.Errorf("SurvivorStore.GetSurvivorsPage(): want: %v, got: %v", ErrInvalidQuery, err)
+	}
+	for _, cursor := range []string{"1", "not base64!"} {
+		if _, _, err := store.GetSurvivorsPage(&SurvivorQuery{Cursor: cursor}); err != ErrInvalidQuery {
+			t.Errorf("SurvivorStore.GetSurvivorsPage() - %q: want: %v, got: %v", cursor, ErrInvalidQuery, err)
+		}
+	}
+
+	// the next page still follows the last survivor of a page once they are
+	// deleted, and a cursor of another sort is refused
+	survivors, next, err := store.GetSurvivorsPage(&SurvivorQuery{Limit: 2, Sort: "name"})
+	if err != nil || len(survivors) != 2 || survivors[1].IdNumber != "A4" || next == "" {
+		t.Fatalf("SurvivorStore.GetSurvivorsPage(): want: %v, got: %v %q %v", "A4", survivors, next, err)
+	}
+	if err = store.DeleteSurvivor("A4"); err != nil {
+		t.Errorf("SurvivorStore.DeleteSurvivor(): want: %v, got: %v", nil, err)
+	}
+	survivors, _, err = store.GetSurvivorsPage(&SurvivorQuery{Limit: 2, Sort: "name", Cursor: next})
+	if err != nil || len(survivors) != 2 || survivors[0].IdNumber != "A1" || survivors[1].IdNumber != "A5" {
+		t.Errorf("SurvivorStore.GetSurvivorsPage(): want: %v, got: %v %v", []string{"A1", "A5"}, survivors, err)
+	}
+	if _, _, err = store.GetSurvivorsPage(&SurvivorQuery{Limit: 2, Sort: "age", Cursor: next}); err != ErrInvalidQuery {
+		t.Errorf("SurvivorStore.GetSurvivorsPage(): want: %v, got: %v", ErrInvalidQuery, err)
+	}
+}
+
+func testStoreGetNearbySurvivors(t *testing.T, store SurvivorStore) {
+	saveSurvivors(t, store,
+		&Survivor{Name: "Far", IdNumber: "A1", LastLocation: LastLocation{Longitude: 10, Latitude: 10}},
+		&Survivor{Name: "Near", IdNumber: "A2", LastLocation: LastLocation{Longitude: 0.01, Latitude: 0.01}},
+		&Survivor{Name: "Nearer", IdNumber: "A3", LastLocation: LastLocation{Longitude: 0.001, Latitude: 0}, Infected: true},
+	)
+
+	nearby := store.GetNearbySurvivors(0, 0, 10, nil)
+	if len(nearby) != 2 || nearby[0].IdNumber != "A3" || nearby[1].IdNumber != "A2" || nearby[0].Distance > nearby[1].Distance {
+		t.Errorf("SurvivorStore.GetNearbySurvivors(): got: %+v", nearby)
+	}
+	if nearby = store.GetNearbySurvivors(0, 0, 10, new(bool)); len(nearby) != 1 || nearby[0].IdNumber != "A2" {
+		t.Errorf("SurvivorStore.GetNearbySurvivors(): got: %+v", nearby)
+	}
+}
+
+func testStoreStats(t *testing.T, store SurvivorStore) {
+	saveSurvivors(t, store,
+		&Survivor{Name: "Jane Doe", Age: 10, Gender: "Female", IdNumber: "A1", Resources: Resources{Water: 4, Food: "2 Fish"}},
+		&Survivor{Name: "John Doe", Age: 35, Gender: "male", IdNumber: "A2", Resources: Resources{Water: 2, Food: "fish"}},
+		&Survivor{Name: "Mary Roe", Age: 35, Gender: "Male", IdNumber: "A3", Infected: true, Resources: Resources{Medication: "Aspirin", Ammunition: 6}},
+	)
+
+	totals := store.InventoryTotals()
+	if len(totals) != 4 || totals[0].Type != ItemAmmunition || totals[1].Name != "Fish" || totals[1].Quantity != 3 ||
+		totals[3].Type != ItemWater || totals[3].Quantity != 6 {
+		t.Errorf("SurvivorStore.InventoryTotals(): got: %+v", totals)
+	}
+
+	stats := store.GetDetailedStats()
+	if stats == nil || stats.Survivors != 3 || stats.Infected != 1 || stats.AverageWater != 3 || stats.AverageFood != 1.5 ||
+		stats.AverageAmmunition != 0 || len(stats.LostResources) != 2 {
+		t.Errorf("SurvivorStore.GetDetailedStats(): got: %+v", stats)
+		return
+	}
+	if len(stats.ByGender) != 2 || stats.ByGender[0].Group != "female" || stats.ByGender[1].Survivors != 2 ||
+		stats.ByGender[1].InfectedPercentage != 50 {
+		t.Errorf("SurvivorStore.GetDetailedStats(): got: %+v", stats.ByGender)
+	}
+	if len(stats.ByAgeBand) != 2 || stats.ByAgeBand[0].Group != "0-12" || stats.ByAgeBand[1].Group != "30-44" {
+		t.Errorf("SurvivorStore.GetDetailedStats(): got: %+v", stats.ByAgeBand)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := store.SaveStatsSnapshot(); err != nil {
+			t.Errorf("SurvivorStore.SaveStatsSnapshot(): want: %v, got: %v", nil, err)
+		}
+	}
+	snapshots, err := store.GetStatsHistory(time.Time{}, time.Time{}, "")
+	if err != nil || len(snapshots) != 2 || snapshots[0].Healthy != 2 || snapshots[0].Infected != 1 ||
+		snapshots[0].Water != 6 || snapshots[0].Ammunition != 6 || len(snapshots[0].Items) != 2 || snapshots[0].Timestamp.IsZero() {
+		t.Errorf("SurvivorStore.GetStatsHistory(): got: %+v %v", snapshots, err)
+	}
+	if snapshots, err = store.GetStatsHistory(time.Time{}, time.Time{}, "month"); err != nil || len(snapshots) != 1 {
+		t.Errorf("SurvivorStore.GetStatsHistory(): want: %v, got: %+v %v", 1, snapshots, err)
+	}
+	if snapshots, err = store.GetStatsHistory(time.Time{}, time.Now().Add(-time.Hour), ""); err != nil || len(snapshots) != 0 {
+		t.Errorf("SurvivorStore.GetStatsHistory(): want: %v, got: %+v %v", 0, snapshots, err)
+	}
+	if _, err = store.GetStatsHistory(time.Time{}, time.Time{}, "year"); err != ErrInvalidQuery {
+		t.Errorf("SurvivorStore.GetStatsHistory(): want: %v, got: %v", ErrInvalidQuery, err)
+	}
+
+	// water is averaged in litres whatever its unit
+	if err = store.UpdateInventory("A2", []InventoryItem{{Type: ItemWater, Name: ItemWater, Quantity: 2000, Unit: "ml"}}); err != nil {
+		t.Errorf("SurvivorStore.UpdateInventory(): want: %v, got: %v", nil, err)
+	}
+	if stats = store.GetDetailedStats(); stats == nil || stats.AverageWater != 3 {
+		t.Errorf("SurvivorStore.GetDetailedStats(): want: %v, got: %+v", 3, stats)
+	}
+}
+
+func testStoreAuditEvents(t *testing.T, store SurvivorStore) {
+	if id, err := store.LastAuditEventID(); err != nil || id != 0 {
+		t.Errorf("SurvivorStore.LastAuditEventID(): want: %v, got: %v %v", 0, id, err)
+	}
+
+	scout := store.WithActor(Actor{Name: "scout", RemoteAddr: "10.0.0.1:1234"})
+	saveSurvivors(t, scout, &Survivor{Name: "Jane Doe", IdNumber: "A1"})
+	saveSurvivors(t, store, &Survivor{Name: "John Doe", IdNumber: "A2"})
+	if _, err := scout.UpdateLocation("A1", 1, 2); err != nil {
+		t.Errorf("SurvivorStore.UpdateLocation(): want: %v, got: %v", nil, err)
+	}
+
+	events := store.GetAuditEvents("A1", time.Time{}, 0, 100)
+	if len(events) != 2 || events[0].Action != AuditCreate || events[0].Actor != "scout" ||
+		events[0].RemoteAddr != "10.0.0.1:1234" || events[0].Diff["name"].After != "Jane Doe" {
+		t.Errorf("SurvivorStore.GetAuditEvents(): got: %+v", events)
+	}
+	if len(events) == 2 && (events[1].Action != AuditUpdateLocation || len(events[1].Diff) != 2 ||
+		events[1].Diff["latitude"].Before != 0.0 || events[1].Diff["latitude"].After != 2.0) {
+		t.Errorf("SurvivorStore.GetAuditEvents(): got: %+v", events[1])
+	}
+	if events = store.GetAuditEvents("", time.Time{}, 0, 100); len(events) != 3 || events[1].Actor != systemActor {
+		t.Errorf("SurvivorStore.GetAuditEvents(): got: %+v", events)
+	}
+	if events = store.GetAuditEvents("", time.Now().Add(time.Hour), 0, 100); len(events) != 0 {
+		t.Errorf("SurvivorStore.GetAuditEvents(): want: %v, got: %+v", 0, events)
+	}
+	if events = store.GetAuditEvents("", time.Time{}, 1, 1); len(events) != 1 || events[0].ID != 2 {
+		t.Errorf("SurvivorStore.GetAuditEvents(): want: %v, got: %+v", 2, events)
+	}
+
+	events, err := store.GetAuditEventsAfter(1, 1)
+	if err != nil || len(events) != 1 || events[0].IdNumber != "A2" {
+		t.Errorf("SurvivorStore.GetAuditEventsAfter(): got: %+v %v", events, err)
+	}
+	if id, err := store.LastAuditEventID(); err != nil || id != 3 {
+		t.Errorf("SurvivorStore.LastAuditEventID(): want: %v, got: %v %v", 3, id, err)
+	}
+}
+
+func testStoreZones(t *testing.T, store SurvivorStore) {
+	saveSurvivors(t, store, &Survivor{Name: "Jane Doe", IdNumber: "A1", LastLocation: LastLocation{Longitude: 50, Latitude: 50}})
+
+	zone := &Zone{Name: "Camp", Kind: ZoneCircle, Center: &LastLocation{Longitude: 0, Latitude: 0}, Radius: 10}
+	if err := store.SaveZone(zone); err != nil || zone.ID == 0 {
+		t.Errorf("SurvivorStore.SaveZone(): want: %v, got: %v %v", nil, zone.ID, err)
+	}
+	if err := store.SaveZone(&Zone{Name: "Camp", Kind: ZoneCircle, Center: &LastLocation{}, Radius: 1}); err != ErrDuplicateZone {
+		t.Errorf("SurvivorStore.SaveZone(): want: %v, got: %v", ErrDuplicateZone, err)
+	}
+	if err := store.SaveZone(&Zone{Name: "Line", Kind: ZonePolygon, Polygon: []LastLocation{{}, {}}}); err != ErrInvalidZone {
+		t.Errorf("SurvivorStore.SaveZone(): want: %v, got: %v", ErrInvalidZone, err)
+	}
+	square := &Zone{Name: "Square", Kind: ZonePolygon, Polygon: []LastLocation{
+		{Longitude: 20, Latitude: 20}, {Longitude: 21, Latitude: 20}, {Longitude: 21, Latitude: 21}, {Longitude: 20, Latitude: 21},
+	}}
+	if err := store.SaveZone(square); err != nil {
+		t.Errorf("SurvivorStore.SaveZone(): want: %v, got: %v", nil, err)
+	}
+	if zones := store.GetZones(); len(zones) != 2 || zones[0].Name != "Camp" || len(zones[1].Polygon) != 4 {
+		t.Errorf("SurvivorStore.GetZones(): got: %+v", zones)
+	}
+
+	for _, test := range []struct {
+		longitude, latitude float64
+		want                []string
+	}{
+		{0.01, 0.01, []string{ZoneEnter}},
+		{0.02, 0.02, []string{}},
+		{20.5, 20.5, []string{ZoneExit, ZoneEnter}},
+	} {
+		events, err := store.UpdateLocation("A1", test.longitude, test.latitude)
+		if err != nil || len(events) != len(test.want) {
+			t.Errorf("SurvivorStore.UpdateLocation(): want: %v, got: %+v %v", test.want, events, err)
+			continue
+		}
+		for i := range events {
+			if events[i].Event != test.want[i] || events[i].IdNumber != "A1" {
+				t.Errorf("SurvivorStore.UpdateLocation(): want: %v, got: %+v", test.want, events)
+			}
+		}
+	}
+	if occupants := store.GetZoneOccupants(square.ID); len(occupants) != 1 || occupants[0].IdNumber != "A1" {
+		t.Errorf("SurvivorStore.GetZoneOccupants(): got: %+v", occupants)
+	}
+	if occupants := store.GetZoneOccupants(zone.ID); len(occupants) != 0 {
+		t.Errorf("SurvivorStore.GetZoneOccupants(): want: %v, got: %+v", 0, occupants)
+	}
+
+	// survivors are entered into the zones they are registered in, and the
+	// zones created around them
+	saveSurvivors(t, store, &Survivor{Name: "John Doe", IdNumber: "A2", LastLocation: LastLocation{Longitude: 0.05, Latitude: 0.05}})
+	if occupants := store.GetZoneOccupants(zone.ID); len(occupants) != 1 || occupants[0].IdNumber != "A2" {
+		t.Errorf("SurvivorStore.GetZoneOccupants(): got: %+v", occupants)
+	}
+	outpost := &Zone{Name: "Outpost", Kind: ZoneCircle, Center: &LastLocation{Longitude: 0.05, Latitude: 0.05}, Radius: 1}
+	if err := store.SaveZone(outpost); err != nil {
+		t.Errorf("SurvivorStore.SaveZone(): want: %v, got: %v", nil, err)
+	}
+	if occupants := store.GetZoneOccupants(outpost.ID); len(occupants) != 1 || occupants[0].IdNumber != "A2" {
+		t.Errorf("SurvivorStore.GetZoneOccupants(): got: %+v", occupants)
+	}
+
+	// moving a zone takes in and leaves out survivors
+	zone.Name = "Base"
+	zone.Center = &LastLocation{Longitude: 20.5, Latitude: 20.5}
+	if err := store.UpdateZone(zone); err != nil {
+		t.Errorf("SurvivorStore.UpdateZone(): want: %v, got: %v", nil, err)
+	}
+	if occupants := store.GetZoneOccupants(zone.ID); len(occupants) != 1 || occupants[0].IdNumber != "A1" {
+		t.Errorf("SurvivorStore.GetZoneOccupants(): got: %+v", occupants)
+	}
+	if events := store.GetZoneEvents(zone.ID); len(events) != 5 || events[0].ZoneName != "Base" ||
+		events[0].Event != ZoneEnter || events[1].Event != ZoneExit ||
+		events[3].IdNumber != "A1" || events[3].Event != ZoneEnter || events[4].IdNumber != "A2" || events[4].Event != ZoneExit {
+		t.Errorf("SurvivorStore.GetZoneEvents(): got: %+v", events)
+	}
+	square.Name = "Base"
+	if err := store.UpdateZone(square); err != ErrDuplicateZone {
+		t.Errorf("SurvivorStore.UpdateZone(): want: %v, got: %v", ErrDuplicateZone, err)
+	}
+
+	if err := store.DeleteZone(zone.ID); err != nil {
+		t.Errorf("SurvivorStore.DeleteZone(): want: %v, got: %v", nil, err)
+	}
+	if store.GetZone(zone.ID) != nil || len(store.GetZoneEvents(zone.ID)) != 0 {
+		t.Errorf("SurvivorStore.DeleteZone(): the zone was not deleted")
+	}
+	if err := store.DeleteZone(zone.ID); err != ErrZoneNotFound {
+		t.Errorf("SurvivorStore.DeleteZone(): want: %v, got: %v", ErrZoneNotFound, err)
+	}
+	zone.Name = "Camp"
+	if err := store.UpdateZone(zone); err != ErrZoneNotFound {
+		t.Errorf("SurvivorStore.UpdateZone(): want: %v, got: %v", ErrZoneNotFound, err)
+	}
+}
+
+func testStoreWebhooks(t *testing.T, store SurvivorStore) {
+	webhook := &Webhook{URL: "http://example.com/hook", Events: []string{EventLocationUpdated}}
+	if err := store.SaveWebhook(webhook); err != nil || webhook.ID == 0 || webhook.Secret == "" {
+		t.Errorf("SurvivorStore.SaveWebhook(): got: %+v %v", webhook, err)
+	}
+	if err := store.SaveWebhook(&Webhook{URL: "ftp://example.com"}); err != ErrInvalidWebhook {
+		t.Errorf("SurvivorStore.SaveWebhook(): want: %v, got: %v", ErrInvalidWebhook, err)
+	}
+	secret := webhook.Secret
+	webhook.Secret = ""
+	webhook.URL = "https://example.com/hook"
+	if err := store.UpdateWebhook(webhook); err != nil {
+		t.Errorf("SurvivorStore.UpdateWebhook(): want: %v, got: %v", nil, err)
+	}
+	if stored := store.GetWebhook(webhook.ID); stored == nil || stored.Secret != secret || stored.URL != webhook.URL ||
+		len(stored.Events) != 1 {
+		t.Errorf("SurvivorStore.GetWebhook(): got: %+v", stored)
+	}
+	if err := store.UpdateWebhook(&Webhook{ID: 99, URL: "http://example.com"}); err != ErrWebhookNotFound {
+		t.Errorf("SurvivorStore.UpdateWebhook(): want: %v, got: %v", ErrWebhookNotFound, err)
+	}
+
+	saveSurvivors(t, store, &Survivor{Name: "Jane Doe", IdNumber: "A1"})
+	if _, err := store.UpdateLocation("A1", 1, 2); err != nil {
+		t.Errorf("SurvivorStore.UpdateLocation(): want: %v, got: %v", nil, err)
+	}
+	if count, err := store.EnqueueWebhookDeliveries(100); err != nil || count != 1 {
+		t.Errorf("SurvivorStore.EnqueueWebhookDeliveries(): want: %v, got: %v %v", 1, count, err)
+	}
+	if count, err := store.EnqueueWebhookDeliveries(100); err != nil || count != 0 {
+		t.Errorf("SurvivorStore.EnqueueWebhookDeliveries(): want: %v, got: %v %v", 0, count, err)
+	}
+
+	due, err := store.GetDueWebhookDeliveries(time.Now(), 10)
+	if err != nil || len(due) != 1 || due[0].EventType != EventLocationUpdated || due[0].URL != webhook.URL ||
+		due[0].Secret != secret || len(due[0].Payload) == 0 {
+		t.Errorf("SurvivorStore.GetDueWebhookDeliveries(): got: %+v %v", due, err)
+		return
+	}
+	attempt := &WebhookAttempt{Attempt: 1, StatusCode: 500, Timestamp: time.Now()}
+	if err = store.RecordWebhookAttempt(due[0].ID, attempt, DeliveryPending, time.Now().Add(time.Hour)); err != nil {
+		t.Errorf("SurvivorStore.RecordWebhookAttempt(): want: %v, got: %v", nil, err)
+	}
+	if due, err = store.GetDueWebhookDeliveries(time.Now(), 10); err != nil || len(due) != 0 {
+		t.Errorf("SurvivorStore.GetDueWebhookDeliveries(): want: %v, got: %+v %v", 0, due, err)
+	}
+	deliveries := store.GetWebhookDeliveries(webhook.ID, 10)
+	if len(deliveries) != 1 || deliveries[0].Attempts != 1 || len(deliveries[0].History) != 1 ||
+		deliveries[0].History[0].StatusCode != 500 {
+		t.Errorf("SurvivorStore.GetWebhookDeliveries(): got: %+v", deliveries)
+	}
+
+	if err = store.DeleteWebhook(webhook.ID); err != nil {
+		t.Errorf("SurvivorStore.DeleteWebhook(): want: %v, got: %v", nil, err)
+	}
+	if len(store.GetWebhooks()) != 0 || len(store.GetWebhookDeliveries(webhook.ID, 10)) != 0 {
+		t.Errorf("SurvivorStore.DeleteWebhook(): the webhook was not deleted")
+	}
+	if err = store.DeleteWebhook(webhook.ID); err != ErrWebhookNotFound {
+		t.Errorf("SurvivorStore.DeleteWebhook(): want: %v, got: %v", ErrWebhookNotFound, err)
+	}
+}
+
+func testStoreAPIKeys(t *testing.T, store SurvivorStore) {
+	key := &APIKey{Name: "scout", Role: RoleScout, Prefix: "abc123", Hash: []byte("hash")}
+	if err := store.SaveAPIKey(key); err != nil || key.ID == 0 {
+		t.Errorf("SurvivorStore.SaveAPIKey(): want: %v, got: %v %v", nil, key.ID, err)
+	}
+	if err := store.SaveAPIKey(&APIKey{Name: "scout", Role: "general", Prefix: "def456", Hash: []byte("hash")}); err != ErrInvalidAPIKey {
+		t.Errorf("SurvivorStore.SaveAPIKey(): want: %v, got: %v", ErrInvalidAPIKey, err)
+	}
+	if err := store.SaveAPIKey(&APIKey{Name: "medic", Role: RoleMedic, Prefix: "abc123", Hash: []byte("hash")}); err == nil {
+		t.Errorf("SurvivorStore.SaveAPIKey(): saved a duplicate prefix")
+	}
+
+	stored := store.GetAPIKeyByPrefix("abc123")
+	if stored == nil || stored.ID != key.ID || stored.Role != RoleScout || string(stored.Hash) != "hash" ||
+		stored.CreatedAt.IsZero() || stored.RevokedAt != nil {
+		t.Errorf("SurvivorStore.GetAPIKeyByPrefix(): got: %+v", stored)
+	}
+	if stored = store.GetAPIKeyByPrefix("def456"); stored != nil {
+		t.Errorf("SurvivorStore.GetAPIKeyByPrefix(): want: %v, got: %+v", nil, stored)
+	}
+
+	if err := store.RevokeAPIKey(key.ID); err != nil {
+		t.Errorf("SurvivorStore.RevokeAPIKey(): want: %v, got: %v", nil, err)
+	}
+	if err := store.RevokeAPIKey(key.ID); err != ErrAPIKeyNotFound {
+		t.Errorf("SurvivorStore.RevokeAPIKey(): want: %v, got: %v", ErrAPIKeyNotFound, err)
+	}
+	if keys := store.GetAPIKeys(); len(keys) != 1 || keys[0].RevokedAt == nil {
+		t.Errorf("SurvivorStore.GetAPIKeys(): got: %+v", keys)
+	}
+}
+
+func testStoreRobots(t *testing.T, store SurvivorStore) {
+	manufactured := time.Date(2021, 9, 3, 11, 4, 17, 0, time.UTC)
+	robots := []Robot{
+		{Model: "AX-2", SerialNumber: "S2", ManufacturedDate: manufactured, Category: "Land"},
+		{Model: "FX-1", SerialNumber: "S1", ManufacturedDate: manufactured, Category: "Flying"},
+	}
+	if err := store.SaveRobots(robots); err != nil {
+		t.Fatalf("SurvivorStore.SaveRobots(): want: %v, got: %v", nil, err)
+	}
+	saved := store.GetRobots()
+	if len(saved) != 2 || saved[0].SerialNumber != "S2" || saved[1].Model != "FX-1" || !saved[0].ManufacturedDate.Equal(manufactured) ||
+		saved[0].FirstSeen.IsZero() || !saved[0].LastSeen.Equal(saved[0].FirstSeen) {
+		t.Fatalf("SurvivorStore.GetRobots(): got: %+v", saved)
+	}
+
+	robots[1].Model = "FX-1b"
+	if err := store.SaveRobots(robots[1:]); err != nil {
+		t.Errorf("SurvivorStore.SaveRobots(): want: %v, got: %v", nil, err)
+	}
+	if got := store.GetRobots(); len(got) != 2 || got[1].Model != "FX-1b" || !got[1].FirstSeen.Equal(saved[1].FirstSeen) ||
+		got[1].LastSeen.Before(saved[1].LastSeen) {
+		t.Errorf("SurvivorStore.GetRobots(): got: %+v", got)
+	}
+
+	if err := store.SaveRobots([]Robot{{Model: "ZX-3", SerialNumber: "S3"}, {Model: "ZX-4"}}); err != ErrInvalidRobot {
+		t.Errorf("SurvivorStore.SaveRobots(): want: %v, got: %v", ErrInvalidRobot, err)
+	}
+	if got := store.GetRobots(); len(got) != 2 {
+		t.Errorf("SurvivorStore.GetRobots(): want: %v, got: %+v", 2, got)
+	}
+}
@@ -2,15 +2,18 @@ package survivordb
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
 	"github.com/sirupsen/logrus"
 )
 
 type SurvivorDB struct {
 	DBName               string
+	Driver               string
 	DB                   *sql.DB
-	dataDefinitionlStmt  *sql.Stmt
 	createStmt           *sql.Stmt
 	selectStmt           *sql.Stmt
 	selectInfectedStmt   *sql.Stmt
@@ -19,37 +22,80 @@ type SurvivorDB struct {
 	updateLocationStmt   *sql.Stmt
 	updateResourceStmt   *sql.Stmt
 	updateInfectedStmt   *sql.Stmt
+	countByIdNumberStmt  *sql.Stmt
+	selectNearbyStmt     *sql.Stmt
+
+	infectionReportStmts
+	inventoryStmts
+	locationHistoryStmts
+	zoneStmts
+	statsStmts
+	statsSnapshotStmts
+	auditStmts
+	webhookStmts
+	apiKeyStmts
+	robotStmts
+
+	// actor is recorded in the audit events of changes, see WithActor
+	actor Actor
 }
 
+// ErrNotFound is returned when a survivor id number is not in the Survivors table
+var ErrNotFound = errors.New("survivor not found")
+
+// ErrDuplicateIdNumber is returned when an id number is already registered
+var ErrDuplicateIdNumber = errors.New("survivor id number already registered")
+
 const (
-	ddlSQL = `CREATE TABLE IF NOT EXISTS Survivors (
-	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
-	name TEXT,
-	age INTEGER,
-	gender TEXT,
-	id_number TEXT,
-	longitude TEXT,
-	latitude TEXT,
-	water TEXT,
-	food TEXT,
-	medication TEXT,
-	ammunition TEXT,
-	infected INTEGER,
-	last_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
-	);`
 	createSQL           = `INSERT INTO Survivors (name, age, gender, id_number, longitude, latitude, water, food, medication, ammunition, infected) VALUES(?,?,?,?,?,?,?,?,?,?,?);`
 	selectSQL           = `SELECT name, age, gender, id_number, longitude, latitude, water, food, medication, ammunition, infected, last_ts FROM Survivors;`
 	selectByIdNumberSQL = `SELECT name, age, gender, id_number, longitude, latitude, water, food, medication, ammunition, infected, last_ts FROM Survivors  WHERE id_number = ?;`
 	selectInfectedSQL   = `SELECT name, age, gender, id_number, longitude, latitude, water, food, medication, ammunition, infected, last_ts FROM Survivors  WHERE infected = ?;`
 	countInfectedSQL    = `SELECT count(*) FROM Survivors  WHERE infected = ?;`
+	countByIdNumberSQL  = `SELECT count(*) FROM Survivors WHERE id_number = ?;`
 
 	updateLocationSQL = `UPDATE Survivors SET longitude = ?, latitude = ?, last_ts = CURRENT_TIMESTAMP WHERE id_number = ?`
 	updateResourceSQL = `UPDATE Survivors SET water = ?, food = ?, medication = ?, ammunition = ?, last_ts = CURRENT_TIMESTAMP WHERE id_number = ?`
 	updateInfectedSQL = `UPDATE Survivors SET infected = 1, last_ts = CURRENT_TIMESTAMP WHERE id_number = ?`
 )
 
+// uniqueViolation reports whether err is a UNIQUE constraint failing, as when
+// a concurrent request inserted the same row after it was checked for
+func uniqueViolation(err error) bool {
+	switch err := err.(type) {
+	case sqlite3.Error:
+		return err.ExtendedCode == sqlite3.ErrConstraintUnique || err.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	case *pq.Error:
+		return err.Code == "23505"
+	}
+
+	return false
+}
+
+// Drivers a SurvivorDB may be opened with
+const (
+	SQLite   = "sqlite3"
+	Postgres = "postgres"
+)
+
+// Open opens the SQLite database file dbName
 func Open(dbName string) *SurvivorDB {
-	db, err := sql.Open("sqlite3", dbName)
+	return OpenDriver(SQLite, dbName)
+}
+
+// OpenDriver opens a database with driverName, SQLite or Postgres. The dsn
+// is a file name for SQLite and a connection string for PostgreSQL
+func OpenDriver(driverName, dsn string) *SurvivorDB {
+	var db *sql.DB
+	var err error
+	switch driverName {
+	case SQLite:
+		db, err = sql.Open(SQLite, dsn)
+	case Postgres:
+		db, err = openPostgres(dsn)
+	default:
+		err = fmt.Errorf("unknown database driver %q", driverName)
+	}
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"Error": err,
@@ -58,27 +104,15 @@ func Open(dbName string) *SurvivorDB {
 	}
 
 	return &SurvivorDB{
-		DBName: dbName,
+		DBName: dsn,
+		Driver: driverName,
 		DB:     db,
 	}
 }
 
+// Setup migrates the schema to the latest version and prepares the statements
 func (s *SurvivorDB) Setup() error {
-	ddlStmt, err := s.DB.Prepare(ddlSQL)
-	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"Error": err,
-		}).Info("Sql error")
-		return err
-	}
-	s.dataDefinitionlStmt = ddlStmt
-
-	_, err = s.dataDefinitionlStmt.Exec()
-	if err != nil {
-		logrus.WithFields(logrus.Fields{
-			"Error": err,
-			"sql":   ddlSQL,
-		}).Info("Sql error")
+	if _, err := s.MigrateUp(0); err != nil {
 		return err
 	}
 
@@ -147,12 +181,113 @@ func (s *SurvivorDB) Setup() error {
 	s.updateLocationStmt = updateLocationStmt
 	s.updateResourceStmt = updateResourceStmt
 	s.updateInfectedStmt = updateInfectedStmt
+
+	s.countByIdNumberStmt, err = s.prepare(countByIdNumberSQL)
+	if err != nil {
+		return err
+	}
+	s.selectNearbyStmt, err = s.prepare(selectNearbySQL)
+	if err != nil {
+		return err
+	}
+
+	for _, setup := range []func() error{
+		s.setupInfectionReports,
+		s.setupInventory,
+		s.backfillInventory,
+		s.setupLocationHistory,
+		s.setupZones,
+		s.setupStats,
+		s.setupStatsSnapshots,
+		s.setupAuditEvents,
+		s.setupWebhooks,
+		s.setupAPIKeys,
+		s.setupRobots,
+	} {
+		if err = setup(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-// Save inserts a survivor into the Survivors table
+// prepare compiles a statement for later use
+func (s *SurvivorDB) prepare(query string) (*sql.Stmt, error) {
+	query = s.dialect(query)
+	stmt, err := s.DB.Prepare(query)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   query,
+		}).Info("Sql error")
+		return nil, err
+	}
+
+	return stmt, nil
+}
+
+// insert runs an insert statement and returns the row id of the new row.
+// PostgreSQL has no last insert id, so there the statement returns the id
+func (s *SurvivorDB) insert(stmt *sql.Stmt, args ...interface{}) (int64, error) {
+	if s.Driver == Postgres {
+		var id int64
+		err := stmt.QueryRow(args...).Scan(&id)
+
+		return id, err
+	}
+
+	result, err := stmt.Exec(args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+// Save inserts a survivor into the Survivors table, their resources into
+// the Inventory table and their first location into the LocationHistory table,
+// entering them into the zones they are in.
+// It returns ErrDuplicateIdNumber when the id number is already registered
 func (s *SurvivorDB) Save(survivor *Survivor) error {
-	_, err := s.createStmt.Exec(survivor.Name,
+	items := survivor.Items()
+	survivor.SetItems(items)
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+	defer tx.Rollback()
+
+	if err = s.saveTx(tx, survivor); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+
+	return nil
+}
+
+// saveTx stores a survivor along with their inventory and first location in
+// tx, entering them into the zones they are in. It returns ErrDuplicateIdNumber when the id number is already registered
+func (s *SurvivorDB) saveTx(tx *sql.Tx, survivor *Survivor) error {
+	count, err := countTx(tx, s.countByIdNumberStmt, countByIdNumberSQL, survivor.IdNumber)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateIdNumber
+	}
+
+	_, err = tx.Stmt(s.createStmt).Exec(survivor.Name,
 		survivor.Age,
 		survivor.Gender,
 		survivor.IdNumber,
@@ -163,6 +298,9 @@ func (s *SurvivorDB) Save(survivor *Survivor) error {
 		survivor.Medication,
 		survivor.Ammunition,
 		survivor.Infected)
+	if uniqueViolation(err) {
+		return ErrDuplicateIdNumber
+	}
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"Error": err,
@@ -171,12 +309,40 @@ func (s *SurvivorDB) Save(survivor *Survivor) error {
 		return err
 	}
 
+	if err = s.saveInventoryTx(tx, survivor.IdNumber, survivor.Inventory); err != nil {
+		return err
+	}
+	if err = s.insertLocationTx(tx, survivor.IdNumber, survivor.Longitude, survivor.Latitude); err != nil {
+		return err
+	}
+	if _, err = s.moveTx(tx, survivor.IdNumber, survivor.Longitude, survivor.Latitude); err != nil {
+		return err
+	}
+	if err = s.auditTx(tx, AuditCreate, survivor.IdNumber, nil); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// UpdateLocation updates a survivor location in the Survivors table
-func (s *SurvivorDB) UpdateLocation(idNumber string, longitude, latitude float64) error {
-	_, err := s.updateLocationStmt.Exec(
+// UpdateLocation updates a survivor location in the Survivors table, appends
+// it to the LocationHistory table and returns the zone events of the move
+func (s *SurvivorDB) UpdateLocation(idNumber string, longitude, latitude float64) ([]ZoneEvent, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := s.getSurvivorTx(tx, idNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Stmt(s.updateLocationStmt).Exec(
 		longitude,
 		latitude,
 		idNumber,
@@ -186,39 +352,76 @@ func (s *SurvivorDB) UpdateLocation(idNumber string, longitude, latitude float64
 			"Error": err,
 			"sql":   updateLocationSQL,
 		}).Info("Sql error")
-		return err
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return nil, ErrNotFound
 	}
 
-	return nil
+	if err = s.insertLocationTx(tx, idNumber, longitude, latitude); err != nil {
+		return nil, err
+	}
+	events, err := s.moveTx(tx, idNumber, longitude, latitude)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.auditTx(tx, AuditUpdateLocation, idNumber, before); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return nil, err
+	}
+
+	return events, nil
 }
 
-// UpdateResource updates a survivor resouce in the Survivors table
+// UpdateResource updates a survivor resouce in the Survivors and Inventory tables
 func (s *SurvivorDB) UpdateResource(idNumber string, water float64, food, medication string, ammunition int) error {
-	_, err := s.updateResourceStmt.Exec(
-		water,
-		food,
-		medication,
-		ammunition,
-		idNumber,
-	)
+	resources := &Resources{
+		Water:      water,
+		Food:       food,
+		Medication: medication,
+		Ammunition: ammunition,
+	}
+
+	return s.UpdateInventory(idNumber, resources.Items())
+}
+
+// UpdateResource updates a survivor resouce in the Survivors table
+func (s *SurvivorDB) UpdateInfected(idNumber string) error {
+	tx, err := s.DB.Begin()
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"Error": err,
-			"sql":   updateResourceSQL,
 		}).Info("Sql error")
 		return err
 	}
+	defer tx.Rollback()
 
-	return nil
-}
+	before, err := s.getSurvivorTx(tx, idNumber)
+	if err != nil {
+		return err
+	}
 
-// UpdateResource updates a survivor resouce in the Survivors table
-func (s *SurvivorDB) UpdateInfected(idNumber string) error {
-	_, err := s.updateInfectedStmt.Exec(idNumber)
+	_, err = tx.Stmt(s.updateInfectedStmt).Exec(idNumber)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"Error": err,
-			"sql":   updateResourceSQL,
+			"sql":   updateInfectedSQL,
+		}).Info("Sql error")
+		return err
+	}
+	if err = s.auditTx(tx, AuditUpdateInfected, idNumber, before); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
 		}).Info("Sql error")
 		return err
 	}
@@ -271,7 +474,7 @@ func (s *SurvivorDB) GetAllSurvivors() []Survivor {
 		return nil
 	}
 
-	return survivors
+	return s.attachInventory(survivors)
 }
 
 // GetInfectedSurvivors selects all infected or uninfected survivors stored in the Survivors table
@@ -319,7 +522,7 @@ func (s *SurvivorDB) GetSurvivors(infected bool) []Survivor {
 		return nil
 	}
 
-	return survivors
+	return s.attachInventory(survivors)
 }
 
 // CountSurvivors count all infected or uninfected survivors stored in the Survivors table
@@ -389,6 +592,7 @@ func (s *SurvivorDB) GetSurvivor(idNumber string) *Survivor {
 		}).Info("Sql error")
 		return nil
 	}
+	survivor.Inventory = s.GetInventory(idNumber)
 
 	return &survivor
 }
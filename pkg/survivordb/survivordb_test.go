@@ -86,6 +86,65 @@ func TestSurvivorDB_Save(t *testing.T) {
 			t.Errorf("SurvivorDB.Save() - %q: want: %v, got: %v", tc.survivor.Name, tc.want, (got == nil))
 		}
 	}
+
+	duplicate := &Survivor{Name: "Jill Doe", Age: 1, Gender: "Female", IdNumber: "HD138VOP34220"}
+	if err = survivordb.Save(duplicate); err != ErrDuplicateIdNumber {
+		t.Errorf("SurvivorDB.Save() - %q: want: %v, got: %v", duplicate.Name, ErrDuplicateIdNumber, err)
+	}
+	if survivor := survivordb.GetSurvivor("HD138VOP34220"); survivor == nil || survivor.Name != "John Doe" {
+		t.Errorf("SurvivorDB.GetSurvivor() - %q: got: %v", "HD138VOP34220", survivor)
+	}
+}
+
+// legacySurvivorsDDL is the Survivors table as databases created before the
+// migrations have it, without a unique index on id_number
+const legacySurvivorsDDL = `CREATE TABLE Survivors (
+	id INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,
+	name TEXT,
+	age INTEGER,
+	gender TEXT,
+	id_number TEXT,
+	longitude TEXT,
+	latitude TEXT,
+	water TEXT,
+	food TEXT,
+	medication TEXT,
+	ammunition TEXT,
+	infected INTEGER,
+	last_ts TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+	);`
+
+// TestSurvivorDB_Setup_UniqueIdNumber checks if Setup deletes duplicate
+// survivors from an existing database and keeps the last one registered
+func TestSurvivorDB_Setup_UniqueIdNumber(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	if _, err := survivordb.DB.Exec(legacySurvivorsDDL); err != nil {
+		t.Errorf("SurvivorDB.DB.Exec(): want: %v, got: %v", nil, err)
+		return
+	}
+	for _, name := range []string{"Jane Doe", "Jill Doe"} {
+		_, err := survivordb.DB.Exec(createSQL, name, 1, "Female", "HD138VOP34219", 0, 0, 0, "", "", 0, false)
+		if err != nil {
+			t.Errorf("SurvivorDB.DB.Exec(): want: %v, got: %v", nil, err)
+			return
+		}
+	}
+
+	if err := survivordb.Setup(); err != nil {
+		t.Errorf("SurvivorDB.Setup(): want: %v, got: %v", nil, err)
+		return
+	}
+	survivors := survivordb.GetAllSurvivors()
+	if len(survivors) != 1 || survivors[0].Name != "Jill Doe" {
+		t.Errorf("SurvivorDB.GetAllSurvivors(): want: %v, got: %v", "Jill Doe", survivors)
+	}
+	if _, err := survivordb.DB.Exec(createSQL, "Jane Doe", 1, "Female", "HD138VOP34219", 0, 0, 0, "", "", 0, false); !uniqueViolation(err) {
+		t.Errorf("SurvivorDB.DB.Exec(): want: unique constraint error, got: %v", err)
+	}
+	if err := survivordb.Setup(); err != nil {
+		t.Errorf("SurvivorDB.Setup(): want: %v, got: %v", nil, err)
+	}
 }
 
 // TestSurvivorDB_UpdateLocation checks if updating a survivor location works
@@ -121,7 +180,7 @@ func TestSurvivorDB_UpdateLocation(t *testing.T) {
 		t.Errorf("SurvivorDB.Save() - %q: want: %v, got: %v", survivor.Name, nil, err)
 	}
 
-	err = survivordb.UpdateLocation(survivor.IdNumber, 1, 2)
+	_, err = survivordb.UpdateLocation(survivor.IdNumber, 1, 2)
 	if err != nil {
 		t.Errorf("SurvivorDB.UpdateLocation() - %q: want: %v, got: %v", survivor.Name, nil, err)
 	}
@@ -432,7 +491,7 @@ func TestSurvivorDB_CountSurvivors_Infected_NotInfected(t *testing.T) {
 				Name:     "Jill Doe",
 				Age:      1,
 				Gender:   "Female",
-				IdNumber: "HD138VOP34220",
+				IdNumber: "HD138VOP34221",
 				LastLocation: LastLocation{
 					Longitude: 0,
 					Latitude:  0,
@@ -0,0 +1,283 @@
+package survivordb
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrInvalidTrade is returned when a trade offers nothing or negative amounts
+	ErrInvalidTrade = errors.New("invalid trade")
+	// ErrInfectedSurvivor is returned when an infected survivor takes part in a trade
+	ErrInfectedSurvivor = errors.New("infected survivors cannot trade")
+	// ErrUnbalancedTrade is returned when both sides of a trade are not worth the same points
+	ErrUnbalancedTrade = errors.New("both sides of a trade must be worth the same points")
+	// ErrInsufficientResources is returned when a survivor offers more than they have
+	ErrInsufficientResources = errors.New("survivor does not have the resources offered")
+)
+
+// TradePoints is the value of one unit of each resource type
+var TradePoints = map[string]float64{
+	"water":      4,
+	"food":       3,
+	"medication": 2,
+	"ammunition": 1,
+}
+
+// TradeOffer defines the resources one survivor gives up in a trade
+// swagger:model
+type TradeOffer struct {
+	// the id number of the survivor making the offer
+	//
+	// required: true
+	IdNumber string `json:"id"`
+
+	// the water offered
+	Water float64 `json:"water"`
+
+	// the food items offered
+	Food []string `json:"food"`
+
+	// the medication items offered
+	Medication []string `json:"medication"`
+
+	// the ammunition offered
+	Ammunition int `json:"ammunition"`
+}
+
+// Points returns the value of the offer according to TradePoints
+func (o *TradeOffer) Points() float64 {
+	return o.Water*TradePoints["water"] +
+		float64(len(o.Food))*TradePoints["food"] +
+		float64(len(o.Medication))*TradePoints["medication"] +
+		float64(o.Ammunition)*TradePoints["ammunition"]
+}
+
+// Trade defines an exchange of resources between two survivors
+// swagger:model
+type Trade struct {
+	// the offer of the survivor starting the trade
+	//
+	// required: true
+	From TradeOffer `json:"from"`
+
+	// the offer of the survivor accepting the trade
+	//
+	// required: true
+	To TradeOffer `json:"to"`
+}
+
+// validate checks that a trade is between two survivors, offers something and
+// is balanced
+func (t *Trade) validate() error {
+	if t.From.IdNumber == t.To.IdNumber ||
+		t.From.Water < 0 || t.To.Water < 0 ||
+		t.From.Ammunition < 0 || t.To.Ammunition < 0 ||
+		t.From.Points() == 0 {
+		return ErrInvalidTrade
+	}
+	if t.From.Points() != t.To.Points() {
+		return ErrUnbalancedTrade
+	}
+
+	return nil
+}
+
+// exchange moves the offered resources between from and to
+func (t *Trade) exchange(from, to *Survivor) error {
+	if from.Infected || to.Infected {
+		return ErrInfectedSurvivor
+	}
+
+	fromItems, err := from.Resources.take(&t.From)
+	if err != nil {
+		return err
+	}
+	toItems, err := to.Resources.take(&t.To)
+	if err != nil {
+		return err
+	}
+	from.SetItems(mergeItems(from.Items(), toItems...))
+	to.SetItems(mergeItems(to.Items(), fromItems...))
+
+	return nil
+}
+
+// Trade atomically moves the offered resources between two survivors
+func (s *SurvivorDB) Trade(trade *Trade) ([]Survivor, error) {
+	if err := trade.validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	from, err := s.getSurvivorTx(tx, trade.From.IdNumber)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.getSurvivorTx(tx, trade.To.IdNumber)
+	if err != nil {
+		return nil, err
+	}
+	fromBefore, toBefore := from.clone(), to.clone()
+	if err = trade.exchange(from, to); err != nil {
+		return nil, err
+	}
+
+	for _, survivor := range []*Survivor{from, to} {
+		_, err = tx.Stmt(s.updateResourceStmt).Exec(
+			survivor.Water,
+			survivor.Food,
+			survivor.Medication,
+			survivor.Ammunition,
+			survivor.IdNumber,
+		)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   updateResourceSQL,
+			}).Info("Sql error")
+			return nil, err
+		}
+		if err = s.saveInventoryTx(tx, survivor.IdNumber, survivor.Inventory); err != nil {
+			return nil, err
+		}
+	}
+	if err = s.auditTx(tx, AuditTrade, from.IdNumber, fromBefore); err != nil {
+		return nil, err
+	}
+	if err = s.auditTx(tx, AuditTrade, to.IdNumber, toBefore); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return nil, err
+	}
+
+	return []Survivor{*from, *to}, nil
+}
+
+// getSurvivorTx selects a survivor inside a transaction
+func (s *SurvivorDB) getSurvivorTx(tx *sql.Tx, idNumber string) (*Survivor, error) {
+	survivor := Survivor{}
+	err := tx.Stmt(s.selectByIdNumberStmt).QueryRow(idNumber).Scan(&survivor.Name,
+		&survivor.Age,
+		&survivor.Gender,
+		&survivor.IdNumber,
+		&survivor.Longitude,
+		&survivor.Latitude,
+		&survivor.Water,
+		&survivor.Food,
+		&survivor.Medication,
+		&survivor.Ammunition,
+		&survivor.Infected,
+		&survivor.LastUpdateTime)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectByIdNumberSQL,
+		}).Info("Sql error")
+		return nil, err
+	}
+
+	rows, err := tx.Stmt(s.selectItemsStmt).Query(idNumber)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectItemsSQL,
+		}).Info("Sql error")
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		item := InventoryItem{}
+		if err = rows.Scan(&item.Type, &item.Name, &item.Quantity, &item.Unit); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectItemsSQL,
+			}).Info("Sql error")
+			return nil, err
+		}
+		survivor.Inventory = append(survivor.Inventory, item)
+	}
+
+	return &survivor, rows.Err()
+}
+
+// take removes the resources in offer from r and returns them as items. Each
+// food and medication entry in the offer is one unit of the named item, and
+// the water is in litres whatever the unit of the water item it comes from
+func (r *Resources) take(offer *TradeOffer) ([]InventoryItem, error) {
+	items := r.Items()
+	given := []InventoryItem{}
+	take := func(itemType, name string, quantity float64) bool {
+		if quantity == 0 {
+			return true
+		}
+		for i := range items {
+			needed := quantity
+			if itemType == ItemWater {
+				perUnit, ok := waterUnits[strings.ToLower(items[i].Unit)]
+				if !ok {
+					continue
+				}
+				needed = quantity / perUnit
+			}
+			if items[i].Type == itemType && (name == "" || strings.EqualFold(items[i].Name, strings.TrimSpace(name))) &&
+				items[i].Quantity >= needed {
+				items[i].Quantity -= needed
+				given = mergeItems(given, InventoryItem{Type: itemType, Name: items[i].Name, Quantity: needed, Unit: items[i].Unit})
+				return true
+			}
+		}
+		return false
+	}
+
+	ok := take(ItemWater, "", offer.Water) && take(ItemAmmunition, "", float64(offer.Ammunition))
+	for _, name := range offer.Food {
+		ok = ok && take(ItemFood, name, 1)
+	}
+	for _, name := range offer.Medication {
+		ok = ok && take(ItemMedication, name, 1)
+	}
+	if !ok {
+		return nil, ErrInsufficientResources
+	}
+	r.SetItems(items)
+
+	return given, nil
+}
+
+// splitItems splits a comma separated list of items
+func splitItems(list string) []string {
+	items := []string{}
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// joinItems joins items into a comma separated list
+func joinItems(items []string) string {
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,120 @@
+package survivordb
+
+import (
+	"os"
+	"testing"
+)
+
+// TestSurvivorDB_Trade checks if trading resources between survivors works
+func TestSurvivorDB_Trade(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	survivors := []*Survivor{
+		{
+			Name:     "Jane Doe",
+			Age:      1,
+			Gender:   "Female",
+			IdNumber: "HD138VOP34219",
+			Resources: Resources{
+				Water:      2,
+				Food:       "Beef, Fish",
+				Medication: "Antibiotics",
+			},
+		},
+		{
+			Name:     "John Doe",
+			Age:      1,
+			Gender:   "Male",
+			IdNumber: "HD138VOP34220",
+			Resources: Resources{
+				Food:       "Pasta",
+				Ammunition: 10,
+			},
+		},
+		{
+			Name:     "Jill Doe",
+			Age:      1,
+			Gender:   "Female",
+			IdNumber: "HD138VOP34221",
+			Resources: Resources{
+				Water: 10,
+			},
+			Infected: true,
+		},
+	}
+	for _, survivor := range survivors {
+		err = survivordb.Save(survivor)
+		if err != nil {
+			t.Errorf("SurvivorDB.Save() - %q: want: %v, got: %v", survivor.Name, nil, err)
+		}
+	}
+
+	testCases := []struct {
+		name  string
+		trade *Trade
+		want  error
+	}{
+		{
+			name: "unbalanced",
+			trade: &Trade{
+				From: TradeOffer{IdNumber: "HD138VOP34219", Water: 1},
+				To:   TradeOffer{IdNumber: "HD138VOP34220", Ammunition: 1},
+			},
+			want: ErrUnbalancedTrade,
+		},
+		{
+			name: "insufficient",
+			trade: &Trade{
+				From: TradeOffer{IdNumber: "HD138VOP34219", Medication: []string{"Aspirin"}},
+				To:   TradeOffer{IdNumber: "HD138VOP34220", Ammunition: 2},
+			},
+			want: ErrInsufficientResources,
+		},
+		{
+			name: "infected",
+			trade: &Trade{
+				From: TradeOffer{IdNumber: "HD138VOP34219", Water: 1},
+				To:   TradeOffer{IdNumber: "HD138VOP34221", Water: 1},
+			},
+			want: ErrInfectedSurvivor,
+		},
+		{
+			name: "unknown",
+			trade: &Trade{
+				From: TradeOffer{IdNumber: "HD138VOP34219", Water: 1},
+				To:   TradeOffer{IdNumber: "UNKNOWN", Water: 1},
+			},
+			want: ErrNotFound,
+		},
+		{
+			name: "balanced",
+			trade: &Trade{
+				From: TradeOffer{IdNumber: "HD138VOP34219", Water: 1, Food: []string{"fish"}},
+				To:   TradeOffer{IdNumber: "HD138VOP34220", Food: []string{"Pasta"}, Ammunition: 4},
+			},
+			want: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		_, got := survivordb.Trade(tc.trade)
+		if got != tc.want {
+			t.Errorf("SurvivorDB.Trade() - %q: want: %v, got: %v", tc.name, tc.want, got)
+		}
+	}
+
+	jane := survivordb.GetSurvivor("HD138VOP34219")
+	if jane == nil || jane.Water != 1 || jane.Food != "Beef, Pasta" || jane.Ammunition != 4 {
+		t.Errorf("SurvivorDB.GetSurvivor() - %q: got: %v", "HD138VOP34219", jane)
+	}
+	john := survivordb.GetSurvivor("HD138VOP34220")
+	if john == nil || john.Water != 1 || john.Food != "Fish" || john.Ammunition != 6 {
+		t.Errorf("SurvivorDB.GetSurvivor() - %q: got: %v", "HD138VOP34220", john)
+	}
+}
@@ -0,0 +1,223 @@
+package survivordb
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Limits of the survivor fields, as declared on the models
+const (
+	MaxNameLength     = 128
+	MaxGenderLength   = 16
+	MaxIdNumberLength = 30
+	MaxItemsLength    = 255
+	MaxUnitLength     = 16
+	MaxAge            = 150
+)
+
+// FieldError describes why the value of a field is invalid
+// swagger:model
+type FieldError struct {
+	// the json name of the field, e.g. latitude or inventory[0].name
+	//
+	// required: true
+	Field string `json:"field"`
+
+	// what is wrong with the value
+	//
+	// required: true
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a payload
+// swagger:model
+type ValidationError struct {
+	// the invalid fields
+	//
+	// required: true
+	Errors []FieldError `json:"errors"`
+}
+
+// Error joins the field errors into one message
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldError := range e.Errors {
+		messages = append(messages, fieldError.Field+" "+fieldError.Message)
+	}
+
+	return "invalid payload: " + strings.Join(messages, ", ")
+}
+
+// validator collects the field errors of a payload
+type validator struct {
+	errors []FieldError
+}
+
+// add records an error for field
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.errors = append(v.errors, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// required checks that a string is set and is at most max bytes long
+func (v *validator) required(field, value string, max int) {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, "is required")
+		return
+	}
+	v.maxLength(field, value, max)
+}
+
+// maxLength checks that a string is at most max bytes long
+func (v *validator) maxLength(field, value string, max int) {
+	if len(value) > max {
+		v.add(field, "must be at most %d characters", max)
+	}
+}
+
+// between checks that a number is in the range [min, max]
+func (v *validator) between(field string, value, min, max float64) {
+	if value < min || value > max {
+		v.add(field, "must be between %v and %v", min, max)
+	}
+}
+
+// nonNegative checks that a number is not below zero
+func (v *validator) nonNegative(field string, value float64) {
+	if value < 0 {
+		v.add(field, "must not be negative")
+	}
+}
+
+// wholeNumber checks that a number has no fractional part
+func (v *validator) wholeNumber(field string, value float64) {
+	if value != math.Trunc(value) {
+		v.add(field, "must be a whole number")
+	}
+}
+
+// err returns the collected errors as a ValidationError, or nil when there are none
+func (v *validator) err() error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Errors: v.errors}
+}
+
+// idNumber checks a survivor id number
+func (v *validator) idNumber(field, value string) {
+	v.required(field, value, MaxIdNumberLength)
+	if strings.ContainsAny(value, "/?#") {
+		v.add(field, "must not contain /, ? or #")
+	}
+}
+
+// location checks a longitude and latitude
+func (v *validator) location(longitude, latitude float64) {
+	v.between("longitude", longitude, -180, 180)
+	v.between("latitude", latitude, -90, 90)
+}
+
+// resources checks the legacy resource fields and the inventory
+func (v *validator) resources(r *Resources) {
+	v.nonNegative("water", r.Water)
+	v.maxLength("food", r.Food, MaxItemsLength)
+	v.maxLength("medication", r.Medication, MaxItemsLength)
+	v.nonNegative("ammunition", float64(r.Ammunition))
+	v.inventory(r.Inventory)
+}
+
+// inventory checks every item of an inventory
+func (v *validator) inventory(items []InventoryItem) {
+	for i, item := range items {
+		field := fmt.Sprintf("inventory[%d]", i)
+		switch item.Type {
+		case ItemWater, ItemFood, ItemMedication, ItemAmmunition:
+		default:
+			v.add(field+".type", "must be one of %s, %s, %s or %s", ItemWater, ItemFood, ItemMedication, ItemAmmunition)
+		}
+		v.required(field+".name", item.Name, MaxItemsLength)
+		v.nonNegative(field+".quantity", item.Quantity)
+		if item.Type == ItemAmmunition {
+			// the legacy ammunition column counts whole rounds
+			v.wholeNumber(field+".quantity", item.Quantity)
+		}
+		if _, ok := item.litres(); item.Type == ItemWater && !ok {
+			// the legacy water column counts litres
+			v.add(field+".unit", "must be l or ml for water")
+		}
+		v.maxLength(field+".unit", item.Unit, MaxUnitLength)
+	}
+}
+
+// Validate checks a survivor against the constraints declared on the model
+func (s *Survivor) Validate() error {
+	v := &validator{}
+	v.required("name", s.Name, MaxNameLength)
+	v.between("age", float64(s.Age), 0, MaxAge)
+	v.required("gender", s.Gender, MaxGenderLength)
+	v.idNumber("id", s.IdNumber)
+	v.location(s.Longitude, s.Latitude)
+	v.resources(&s.Resources)
+
+	return v.err()
+}
+
+// Validate checks the fields that are set on a patch
+func (p *SurvivorPatch) Validate() error {
+	v := &validator{}
+	if p.Name != nil {
+		v.required("name", *p.Name, MaxNameLength)
+	}
+	if p.Age != nil {
+		v.between("age", float64(*p.Age), 0, MaxAge)
+	}
+	if p.Gender != nil {
+		v.required("gender", *p.Gender, MaxGenderLength)
+	}
+	if p.IdNumber != nil {
+		v.idNumber("id", *p.IdNumber)
+	}
+	if p.Longitude != nil {
+		v.between("longitude", *p.Longitude, -180, 180)
+	}
+	if p.Latitude != nil {
+		v.between("latitude", *p.Latitude, -90, 90)
+	}
+	if p.Water != nil {
+		v.nonNegative("water", *p.Water)
+	}
+	if p.Food != nil {
+		v.maxLength("food", *p.Food, MaxItemsLength)
+	}
+	if p.Medication != nil {
+		v.maxLength("medication", *p.Medication, MaxItemsLength)
+	}
+	if p.Ammunition != nil {
+		v.nonNegative("ammunition", float64(*p.Ammunition))
+	}
+	if p.Inventory != nil {
+		v.inventory(*p.Inventory)
+	}
+
+	return v.err()
+}
+
+// ValidateLocation checks a location update of a survivor
+func ValidateLocation(idNumber string, location LastLocation) error {
+	v := &validator{}
+	v.idNumber("id", idNumber)
+	v.location(location.Longitude, location.Latitude)
+
+	return v.err()
+}
+
+// ValidateResources checks a resource update of a survivor
+func ValidateResources(idNumber string, resources *Resources) error {
+	v := &validator{}
+	v.idNumber("id", idNumber)
+	v.resources(resources)
+
+	return v.err()
+}
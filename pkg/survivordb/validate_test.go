@@ -0,0 +1,109 @@
+package survivordb
+
+import (
+	"testing"
+)
+
+// TestSurvivor_Validate checks if the survivor constraints are enforced
+func TestSurvivor_Validate(t *testing.T) {
+	valid := func() *Survivor {
+		return &Survivor{
+			Name:         "Jane Doe",
+			Age:          31,
+			Gender:       "Female",
+			IdNumber:     "HD138VOP34219",
+			LastLocation: LastLocation{Longitude: 31.05, Latitude: -17.82},
+			Resources:    Resources{Water: 2, Food: "Fish", Ammunition: 10},
+		}
+	}
+
+	testCases := []struct {
+		name   string
+		change func(*Survivor)
+		fields []string
+	}{
+		{"valid", func(s *Survivor) {}, nil},
+		{"empty id", func(s *Survivor) { s.IdNumber = "" }, []string{"id"}},
+		{"long id", func(s *Survivor) { s.IdNumber = "HD138VOP34219HD138VOP34219HD138VOP34219" }, []string{"id"}},
+		{"id with slash", func(s *Survivor) { s.IdNumber = "HD138/VOP34219" }, []string{"id"}},
+		{"blank name", func(s *Survivor) { s.Name = "   " }, []string{"name"}},
+		{"latitude 900", func(s *Survivor) { s.Latitude = 900 }, []string{"latitude"}},
+		{"longitude -181", func(s *Survivor) { s.Longitude = -181 }, []string{"longitude"}},
+		{"negative ammunition", func(s *Survivor) { s.Ammunition = -1 }, []string{"ammunition"}},
+		{"negative age and water", func(s *Survivor) { s.Age, s.Water = -1, -1 }, []string{"age", "water"}},
+		{"bad inventory", func(s *Survivor) {
+			s.Inventory = []InventoryItem{{Type: "fuel", Name: "", Quantity: -1}}
+		}, []string{"inventory[0].type", "inventory[0].name", "inventory[0].quantity"}},
+	}
+
+	for _, tc := range testCases {
+		survivor := valid()
+		tc.change(survivor)
+		err := survivor.Validate()
+		if tc.fields == nil {
+			if err != nil {
+				t.Errorf("Survivor.Validate() - %q: want: %v, got: %v", tc.name, nil, err)
+			}
+			continue
+		}
+		validationErr, ok := err.(*ValidationError)
+		if !ok || len(validationErr.Errors) != len(tc.fields) {
+			t.Errorf("Survivor.Validate() - %q: want: %v, got: %v", tc.name, tc.fields, err)
+			continue
+		}
+		for i, field := range tc.fields {
+			if validationErr.Errors[i].Field != field {
+				t.Errorf("Survivor.Validate() - %q: want: %v, got: %v", tc.name, field, validationErr.Errors[i].Field)
+			}
+		}
+	}
+}
+
+// TestSurvivorPatch_Validate checks if only the fields set on a patch are validated
+func TestSurvivorPatch_Validate(t *testing.T) {
+	age, latitude, name := 31, 45.0, ""
+	if err := (&SurvivorPatch{Age: &age, Latitude: &latitude}).Validate(); err != nil {
+		t.Errorf("SurvivorPatch.Validate(): want: %v, got: %v", nil, err)
+	}
+
+	latitude = -91
+	err := (&SurvivorPatch{Name: &name, Latitude: &latitude}).Validate()
+	validationErr, ok := err.(*ValidationError)
+	if !ok || len(validationErr.Errors) != 2 {
+		t.Errorf("SurvivorPatch.Validate(): want: %v, got: %v", 2, err)
+	}
+}
+
+// TestValidateLocation checks if location updates are validated
+func TestValidateLocation(t *testing.T) {
+	if err := ValidateLocation("HD138VOP34219", LastLocation{Longitude: 180, Latitude: -90}); err != nil {
+		t.Errorf("ValidateLocation(): want: %v, got: %v", nil, err)
+	}
+	err := ValidateLocation("", LastLocation{Latitude: 900})
+	validationErr, ok := err.(*ValidationError)
+	if !ok || len(validationErr.Errors) != 2 {
+		t.Errorf("ValidateLocation(): want: %v, got: %v", 2, err)
+	}
+}
+
+// TestValidateResources checks if resource updates are validated
+func TestValidateResources(t *testing.T) {
+	if err := ValidateResources("HD138VOP34219", &Resources{Water: 1, Food: "Fish"}); err != nil {
+		t.Errorf("ValidateResources(): want: %v, got: %v", nil, err)
+	}
+	err := ValidateResources("HD138VOP34219", &Resources{Ammunition: -5})
+	validationErr, ok := err.(*ValidationError)
+	if !ok || len(validationErr.Errors) != 1 || validationErr.Errors[0].Field != "ammunition" {
+		t.Errorf("ValidateResources(): want: %v, got: %v", "ammunition", err)
+	}
+	err = ValidateResources("HD138VOP34219", &Resources{Inventory: []InventoryItem{
+		{Type: ItemWater, Name: ItemWater, Quantity: 1.5, Unit: "L"},
+		{Type: ItemAmmunition, Name: ItemAmmunition, Quantity: 2.5},
+		{Type: ItemWater, Name: ItemWater, Quantity: 2, Unit: "bottles"},
+	}})
+	validationErr, ok = err.(*ValidationError)
+	if !ok || len(validationErr.Errors) != 2 || validationErr.Errors[0].Field != "inventory[1].quantity" ||
+		validationErr.Errors[1].Field != "inventory[2].unit" {
+		t.Errorf("ValidateResources(): want: %v, got: %v", []string{"inventory[1].quantity", "inventory[2].unit"}, err)
+	}
+}
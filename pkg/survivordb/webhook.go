@@ -0,0 +1,664 @@
+package survivordb
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrInvalidWebhook is returned when a webhook has an invalid url or event type
+	ErrInvalidWebhook = errors.New("invalid webhook")
+	// ErrWebhookNotFound is returned when a webhook id is not in the Webhooks table
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook defines an endpoint that is sent survivor events
+// swagger:model
+type Webhook struct {
+	// the id of the webhook
+	//
+	// required: false
+	ID int64 `json:"id"`
+
+	// the http or https url events are posted to
+	//
+	// required: true
+	URL string `json:"url"`
+
+	// the key the payloads are signed with using HMAC SHA-256. It is
+	// generated when empty and only returned when the webhook is created
+	//
+	// required: false
+	Secret string `json:"secret,omitempty"`
+
+	// the event types to send, all types when empty
+	//
+	// required: false
+	Events []string `json:"events"`
+
+	// no events are sent while the webhook is disabled
+	//
+	// required: false
+	Disabled bool `json:"disabled"`
+}
+
+// Validate checks the url and event types of a webhook
+func (w *Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidWebhook
+	}
+	for _, event := range w.Events {
+		known := false
+		for _, eventType := range EventTypes {
+			known = known || event == eventType
+		}
+		if !known {
+			return ErrInvalidWebhook
+		}
+	}
+
+	return nil
+}
+
+// Subscribed reports whether the webhook is sent events of eventType
+func (w *Webhook) Subscribed(eventType string) bool {
+	if w.Disabled {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, event := range w.Events {
+		if event == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WebhookAttempt defines one attempt to deliver an event to a webhook
+// swagger:model
+type WebhookAttempt struct {
+	// the number of the attempt, starting at 1
+	//
+	// required: true
+	Attempt int `json:"attempt"`
+
+	// the http status code of the response, 0 when there was no response
+	//
+	// required: true
+	StatusCode int `json:"statusCode"`
+
+	// why the attempt failed
+	//
+	// required: false
+	Error string `json:"error,omitempty"`
+
+	// the time of the attempt
+	//
+	// required: true
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// WebhookDelivery defines an event to be delivered to a webhook
+// swagger:model
+type WebhookDelivery struct {
+	// the id of the delivery
+	//
+	// required: true
+	ID int64 `json:"id"`
+
+	// the id of the webhook
+	//
+	// required: true
+	WebhookID int64 `json:"webhookId"`
+
+	// the id of the survivor event
+	//
+	// required: true
+	EventID int64 `json:"eventId"`
+
+	// the type of the survivor event
+	//
+	// required: true
+	EventType string `json:"eventType"`
+
+	// the json body that is posted
+	//
+	// required: true
+	Payload json.RawMessage `json:"payload"`
+
+	// pending, delivered or failed
+	//
+	// required: true
+	Status string `json:"status"`
+
+	// the number of attempts made so far
+	//
+	// required: true
+	Attempts int `json:"attempts"`
+
+	// when the next attempt is due if the delivery is pending
+	//
+	// required: true
+	NextAttempt time.Time `json:"nextAttempt"`
+
+	// every attempt made in order
+	//
+	// required: false
+	History []WebhookAttempt `json:"history,omitempty"`
+
+	// URL and Secret of the webhook, set on due deliveries
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+type webhookStmts struct {
+	insertWebhookStmt    *sql.Stmt
+	updateWebhookStmt    *sql.Stmt
+	selectWebhooksStmt   *sql.Stmt
+	selectWebhookStmt    *sql.Stmt
+	selectCursorStmt     *sql.Stmt
+	updateCursorStmt     *sql.Stmt
+	insertDeliveryStmt   *sql.Stmt
+	selectDueStmt        *sql.Stmt
+	updateDeliveryStmt   *sql.Stmt
+	insertAttemptStmt    *sql.Stmt
+	selectDeliveriesStmt *sql.Stmt
+	selectAttemptsStmt   *sql.Stmt
+}
+
+const (
+	insertWebhookSQL    = `INSERT INTO Webhooks (url, secret, events, disabled) VALUES(?,?,?,?);`
+	updateWebhookSQL    = `UPDATE Webhooks SET url = ?, secret = coalesce(nullif(?, ''), secret), events = ?, disabled = ? WHERE id = ?;`
+	selectWebhooksSQL   = `SELECT id, url, secret, events, disabled FROM Webhooks ORDER BY id;`
+	selectWebhookSQL    = `SELECT id, url, secret, events, disabled FROM Webhooks WHERE id = ?;`
+	deleteWebhookSQL    = `DELETE FROM Webhooks WHERE id = ?;`
+	deleteAttemptsSQL   = `DELETE FROM WebhookAttempts WHERE delivery_id IN (SELECT id FROM WebhookDeliveries WHERE webhook_id = ?);`
+	deleteDeliveriesSQL = `DELETE FROM WebhookDeliveries WHERE webhook_id = ?;`
+	selectCursorSQL     = `SELECT audit_id FROM WebhookCursor WHERE id = 1;`
+	updateCursorSQL     = `UPDATE WebhookCursor SET audit_id = ? WHERE id = 1;`
+	insertDeliverySQL   = `INSERT INTO WebhookDeliveries (webhook_id, event_id, event_type, payload, status, attempts, next_attempt_ts) VALUES(?,?,?,?,?,0,?);`
+	selectDueSQL        = `SELECT d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.next_attempt_ts, w.url, w.secret
+	FROM WebhookDeliveries d JOIN Webhooks w ON w.id = d.webhook_id
+	WHERE d.status = 'pending' AND d.next_attempt_ts <= ? ORDER BY d.next_attempt_ts, d.id LIMIT ?;`
+	updateDeliverySQL   = `UPDATE WebhookDeliveries SET status = ?, attempts = ?, next_attempt_ts = ? WHERE id = ?;`
+	insertAttemptSQL    = `INSERT INTO WebhookAttempts (delivery_id, attempt, status_code, error, attempted_ts) VALUES(?,?,?,?,?);`
+	selectDeliveriesSQL = `SELECT id, webhook_id, event_id, event_type, payload, status, attempts, next_attempt_ts FROM WebhookDeliveries WHERE webhook_id = ? ORDER BY id DESC LIMIT ?;`
+	selectAttemptsSQL   = `SELECT attempt, status_code, error, attempted_ts FROM WebhookAttempts WHERE delivery_id = ? ORDER BY attempt;`
+)
+
+// setupWebhooks prepares the statements of the webhook tables
+func (s *SurvivorDB) setupWebhooks() error {
+	for _, stmt := range []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&s.insertWebhookStmt, insertWebhookSQL},
+		{&s.updateWebhookStmt, updateWebhookSQL},
+		{&s.selectWebhooksStmt, selectWebhooksSQL},
+		{&s.selectWebhookStmt, selectWebhookSQL},
+		{&s.selectCursorStmt, selectCursorSQL},
+		{&s.updateCursorStmt, updateCursorSQL},
+		{&s.insertDeliveryStmt, insertDeliverySQL},
+		{&s.selectDueStmt, selectDueSQL},
+		{&s.updateDeliveryStmt, updateDeliverySQL},
+		{&s.insertAttemptStmt, insertAttemptSQL},
+		{&s.selectDeliveriesStmt, selectDeliveriesSQL},
+		{&s.selectAttemptsStmt, selectAttemptsSQL},
+	} {
+		var err error
+		if *stmt.stmt, err = s.prepare(stmt.query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newWebhookSecret generates a random signing key
+func newWebhookSecret() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(key), nil
+}
+
+// SaveWebhook inserts a webhook into the Webhooks table, generating its
+// secret when it has none
+func (s *SurvivorDB) SaveWebhook(webhook *Webhook) error {
+	if err := webhook.Validate(); err != nil {
+		return err
+	}
+	if webhook.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+			}).Info("Error generating webhook secret")
+			return err
+		}
+		webhook.Secret = secret
+	}
+
+	id, err := s.insert(s.insertWebhookStmt, webhook.URL, webhook.Secret, strings.Join(webhook.Events, ","), webhook.Disabled)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   insertWebhookSQL,
+		}).Info("Sql error")
+		return err
+	}
+	webhook.ID = id
+
+	return nil
+}
+
+// UpdateWebhook replaces the url, events and disabled flag of a webhook. The
+// secret is only replaced when one is given
+func (s *SurvivorDB) UpdateWebhook(webhook *Webhook) error {
+	if err := webhook.Validate(); err != nil {
+		return err
+	}
+
+	result, err := s.updateWebhookStmt.Exec(webhook.URL, webhook.Secret, strings.Join(webhook.Events, ","), webhook.Disabled, webhook.ID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   updateWebhookSQL,
+		}).Info("Sql error")
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// DeleteWebhook deletes a webhook along with its deliveries and attempts
+func (s *SurvivorDB) DeleteWebhook(id int64) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(deleteWebhookSQL, id)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   deleteWebhookSQL,
+		}).Info("Sql error")
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrWebhookNotFound
+	}
+	for _, query := range []string{deleteAttemptsSQL, deleteDeliveriesSQL} {
+		if _, err = tx.Exec(query, id); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   query,
+			}).Info("Sql error")
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+
+	return nil
+}
+
+// scanWebhook reads a row of the Webhooks table
+func scanWebhook(row interface{ Scan(...interface{}) error }) (*Webhook, error) {
+	webhook := &Webhook{}
+	var events string
+	if err := row.Scan(&webhook.ID, &webhook.URL, &webhook.Secret, &events, &webhook.Disabled); err != nil {
+		return nil, err
+	}
+	webhook.Events = []string{}
+	if events != "" {
+		webhook.Events = strings.Split(events, ",")
+	}
+
+	return webhook, nil
+}
+
+// GetWebhooks selects all webhooks including their secrets
+func (s *SurvivorDB) GetWebhooks() []Webhook {
+	return s.getWebhooks(s.selectWebhooksStmt)
+}
+
+// getWebhooks selects all webhooks with stmt, which may belong to a transaction
+func (s *SurvivorDB) getWebhooks(stmt *sql.Stmt) []Webhook {
+	rows, err := stmt.Query()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectWebhooksSQL,
+		}).Info("Sql error")
+		return nil
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectWebhooksSQL,
+			}).Info("Sql error")
+			return nil
+		}
+		webhooks = append(webhooks, *webhook)
+	}
+	if err = rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectWebhooksSQL,
+		}).Info("Sql error")
+		return nil
+	}
+
+	return webhooks
+}
+
+// GetWebhook selects a webhook including its secret
+func (s *SurvivorDB) GetWebhook(id int64) *Webhook {
+	webhook, err := scanWebhook(s.selectWebhookStmt.QueryRow(id))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectWebhookSQL,
+			}).Info("Sql error")
+		}
+		return nil
+	}
+
+	return webhook
+}
+
+// EnqueueWebhookDeliveries turns the survivor changes recorded since the
+// last call into pending deliveries for every subscribed webhook. At most
+// limit audit events are read per call. It returns the number of deliveries
+// added
+func (s *SurvivorDB) EnqueueWebhookDeliveries(limit int) (int, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var cursor int64
+	if err = tx.Stmt(s.selectCursorStmt).QueryRow().Scan(&cursor); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectCursorSQL,
+		}).Info("Sql error")
+		return 0, err
+	}
+
+	rows, err := tx.Stmt(s.selectAuditAfterStmt).Query(cursor, limit)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectAuditAfterSQL,
+		}).Info("Sql error")
+		return 0, err
+	}
+	auditEvents, err := scanAuditEvents(rows, selectAuditAfterSQL)
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+	if len(auditEvents) == 0 {
+		return 0, nil
+	}
+
+	webhooks := s.getWebhooks(tx.Stmt(s.selectWebhooksStmt))
+	if webhooks == nil {
+		return 0, errors.New("could not read webhooks")
+	}
+
+	now := time.Now().UTC().Format(sqliteTimeFormat)
+	insertDeliveryStmt := tx.Stmt(s.insertDeliveryStmt)
+	count := 0
+	for _, auditEvent := range auditEvents {
+		for _, event := range auditEvent.SurvivorEvents() {
+			payload, err := json.Marshal(event)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"body":  event,
+					"Error": err,
+				}).Error("Marshal")
+				return 0, err
+			}
+			for _, webhook := range webhooks {
+				if !webhook.Subscribed(event.Type) {
+					continue
+				}
+				_, err = insertDeliveryStmt.Exec(webhook.ID, event.ID, event.Type, string(payload), DeliveryPending, now)
+				if err != nil {
+					logrus.WithFields(logrus.Fields{
+						"Error": err,
+						"sql":   insertDeliverySQL,
+					}).Info("Sql error")
+					return 0, err
+				}
+				count++
+			}
+		}
+	}
+
+	_, err = tx.Stmt(s.updateCursorStmt).Exec(auditEvents[len(auditEvents)-1].ID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   updateCursorSQL,
+		}).Info("Sql error")
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// scanDeliveries reads the rows of a WebhookDeliveries query, with the url
+// and secret of the webhook when withWebhook is set
+func scanDeliveries(rows *sql.Rows, query string, withWebhook bool) ([]WebhookDelivery, error) {
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		delivery := WebhookDelivery{}
+		var payload string
+		columns := []interface{}{
+			&delivery.ID,
+			&delivery.WebhookID,
+			&delivery.EventID,
+			&delivery.EventType,
+			&payload,
+			&delivery.Status,
+			&delivery.Attempts,
+			&delivery.NextAttempt,
+		}
+		if withWebhook {
+			columns = append(columns, &delivery.URL, &delivery.Secret)
+		}
+		if err := rows.Scan(columns...); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   query,
+			}).Info("Sql error")
+			return nil, err
+		}
+		delivery.Payload = json.RawMessage(payload)
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   query,
+		}).Info("Sql error")
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// GetDueWebhookDeliveries selects at most limit pending deliveries whose
+// next attempt is due at now, with the url and secret of their webhook
+func (s *SurvivorDB) GetDueWebhookDeliveries(now time.Time, limit int) ([]WebhookDelivery, error) {
+	rows, err := s.selectDueStmt.Query(now.UTC().Format(sqliteTimeFormat), limit)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectDueSQL,
+		}).Info("Sql error")
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanDeliveries(rows, selectDueSQL, true)
+}
+
+// RecordWebhookAttempt stores an attempt to deliver an event and moves the
+// delivery to status, with its next attempt due at next when it is pending
+func (s *SurvivorDB) RecordWebhookAttempt(deliveryID int64, attempt *WebhookAttempt, status string, next time.Time) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Stmt(s.insertAttemptStmt).Exec(deliveryID,
+		attempt.Attempt,
+		attempt.StatusCode,
+		attempt.Error,
+		attempt.Timestamp.UTC().Format(sqliteTimeFormat))
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   insertAttemptSQL,
+		}).Info("Sql error")
+		return err
+	}
+	_, err = tx.Stmt(s.updateDeliveryStmt).Exec(status, attempt.Attempt, next.UTC().Format(sqliteTimeFormat), deliveryID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   updateDeliverySQL,
+		}).Info("Sql error")
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries selects the last limit deliveries of a webhook, newest
+// first, with the history of their attempts
+func (s *SurvivorDB) GetWebhookDeliveries(webhookID int64, limit int) []WebhookDelivery {
+	rows, err := s.selectDeliveriesStmt.Query(webhookID, limit)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectDeliveriesSQL,
+		}).Info("Sql error")
+		return nil
+	}
+	deliveries, err := scanDeliveries(rows, selectDeliveriesSQL, false)
+	rows.Close()
+	if err != nil {
+		return nil
+	}
+
+	for i := range deliveries {
+		if deliveries[i].History = s.getWebhookAttempts(deliveries[i].ID); deliveries[i].History == nil {
+			return nil
+		}
+	}
+
+	return deliveries
+}
+
+// getWebhookAttempts selects the attempts of a delivery in order
+func (s *SurvivorDB) getWebhookAttempts(deliveryID int64) []WebhookAttempt {
+	rows, err := s.selectAttemptsStmt.Query(deliveryID)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectAttemptsSQL,
+		}).Info("Sql error")
+		return nil
+	}
+	defer rows.Close()
+
+	attempts := []WebhookAttempt{}
+	for rows.Next() {
+		attempt := WebhookAttempt{}
+		if err = rows.Scan(&attempt.Attempt, &attempt.StatusCode, &attempt.Error, &attempt.Timestamp); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectAttemptsSQL,
+			}).Info("Sql error")
+			return nil
+		}
+		attempts = append(attempts, attempt)
+	}
+	if err = rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectAttemptsSQL,
+		}).Info("Sql error")
+		return nil
+	}
+
+	return attempts
+}
@@ -0,0 +1,128 @@
+package survivordb
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// TestSurvivorDB_Webhooks checks if webhooks can be created, updated and deleted
+func TestSurvivorDB_Webhooks(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	for _, webhook := range []*Webhook{
+		{URL: "ftp://example.com/hook"},
+		{URL: "https://"},
+		{URL: "https://example.com/hook", Events: []string{"survivor.eaten"}},
+	} {
+		if err = survivordb.SaveWebhook(webhook); err != ErrInvalidWebhook {
+			t.Errorf("SurvivorDB.SaveWebhook() - %q: want: %v, got: %v", webhook.URL, ErrInvalidWebhook, err)
+		}
+	}
+
+	webhook := &Webhook{URL: "https://example.com/hook", Events: []string{EventSurvivorInfected}}
+	if err = survivordb.SaveWebhook(webhook); err != nil {
+		t.Errorf("SurvivorDB.SaveWebhook(): want: %v, got: %v", nil, err)
+		return
+	}
+	if webhook.ID == 0 || len(webhook.Secret) != 64 {
+		t.Errorf("SurvivorDB.SaveWebhook(): want an id and a generated secret, got: %v", webhook)
+	}
+
+	updated := &Webhook{ID: webhook.ID, URL: "http://example.com/other", Disabled: true}
+	if err = survivordb.UpdateWebhook(updated); err != nil {
+		t.Errorf("SurvivorDB.UpdateWebhook(): want: %v, got: %v", nil, err)
+	}
+	got := survivordb.GetWebhook(webhook.ID)
+	if got == nil || got.URL != updated.URL || got.Secret != webhook.Secret || len(got.Events) != 0 || !got.Disabled {
+		t.Errorf("SurvivorDB.GetWebhook(): want: %v with the old secret, got: %v", updated, got)
+	}
+	if err = survivordb.UpdateWebhook(&Webhook{ID: 99, URL: "http://example.com"}); err != ErrWebhookNotFound {
+		t.Errorf("SurvivorDB.UpdateWebhook(): want: %v, got: %v", ErrWebhookNotFound, err)
+	}
+
+	if webhooks := survivordb.GetWebhooks(); len(webhooks) != 1 {
+		t.Errorf("SurvivorDB.GetWebhooks(): want: %v, got: %v", 1, webhooks)
+	}
+	if err = survivordb.DeleteWebhook(webhook.ID); err != nil {
+		t.Errorf("SurvivorDB.DeleteWebhook(): want: %v, got: %v", nil, err)
+	}
+	if err = survivordb.DeleteWebhook(webhook.ID); err != ErrWebhookNotFound {
+		t.Errorf("SurvivorDB.DeleteWebhook(): want: %v, got: %v", ErrWebhookNotFound, err)
+	}
+	if got = survivordb.GetWebhook(webhook.ID); got != nil {
+		t.Errorf("SurvivorDB.GetWebhook(): want: %v, got: %v", nil, got)
+	}
+}
+
+// TestSurvivorDB_WebhookDeliveries checks if survivor changes become deliveries
+// for the subscribed webhooks and record their attempts
+func TestSurvivorDB_WebhookDeliveries(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	all := &Webhook{URL: "https://example.com/all"}
+	located := &Webhook{URL: "https://example.com/located", Events: []string{EventLocationUpdated}}
+	disabled := &Webhook{URL: "https://example.com/disabled", Disabled: true}
+	for _, webhook := range []*Webhook{all, located, disabled} {
+		if err = survivordb.SaveWebhook(webhook); err != nil {
+			t.Errorf("SurvivorDB.SaveWebhook(): want: %v, got: %v", nil, err)
+			return
+		}
+	}
+
+	survivor := &Survivor{Name: "Jane Doe", Age: 1, Gender: "Female", IdNumber: "HD138VOP34219"}
+	if err = survivordb.Save(survivor); err != nil {
+		t.Errorf("SurvivorDB.Save(): want: %v, got: %v", nil, err)
+	}
+	if _, err = survivordb.UpdateLocation("HD138VOP34219", 1, 2); err != nil {
+		t.Errorf("SurvivorDB.UpdateLocation(): want: %v, got: %v", nil, err)
+	}
+
+	count, err := survivordb.EnqueueWebhookDeliveries(100)
+	if err != nil || count != 3 {
+		t.Errorf("SurvivorDB.EnqueueWebhookDeliveries(): want: %v, got: %v, %v", 3, count, err)
+	}
+	if count, err = survivordb.EnqueueWebhookDeliveries(100); err != nil || count != 0 {
+		t.Errorf("SurvivorDB.EnqueueWebhookDeliveries(): want: %v, got: %v, %v", 0, count, err)
+	}
+
+	due, err := survivordb.GetDueWebhookDeliveries(time.Now(), 100)
+	if err != nil || len(due) != 3 {
+		t.Errorf("SurvivorDB.GetDueWebhookDeliveries(): want: %v, got: %v, %v", 3, due, err)
+		return
+	}
+	if due[0].URL == "" || due[0].Secret == "" || due[0].Status != DeliveryPending || len(due[0].Payload) == 0 {
+		t.Errorf("SurvivorDB.GetDueWebhookDeliveries(): got: %v", due[0])
+	}
+
+	for _, delivery := range due {
+		attempt := &WebhookAttempt{Attempt: 1, StatusCode: 500, Error: "unexpected status", Timestamp: time.Now()}
+		if err = survivordb.RecordWebhookAttempt(delivery.ID, attempt, DeliveryPending, time.Now().Add(time.Hour)); err != nil {
+			t.Errorf("SurvivorDB.RecordWebhookAttempt(): want: %v, got: %v", nil, err)
+		}
+	}
+	if due, err = survivordb.GetDueWebhookDeliveries(time.Now(), 100); err != nil || len(due) != 0 {
+		t.Errorf("SurvivorDB.GetDueWebhookDeliveries(): want: %v, got: %v, %v", 0, due, err)
+	}
+
+	deliveries := survivordb.GetWebhookDeliveries(located.ID, 100)
+	if len(deliveries) != 1 || deliveries[0].EventType != EventLocationUpdated || deliveries[0].Attempts != 1 ||
+		len(deliveries[0].History) != 1 || deliveries[0].History[0].StatusCode != 500 {
+		t.Errorf("SurvivorDB.GetWebhookDeliveries(): got: %v", deliveries)
+	}
+	if deliveries = survivordb.GetWebhookDeliveries(disabled.ID, 100); len(deliveries) != 0 {
+		t.Errorf("SurvivorDB.GetWebhookDeliveries(): want: %v, got: %v", 0, deliveries)
+	}
+}
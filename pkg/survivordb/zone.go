@@ -0,0 +1,703 @@
+package survivordb
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Zone kinds
+const (
+	ZoneCircle  = "circle"
+	ZonePolygon = "polygon"
+)
+
+// Zone events
+const (
+	ZoneEnter = "enter"
+	ZoneExit  = "exit"
+)
+
+var (
+	// ErrInvalidZone is returned when a zone has no name or an invalid shape
+	ErrInvalidZone = errors.New("invalid zone")
+	// ErrDuplicateZone is returned when a zone name is already in use
+	ErrDuplicateZone = errors.New("zone name already in use")
+	// ErrZoneNotFound is returned when a zone id is not in the Zones table
+	ErrZoneNotFound = errors.New("zone not found")
+)
+
+// Zone defines a named safe zone, either a circle or a polygon
+// swagger:model
+type Zone struct {
+	// the id of the zone
+	//
+	// required: false
+	ID int64 `json:"id"`
+
+	// the name of the zone
+	//
+	// required: true
+	// max length: 128
+	Name string `json:"name"`
+
+	// the shape of the zone: circle or polygon
+	//
+	// required: true
+	Kind string `json:"kind"`
+
+	// the centre of a circle zone
+	//
+	// required: false
+	Center *LastLocation `json:"center,omitempty"`
+
+	// the radius of a circle zone in km
+	//
+	// required: false
+	Radius float64 `json:"radius,omitempty"`
+
+	// the vertices of a polygon zone
+	//
+	// required: false
+	Polygon []LastLocation `json:"polygon,omitempty"`
+}
+
+// Validate checks the zone has a name and a well formed shape
+func (z *Zone) Validate() error {
+	if z.Name == "" || len(z.Name) > 128 {
+		return ErrInvalidZone
+	}
+	validPoint := func(p LastLocation) bool {
+		return p.Latitude >= -90 && p.Latitude <= 90 && p.Longitude >= -180 && p.Longitude <= 180
+	}
+	switch z.Kind {
+	case ZoneCircle:
+		if z.Center == nil || !validPoint(*z.Center) || z.Radius <= 0 || len(z.Polygon) > 0 {
+			return ErrInvalidZone
+		}
+	case ZonePolygon:
+		if len(z.Polygon) < 3 || z.Center != nil || z.Radius != 0 {
+			return ErrInvalidZone
+		}
+		for _, p := range z.Polygon {
+			if !validPoint(p) {
+				return ErrInvalidZone
+			}
+		}
+	default:
+		return ErrInvalidZone
+	}
+
+	return nil
+}
+
+// Contains reports whether a point is inside the zone
+func (z *Zone) Contains(longitude, latitude float64) bool {
+	switch z.Kind {
+	case ZoneCircle:
+		return Distance(z.Center.Latitude, z.Center.Longitude, latitude, longitude) <= z.Radius
+	case ZonePolygon:
+		inside := false
+		for i, j := 0, len(z.Polygon)-1; i < len(z.Polygon); j, i = i, i+1 {
+			pi, pj := z.Polygon[i], z.Polygon[j]
+			if (pi.Latitude > latitude) != (pj.Latitude > latitude) &&
+				longitude < (pj.Longitude-pi.Longitude)*(latitude-pi.Latitude)/(pj.Latitude-pi.Latitude)+pi.Longitude {
+				inside = !inside
+			}
+		}
+		return inside
+	}
+
+	return false
+}
+
+// ZoneEvent defines a survivor entering or leaving a zone
+// swagger:model
+type ZoneEvent struct {
+	// the id of the zone
+	ZoneID int64 `json:"zoneId"`
+
+	// the name of the zone
+	ZoneName string `json:"zoneName"`
+
+	// the id number of the survivor
+	IdNumber string `json:"id"`
+
+	// enter or exit
+	Event string `json:"event"`
+
+	LastLocation
+
+	// the time of the event
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type zoneStmts struct {
+	insertZoneStmt       *sql.Stmt
+	updateZoneStmt       *sql.Stmt
+	deleteZoneStmt       *sql.Stmt
+	selectZonesStmt      *sql.Stmt
+	selectZoneStmt       *sql.Stmt
+	countZoneNameStmt    *sql.Stmt
+	selectOccupiedStmt   *sql.Stmt
+	insertOccupantStmt   *sql.Stmt
+	deleteOccupantStmt   *sql.Stmt
+	deleteOccupantsStmt  *sql.Stmt
+	selectOccupantsStmt  *sql.Stmt
+	insertZoneEventStmt  *sql.Stmt
+	deleteZoneEventsStmt *sql.Stmt
+	selectZoneEventsStmt *sql.Stmt
+	selectPositionsStmt  *sql.Stmt
+}
+
+const (
+	insertZoneSQL       = `INSERT INTO Zones (name, kind, latitude, longitude, radius, polygon) VALUES(?,?,?,?,?,?);`
+	updateZoneSQL       = `UPDATE Zones SET name = ?, kind = ?, latitude = ?, longitude = ?, radius = ?, polygon = ? WHERE id = ?;`
+	deleteZoneSQL       = `DELETE FROM Zones WHERE id = ?;`
+	selectZonesSQL      = `SELECT id, name, kind, latitude, longitude, radius, polygon FROM Zones ORDER BY id;`
+	selectZoneSQL       = `SELECT id, name, kind, latitude, longitude, radius, polygon FROM Zones WHERE id = ?;`
+	countZoneNameSQL    = `SELECT count(*) FROM Zones WHERE name = ? AND id != ?;`
+	selectOccupiedSQL   = `SELECT zone_id FROM ZoneOccupants WHERE survivor_id = ?;`
+	insertOccupantSQL   = `INSERT INTO ZoneOccupants (zone_id, survivor_id) VALUES(?,?);`
+	deleteOccupantSQL   = `DELETE FROM ZoneOccupants WHERE zone_id = ? AND survivor_id = ?;`
+	deleteOccupantsSQL  = `DELETE FROM ZoneOccupants WHERE zone_id = ?;`
+	selectOccupantsSQL  = `SELECT s.name, s.age, s.gender, s.id_number, s.longitude, s.latitude, s.water, s.food, s.medication, s.ammunition, s.infected, s.last_ts FROM ZoneOccupants o JOIN Survivors s ON s.id_number = o.survivor_id WHERE o.zone_id = ? ORDER BY o.entered_ts, s.id;`
+	insertZoneEventSQL  = `INSERT INTO ZoneEvents (zone_id, survivor_id, event, longitude, latitude) VALUES(?,?,?,?,?);`
+	deleteZoneEventsSQL = `DELETE FROM ZoneEvents WHERE zone_id = ?;`
+	selectZoneEventsSQL = `SELECT e.zone_id, z.name, e.survivor_id, e.event, e.longitude, e.latitude, e.event_ts FROM ZoneEvents e JOIN Zones z ON z.id = e.zone_id WHERE e.zone_id = ? ORDER BY e.event_ts, e.id;`
+	selectPositionsSQL  = `SELECT id_number, longitude, latitude FROM Survivors ORDER BY id;`
+)
+
+// setupZones prepares the statements of the Zones, ZoneOccupants and ZoneEvents tables
+func (s *SurvivorDB) setupZones() error {
+	for _, stmt := range []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&s.insertZoneStmt, insertZoneSQL},
+		{&s.updateZoneStmt, updateZoneSQL},
+		{&s.deleteZoneStmt, deleteZoneSQL},
+		{&s.selectZonesStmt, selectZonesSQL},
+		{&s.selectZoneStmt, selectZoneSQL},
+		{&s.countZoneNameStmt, countZoneNameSQL},
+		{&s.selectOccupiedStmt, selectOccupiedSQL},
+		{&s.insertOccupantStmt, insertOccupantSQL},
+		{&s.deleteOccupantStmt, deleteOccupantSQL},
+		{&s.deleteOccupantsStmt, deleteOccupantsSQL},
+		{&s.selectOccupantsStmt, selectOccupantsSQL},
+		{&s.insertZoneEventStmt, insertZoneEventSQL},
+		{&s.deleteZoneEventsStmt, deleteZoneEventsSQL},
+		{&s.selectZoneEventsStmt, selectZoneEventsSQL},
+		{&s.selectPositionsStmt, selectPositionsSQL},
+	} {
+		var err error
+		if *stmt.stmt, err = s.prepare(stmt.query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// zoneColumns returns the column values of a zone for the Zones table
+func zoneColumns(zone *Zone) ([]interface{}, error) {
+	var latitude, longitude, radius sql.NullFloat64
+	var polygon sql.NullString
+	if zone.Center != nil {
+		latitude = sql.NullFloat64{Float64: zone.Center.Latitude, Valid: true}
+		longitude = sql.NullFloat64{Float64: zone.Center.Longitude, Valid: true}
+		radius = sql.NullFloat64{Float64: zone.Radius, Valid: true}
+	}
+	if len(zone.Polygon) > 0 {
+		buffer, err := json.Marshal(zone.Polygon)
+		if err != nil {
+			return nil, err
+		}
+		polygon = sql.NullString{String: string(buffer), Valid: true}
+	}
+
+	return []interface{}{zone.Name, zone.Kind, latitude, longitude, radius, polygon}, nil
+}
+
+// scanZone reads a zone from a row of the Zones table
+func scanZone(row interface{ Scan(...interface{}) error }) (*Zone, error) {
+	zone := &Zone{}
+	var latitude, longitude, radius sql.NullFloat64
+	var polygon sql.NullString
+	if err := row.Scan(&zone.ID, &zone.Name, &zone.Kind, &latitude, &longitude, &radius, &polygon); err != nil {
+		return nil, err
+	}
+	if latitude.Valid && longitude.Valid {
+		zone.Center = &LastLocation{Latitude: latitude.Float64, Longitude: longitude.Float64}
+		zone.Radius = radius.Float64
+	}
+	if polygon.Valid {
+		if err := json.Unmarshal([]byte(polygon.String), &zone.Polygon); err != nil {
+			return nil, err
+		}
+	}
+
+	return zone, nil
+}
+
+// checkZoneName returns ErrDuplicateZone when another zone already has the name
+func (s *SurvivorDB) checkZoneName(zone *Zone) error {
+	count := 0
+	err := s.countZoneNameStmt.QueryRow(zone.Name, zone.ID).Scan(&count)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   countZoneNameSQL,
+		}).Info("Sql error")
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateZone
+	}
+
+	return nil
+}
+
+// SaveZone inserts a zone into the Zones table, sets its id and records
+// every survivor already inside it as entering it
+func (s *SurvivorDB) SaveZone(zone *Zone) error {
+	if err := zone.Validate(); err != nil {
+		return err
+	}
+	zone.ID = 0
+	if err := s.checkZoneName(zone); err != nil {
+		return err
+	}
+
+	columns, err := zoneColumns(zone)
+	if err != nil {
+		return err
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+	defer tx.Rollback()
+
+	zone.ID, err = s.insert(tx.Stmt(s.insertZoneStmt), columns...)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   insertZoneSQL,
+		}).Info("Sql error")
+		return err
+	}
+	if err = s.occupyZoneTx(tx, zone); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+
+	return nil
+}
+
+// UpdateZone replaces the definition of a zone and records the survivors
+// the new shape takes in or leaves out as entering or leaving it
+func (s *SurvivorDB) UpdateZone(zone *Zone) error {
+	if err := zone.Validate(); err != nil {
+		return err
+	}
+	if err := s.checkZoneName(zone); err != nil {
+		return err
+	}
+
+	columns, err := zoneColumns(zone)
+	if err != nil {
+		return err
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Stmt(s.updateZoneStmt).Exec(append(columns, zone.ID)...)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   updateZoneSQL,
+		}).Info("Sql error")
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrZoneNotFound
+	}
+	if err = s.occupyZoneTx(tx, zone); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+
+	return nil
+}
+
+// occupyZoneTx checks the current location of every survivor against a zone
+// inside a transaction
+func (s *SurvivorDB) occupyZoneTx(tx *sql.Tx, zone *Zone) error {
+	rows, err := tx.Stmt(s.selectPositionsStmt).Query()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectPositionsSQL,
+		}).Info("Sql error")
+		return err
+	}
+	type position struct {
+		idNumber string
+		LastLocation
+	}
+	positions := []position{}
+	for rows.Next() {
+		p := position{}
+		if err = rows.Scan(&p.idNumber, &p.Longitude, &p.Latitude); err != nil {
+			rows.Close()
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectPositionsSQL,
+			}).Info("Sql error")
+			return err
+		}
+		positions = append(positions, p)
+	}
+	rows.Close()
+
+	for _, p := range positions {
+		if _, err = s.occupancyTx(tx, []Zone{*zone}, p.idNumber, p.Longitude, p.Latitude); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteZone deletes a zone along with its occupants and events
+func (s *SurvivorDB) DeleteZone(id int64) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Stmt(s.deleteZoneStmt).Exec(id)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   deleteZoneSQL,
+		}).Info("Sql error")
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrZoneNotFound
+	}
+	for _, stmt := range []struct {
+		stmt  *sql.Stmt
+		query string
+	}{
+		{s.deleteOccupantsStmt, deleteOccupantsSQL},
+		{s.deleteZoneEventsStmt, deleteZoneEventsSQL},
+	} {
+		if _, err = tx.Stmt(stmt.stmt).Exec(id); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   stmt.query,
+			}).Info("Sql error")
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return err
+	}
+
+	return nil
+}
+
+// GetZones selects all zones stored in the Zones table
+func (s *SurvivorDB) GetZones() []Zone {
+	rows, err := s.selectZonesStmt.Query()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectZonesSQL,
+		}).Info("Sql error")
+		return nil
+	}
+	defer rows.Close()
+
+	zones := []Zone{}
+	for rows.Next() {
+		zone, err := scanZone(rows)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectZonesSQL,
+			}).Info("Sql error")
+			return nil
+		}
+		zones = append(zones, *zone)
+	}
+	if err = rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectZonesSQL,
+		}).Info("Sql error")
+		return nil
+	}
+
+	return zones
+}
+
+// GetZone selects a zone by id
+func (s *SurvivorDB) GetZone(id int64) *Zone {
+	zone, err := scanZone(s.selectZoneStmt.QueryRow(id))
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectZoneSQL,
+			}).Info("Sql error")
+		}
+		return nil
+	}
+
+	return zone
+}
+
+// UpdateZoneOccupancy checks a survivor location against every zone and
+// records an enter or exit event for each zone the survivor crossed into or
+// out of
+func (s *SurvivorDB) UpdateZoneOccupancy(idNumber string, longitude, latitude float64) ([]ZoneEvent, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	events, err := s.moveTx(tx, idNumber, longitude, latitude)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+		}).Info("Sql error")
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// moveTx checks a survivor location against every zone inside a transaction
+func (s *SurvivorDB) moveTx(tx *sql.Tx, idNumber string, longitude, latitude float64) ([]ZoneEvent, error) {
+	rows, err := tx.Stmt(s.selectZonesStmt).Query()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectZonesSQL,
+		}).Info("Sql error")
+		return nil, err
+	}
+	zones := []Zone{}
+	for rows.Next() {
+		zone, err := scanZone(rows)
+		if err != nil {
+			rows.Close()
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectZonesSQL,
+			}).Info("Sql error")
+			return nil, err
+		}
+		zones = append(zones, *zone)
+	}
+	rows.Close()
+
+	return s.occupancyTx(tx, zones, idNumber, longitude, latitude)
+}
+
+// occupancyTx checks a survivor location against zones inside a transaction
+// and records an enter or exit event for each zone the survivor crossed into
+// or out of
+func (s *SurvivorDB) occupancyTx(tx *sql.Tx, zones []Zone, idNumber string, longitude, latitude float64) ([]ZoneEvent, error) {
+	rows, err := tx.Stmt(s.selectOccupiedStmt).Query(idNumber)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectOccupiedSQL,
+		}).Info("Sql error")
+		return nil, err
+	}
+	occupied := map[int64]bool{}
+	for rows.Next() {
+		var zoneID int64
+		if err = rows.Scan(&zoneID); err != nil {
+			rows.Close()
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectOccupiedSQL,
+			}).Info("Sql error")
+			return nil, err
+		}
+		occupied[zoneID] = true
+	}
+	rows.Close()
+
+	now := time.Now().UTC()
+	events := []ZoneEvent{}
+	for _, zone := range zones {
+		inside := zone.Contains(longitude, latitude)
+		if inside == occupied[zone.ID] {
+			continue
+		}
+
+		event := ZoneEvent{
+			ZoneID:       zone.ID,
+			ZoneName:     zone.Name,
+			IdNumber:     idNumber,
+			Event:        ZoneEnter,
+			LastLocation: LastLocation{Longitude: longitude, Latitude: latitude},
+			Timestamp:    now,
+		}
+		stmt, query := s.insertOccupantStmt, insertOccupantSQL
+		if !inside {
+			event.Event = ZoneExit
+			stmt, query = s.deleteOccupantStmt, deleteOccupantSQL
+		}
+		if _, err = tx.Stmt(stmt).Exec(zone.ID, idNumber); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   query,
+			}).Info("Sql error")
+			return nil, err
+		}
+		_, err = tx.Stmt(s.insertZoneEventStmt).Exec(zone.ID, idNumber, event.Event, longitude, latitude)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   insertZoneEventSQL,
+			}).Info("Sql error")
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// GetZoneOccupants selects the survivors currently inside a zone
+func (s *SurvivorDB) GetZoneOccupants(id int64) []Survivor {
+	rows, err := s.selectOccupantsStmt.Query(id)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectOccupantsSQL,
+		}).Info("Sql error")
+		return nil
+	}
+	defer rows.Close()
+
+	survivors := []Survivor{}
+	for rows.Next() {
+		survivor := Survivor{}
+		err = rows.Scan(&survivor.Name,
+			&survivor.Age,
+			&survivor.Gender,
+			&survivor.IdNumber,
+			&survivor.Longitude,
+			&survivor.Latitude,
+			&survivor.Water,
+			&survivor.Food,
+			&survivor.Medication,
+			&survivor.Ammunition,
+			&survivor.Infected,
+			&survivor.LastUpdateTime)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectOccupantsSQL,
+			}).Info("Sql error")
+			return nil
+		}
+		survivors = append(survivors, survivor)
+	}
+	if err = rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectOccupantsSQL,
+		}).Info("Sql error")
+		return nil
+	}
+
+	return s.attachInventory(survivors)
+}
+
+// GetZoneEvents selects the enter and exit events of a zone in time order
+func (s *SurvivorDB) GetZoneEvents(id int64) []ZoneEvent {
+	rows, err := s.selectZoneEventsStmt.Query(id)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectZoneEventsSQL,
+		}).Info("Sql error")
+		return nil
+	}
+	defer rows.Close()
+
+	events := []ZoneEvent{}
+	for rows.Next() {
+		event := ZoneEvent{}
+		err = rows.Scan(&event.ZoneID, &event.ZoneName, &event.IdNumber, &event.Event,
+			&event.Longitude, &event.Latitude, &event.Timestamp)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+				"sql":   selectZoneEventsSQL,
+			}).Info("Sql error")
+			return nil
+		}
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"Error": err,
+			"sql":   selectZoneEventsSQL,
+		}).Info("Sql error")
+		return nil
+	}
+
+	return events
+}
@@ -0,0 +1,106 @@
+package survivordb
+
+import (
+	"os"
+	"testing"
+)
+
+// TestZone_Contains checks if points are found inside circle and polygon zones
+func TestZone_Contains(t *testing.T) {
+	circle := &Zone{Name: "Camp", Kind: ZoneCircle, Center: &LastLocation{}, Radius: 2}
+	polygon := &Zone{Name: "Farm", Kind: ZonePolygon, Polygon: []LastLocation{
+		{Longitude: 1, Latitude: 1},
+		{Longitude: 3, Latitude: 1},
+		{Longitude: 3, Latitude: 3},
+		{Longitude: 1, Latitude: 3},
+	}}
+
+	testCases := []struct {
+		zone      *Zone
+		longitude float64
+		latitude  float64
+		want      bool
+	}{
+		{circle, 0, 0, true},
+		{circle, 0, 0.01, true},
+		{circle, 0, 0.1, false},
+		{polygon, 2, 2, true},
+		{polygon, 0, 2, false},
+		{polygon, 2, 4, false},
+	}
+	for _, tc := range testCases {
+		if got := tc.zone.Contains(tc.longitude, tc.latitude); got != tc.want {
+			t.Errorf("Zone.Contains(%v, %v) - %q: want: %v, got: %v", tc.longitude, tc.latitude, tc.zone.Name, tc.want, got)
+		}
+	}
+}
+
+// TestSurvivorDB_UpdateZoneOccupancy checks if entering and leaving zones is recorded
+func TestSurvivorDB_UpdateZoneOccupancy(t *testing.T) {
+	os.Remove("./test.db")
+	survivordb := Open("./test.db")
+	err := survivordb.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	zone := &Zone{Name: "Camp", Kind: ZoneCircle, Center: &LastLocation{Longitude: 10, Latitude: 10}, Radius: 2}
+	if err = survivordb.SaveZone(zone); err != nil || zone.ID == 0 {
+		t.Errorf("SurvivorDB.SaveZone(): want: %v, got: %v", nil, err)
+		return
+	}
+	if err = survivordb.SaveZone(&Zone{Name: "Camp", Kind: ZoneCircle, Center: &LastLocation{}, Radius: 1}); err != ErrDuplicateZone {
+		t.Errorf("SurvivorDB.SaveZone(): want: %v, got: %v", ErrDuplicateZone, err)
+	}
+	if err = survivordb.SaveZone(&Zone{Name: "Line", Kind: ZonePolygon, Polygon: []LastLocation{{}, {}}}); err != ErrInvalidZone {
+		t.Errorf("SurvivorDB.SaveZone(): want: %v, got: %v", ErrInvalidZone, err)
+	}
+
+	survivor := &Survivor{Name: "Jane Doe", Age: 1, Gender: "Female", IdNumber: "HD138VOP34219"}
+	if err = survivordb.Save(survivor); err != nil {
+		t.Errorf("SurvivorDB.Save() - %q: want: %v, got: %v", survivor.Name, nil, err)
+	}
+
+	testCases := []struct {
+		longitude float64
+		latitude  float64
+		want      string
+	}{
+		{10, 10, ZoneEnter},
+		{10, 10.001, ""},
+		{0, 0, ZoneExit},
+		{0, 0, ""},
+	}
+	for _, tc := range testCases {
+		events, err := survivordb.UpdateZoneOccupancy(survivor.IdNumber, tc.longitude, tc.latitude)
+		if err != nil {
+			t.Errorf("SurvivorDB.UpdateZoneOccupancy(): want: %v, got: %v", nil, err)
+			continue
+		}
+		got := ""
+		if len(events) > 0 {
+			got = events[0].Event
+		}
+		if got != tc.want || len(events) > 1 {
+			t.Errorf("SurvivorDB.UpdateZoneOccupancy(%v, %v): want: %q, got: %v", tc.longitude, tc.latitude, tc.want, events)
+		}
+		if tc.want == ZoneEnter && len(survivordb.GetZoneOccupants(zone.ID)) != 1 {
+			t.Errorf("SurvivorDB.GetZoneOccupants(): want: %v, got: %v", 1, survivordb.GetZoneOccupants(zone.ID))
+		}
+	}
+
+	if occupants := survivordb.GetZoneOccupants(zone.ID); len(occupants) != 0 {
+		t.Errorf("SurvivorDB.GetZoneOccupants(): want: %v, got: %v", 0, occupants)
+	}
+	if events := survivordb.GetZoneEvents(zone.ID); len(events) != 2 || events[1].Event != ZoneExit {
+		t.Errorf("SurvivorDB.GetZoneEvents(): want: %v, got: %v", 2, events)
+	}
+
+	if err = survivordb.DeleteZone(zone.ID); err != nil {
+		t.Errorf("SurvivorDB.DeleteZone(): want: %v, got: %v", nil, err)
+	}
+	if err = survivordb.DeleteZone(zone.ID); err != ErrZoneNotFound {
+		t.Errorf("SurvivorDB.DeleteZone(): want: %v, got: %v", ErrZoneNotFound, err)
+	}
+}
@@ -0,0 +1,196 @@
+// Package webhook delivers survivor events to the registered webhooks
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"robo-apocalypse/pkg/survivordb"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Request headers sent with every delivery
+const (
+	EventHeader     = "X-Apocalypse-Event"
+	DeliveryHeader  = "X-Apocalypse-Delivery"
+	SignatureHeader = "X-Apocalypse-Signature"
+)
+
+// Defaults used for the zero fields of a Worker
+const (
+	DefaultPollInterval = 5 * time.Second
+	DefaultMaxAttempts  = 8
+	DefaultBackoff      = 10 * time.Second
+	DefaultMaxBackoff   = time.Hour
+	DefaultTimeout      = 10 * time.Second
+	DefaultBatchSize    = 100
+)
+
+// Sign returns the signature header value of a payload: the hex encoded
+// HMAC SHA-256 of the body keyed with the webhook secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Worker turns survivor changes into webhook deliveries and posts them,
+// retrying failed deliveries with exponential backoff
+type Worker struct {
+	DB survivordb.SurvivorStore
+	// Client posts the deliveries, a client with DefaultTimeout when nil
+	Client *http.Client
+	// PollInterval is how often new changes and due deliveries are checked
+	PollInterval time.Duration
+	// MaxAttempts is the number of attempts before a delivery fails
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled for every
+	// retry after it up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// BatchSize is the most changes and deliveries handled per poll
+	BatchSize int
+}
+
+// withDefaults returns a copy of the worker with its zero fields set to the defaults
+func (w Worker) withDefaults() *Worker {
+	if w.Client == nil {
+		w.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+	if w.PollInterval <= 0 {
+		w.PollInterval = DefaultPollInterval
+	}
+	if w.MaxAttempts <= 0 {
+		w.MaxAttempts = DefaultMaxAttempts
+	}
+	if w.Backoff <= 0 {
+		w.Backoff = DefaultBackoff
+	}
+	if w.MaxBackoff <= 0 {
+		w.MaxBackoff = DefaultMaxBackoff
+	}
+	if w.BatchSize <= 0 {
+		w.BatchSize = DefaultBatchSize
+	}
+
+	return &w
+}
+
+// Run polls every PollInterval until ctx is done
+func (w *Worker) Run(ctx context.Context) {
+	worker := w.withDefaults()
+	ticker := time.NewTicker(worker.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := worker.Poll(ctx); err != nil {
+			logrus.WithFields(logrus.Fields{
+				"Error": err,
+			}).Info("Error delivering webhooks")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll enqueues the deliveries of new survivor changes and makes every
+// delivery attempt that is due
+func (w *Worker) Poll(ctx context.Context) error {
+	worker := w.withDefaults()
+	if _, err := worker.DB.EnqueueWebhookDeliveries(worker.BatchSize); err != nil {
+		return err
+	}
+
+	deliveries, err := worker.DB.GetDueWebhookDeliveries(time.Now(), worker.BatchSize)
+	if err != nil {
+		return err
+	}
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		attempt := worker.deliver(ctx, &delivery)
+
+		status, next := survivordb.DeliveryDelivered, attempt.Timestamp
+		if attempt.Error != "" {
+			status, next = survivordb.DeliveryPending, attempt.Timestamp.Add(worker.backoff(attempt.Attempt))
+			if attempt.Attempt >= worker.MaxAttempts {
+				status = survivordb.DeliveryFailed
+			}
+		}
+		logrus.WithFields(logrus.Fields{
+			"delivery": delivery.ID,
+			"webhook":  delivery.WebhookID,
+			"event":    delivery.EventType,
+			"attempt":  attempt.Attempt,
+			"status":   status,
+			"Error":    attempt.Error,
+		}).Info("Webhook delivery")
+		if err := worker.DB.RecordWebhookAttempt(delivery.ID, attempt, status, next); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// backoff returns the delay after the attempt-th failed attempt
+func (w *Worker) backoff(attempt int) time.Duration {
+	delay := w.Backoff
+	for i := 1; i < attempt && delay < w.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > w.MaxBackoff {
+		delay = w.MaxBackoff
+	}
+
+	return delay
+}
+
+// deliver posts a delivery once. Any response other than 2xx is a failure
+func (w *Worker) deliver(ctx context.Context, delivery *survivordb.WebhookDelivery) *survivordb.WebhookAttempt {
+	attempt := &survivordb.WebhookAttempt{
+		Attempt:   delivery.Attempts + 1,
+		Timestamp: time.Now(),
+	}
+
+	request, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(EventHeader, delivery.EventType)
+	request.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	request.Header.Set(SignatureHeader, Sign(delivery.Secret, delivery.Payload))
+
+	response, err := w.Client.Do(request)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(response.Body, 1048576))
+
+	attempt.StatusCode = response.StatusCode
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("unexpected status %s", response.Status)
+	}
+
+	return attempt
+}
@@ -0,0 +1,121 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"robo-apocalypse/pkg/survivordb"
+	"testing"
+	"time"
+)
+
+// TestWorker_Poll checks if events are posted signed and retried until they are delivered
+func TestWorker_Poll(t *testing.T) {
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	defer os.Remove("./test.db")
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	calls := 0
+	secret := "s3cret"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := ioutil.ReadAll(r.Body)
+		if got, want := r.Header.Get(SignatureHeader), Sign(secret, body); got != want {
+			t.Errorf("Worker.Poll(): signature: want: %v, got: %v", want, got)
+		}
+		if got := r.Header.Get(EventHeader); got != survivordb.EventSurvivorCreated {
+			t.Errorf("Worker.Poll(): event: want: %v, got: %v", survivordb.EventSurvivorCreated, got)
+		}
+		event := survivordb.SurvivorEvent{}
+		if err := json.Unmarshal(body, &event); err != nil || event.IdNumber != "HD138VOP34219" {
+			t.Errorf("Worker.Poll(): could not decode event: %v, %v", event, err)
+		}
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	webhook := &survivordb.Webhook{URL: server.URL, Secret: secret}
+	if err = db.SaveWebhook(webhook); err != nil {
+		t.Errorf("SurvivorDB.SaveWebhook(): want: %v, got: %v", nil, err)
+		return
+	}
+	if err = db.Save(&survivordb.Survivor{Name: "Jane Doe", Age: 1, Gender: "Female", IdNumber: "HD138VOP34219"}); err != nil {
+		t.Errorf("SurvivorDB.Save(): want: %v, got: %v", nil, err)
+	}
+
+	worker := &Worker{DB: db, Backoff: time.Nanosecond}
+	for i := 0; i < 3; i++ {
+		if err = worker.Poll(context.Background()); err != nil {
+			t.Errorf("Worker.Poll(): want: %v, got: %v", nil, err)
+		}
+		time.Sleep(time.Second)
+	}
+	if calls != 2 {
+		t.Errorf("Worker.Poll(): calls: want: %v, got: %v", 2, calls)
+	}
+
+	deliveries := db.GetWebhookDeliveries(webhook.ID, 10)
+	if len(deliveries) != 1 || deliveries[0].Status != survivordb.DeliveryDelivered || len(deliveries[0].History) != 2 ||
+		deliveries[0].History[0].StatusCode != http.StatusServiceUnavailable || deliveries[0].History[1].StatusCode != http.StatusOK {
+		t.Errorf("SurvivorDB.GetWebhookDeliveries(): got: %v", deliveries)
+	}
+}
+
+// TestWorker_PollFailed checks if a delivery fails after the last attempt
+func TestWorker_PollFailed(t *testing.T) {
+	os.Remove("./test.db")
+	db := survivordb.Open("./test.db")
+	defer os.Remove("./test.db")
+	err := db.Setup()
+	if err != nil {
+		t.Errorf("SurvivorDB.Setup(): Failed to setup database")
+		return
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	webhook := &survivordb.Webhook{URL: server.URL}
+	if err = db.SaveWebhook(webhook); err != nil {
+		t.Errorf("SurvivorDB.SaveWebhook(): want: %v, got: %v", nil, err)
+		return
+	}
+	if err = db.Save(&survivordb.Survivor{Name: "Jane Doe", Age: 1, Gender: "Female", IdNumber: "HD138VOP34219"}); err != nil {
+		t.Errorf("SurvivorDB.Save(): want: %v, got: %v", nil, err)
+	}
+
+	worker := &Worker{DB: db, MaxAttempts: 2, Backoff: time.Nanosecond}
+	for i := 0; i < 3; i++ {
+		if err = worker.Poll(context.Background()); err != nil {
+			t.Errorf("Worker.Poll(): want: %v, got: %v", nil, err)
+		}
+		time.Sleep(time.Second)
+	}
+
+	deliveries := db.GetWebhookDeliveries(webhook.ID, 10)
+	if len(deliveries) != 1 || deliveries[0].Status != survivordb.DeliveryFailed || deliveries[0].Attempts != 2 {
+		t.Errorf("SurvivorDB.GetWebhookDeliveries(): got: %v", deliveries)
+	}
+}
+
+// TestWorker_backoff checks if the delay doubles up to the maximum
+func TestWorker_backoff(t *testing.T) {
+	worker := &Worker{Backoff: time.Second, MaxBackoff: 10 * time.Second}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 5: 10 * time.Second, 50: 10 * time.Second} {
+		if got := worker.backoff(attempt); got != want {
+			t.Errorf("Worker.backoff(%v): want: %v, got: %v", attempt, want, got)
+		}
+	}
+}
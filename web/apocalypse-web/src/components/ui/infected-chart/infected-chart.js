@@ -4,10 +4,13 @@ import { Pie } from "react-chartjs-2";
 
 ChartJS.register(ArcElement, Tooltip, Legend);
 
+// a viewer API key, see the keys command of the server
+const apiKey = process.env.REACT_APP_API_KEY || "";
+
 const InfectedChart = (props) => {
   const [stats, setStats] = useState([]);
   const getStats = useCallback(async () => {
-    const response = await fetch("http://localhost:8080/survivors/stats");
+    const response = await fetch(`http://localhost:8080/survivors/stats?token=${apiKey}`);
     const values = await response.json();
     setStats(values);
     console.log(values);
@@ -15,6 +18,11 @@ const InfectedChart = (props) => {
 
   useEffect(() => {
     getStats();
+    const events = new EventSource(`http://localhost:8080/events?token=${apiKey}`);
+    ["survivor.created", "survivor.infected", "resources.updated", "survivor.deleted"].forEach((type) =>
+      events.addEventListener(type, getStats)
+    );
+    return () => events.close();
   }, [getStats]);
 
   const data = {
@@ -3,6 +3,9 @@ import { AgGridReact } from "ag-grid-react";
 import "ag-grid-community/dist/styles/ag-grid.css";
 import "ag-grid-community/dist/styles/ag-theme-alpine-dark.css";
 
+// a viewer API key, see the keys command of the server
+const apiKey = process.env.REACT_APP_API_KEY || "";
+
 const SurvivorsGrid = props => {
   const [survivors, setSurvivors] = useState([]);
 
@@ -20,7 +23,7 @@ const SurvivorsGrid = props => {
   ]);
 
   const getSurvivors = useCallback(async () => {
-    const response = await fetch("http://localhost:8080/survivors");
+    const response = await fetch(`http://localhost:8080/survivors?token=${apiKey}`);
     const data = await response.json();
     setSurvivors(data);
     console.log(data);
@@ -28,6 +31,11 @@ const SurvivorsGrid = props => {
 
   useEffect(() => {
     getSurvivors();
+    const events = new EventSource(`http://localhost:8080/events?token=${apiKey}`);
+    ["survivor.created", "survivor.infected", "location.updated", "resources.updated", "survivor.deleted"].forEach(type =>
+      events.addEventListener(type, getSurvivors)
+    );
+    return () => events.close();
   }, [getSurvivors]);
   return (
     <div className="ag-theme-alpine-dark" style={{ height: 400, width: 1800 }}>